@@ -1,6 +1,9 @@
 package firestore
 
 import (
+	"fmt"
+	"strings"
+
 	"cloud.google.com/go/firestore"
 )
 
@@ -8,10 +11,94 @@ import (
 
 // BulkWriter abstracts Firestore bulk writer behavior
 type BulkWriter interface {
-	Create(docRef *firestore.DocumentRef, data interface{}) (*firestore.BulkWriterJob, error)
-	Set(docRef *firestore.DocumentRef, data interface{}, opts ...firestore.SetOption) (*firestore.BulkWriterJob, error)
-	Update(docRef *firestore.DocumentRef, updates []firestore.Update, preconds ...firestore.Precondition) (*firestore.BulkWriterJob, error)
-	Delete(docRef *firestore.DocumentRef, preconds ...firestore.Precondition) (*firestore.BulkWriterJob, error)
+	Create(docRef *firestore.DocumentRef, data interface{}) (BulkWriterJob, error)
+	Set(docRef *firestore.DocumentRef, data interface{}, opts ...firestore.SetOption) (BulkWriterJob, error)
+	Update(docRef *firestore.DocumentRef, updates []firestore.Update, preconds ...firestore.Precondition) (BulkWriterJob, error)
+	Delete(docRef *firestore.DocumentRef, preconds ...firestore.Precondition) (BulkWriterJob, error)
 	Flush()
 	End()
 }
+
+// BulkWriterJob abstracts Firestore BulkWriterJob behavior
+// (the pending result of one BulkWriter write).
+type BulkWriterJob interface {
+	// Results blocks until the write has completed and returns its result.
+	Results() (*firestore.WriteResult, error)
+}
+
+// newBulkWriterJob wraps the job returned by a BulkWriter write, keeping a nil
+// interface when the write was rejected.
+func newBulkWriterJob(job *firestore.BulkWriterJob, err error) (BulkWriterJob, error) {
+	if job == nil {
+		return nil, err
+	}
+	return &bulkWriterJobWrapper{job: job}, err
+}
+
+type bulkWriterJobWrapper struct {
+	job *firestore.BulkWriterJob
+}
+
+func (w *bulkWriterJobWrapper) Results() (*firestore.WriteResult, error) {
+	return w.job.Results()
+}
+
+// BulkWrite is a write enqueued on a BulkWriter: the path of its document and
+// its job.
+type BulkWrite struct {
+	Path string
+	Job  BulkWriterJob
+}
+
+// BulkWriteFailure is the error of one failed bulk write.
+type BulkWriteFailure struct {
+	Path string
+	Err  error
+}
+
+// BulkWriteError is returned by EndBulkWriter when writes failed. It holds the
+// failed writes in the order they were passed to EndBulkWriter; a document
+// written several times appears once per failed write.
+type BulkWriteError struct {
+	Errors []BulkWriteFailure
+}
+
+func (e *BulkWriteError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "go-firestore-mock: %d bulk writes failed", len(e.Errors))
+	for _, f := range e.Errors {
+		fmt.Fprintf(&b, "\n  %s: %v", f.Path, f.Err)
+	}
+	return b.String()
+}
+
+// Unwrap returns the errors of the failed writes, so errors.Is and errors.As
+// match any of them.
+func (e *BulkWriteError) Unwrap() []error {
+	errs := make([]error, len(e.Errors))
+	for i, f := range e.Errors {
+		errs[i] = f.Err
+	}
+	return errs
+}
+
+// EndBulkWriter ends bw and waits for the jobs of writes, in order, so that
+// recorded sessions replay. It returns a *BulkWriteError with the error of
+// every failed write, or nil if all writes succeeded. Writes without a job
+// (whose enqueue call failed) are skipped.
+func EndBulkWriter(bw BulkWriter, writes []BulkWrite) error {
+	bw.End()
+	var errs []BulkWriteFailure
+	for _, w := range writes {
+		if w.Job == nil {
+			continue
+		}
+		if _, err := w.Job.Results(); err != nil {
+			errs = append(errs, BulkWriteFailure{Path: w.Path, Err: err})
+		}
+	}
+	if len(errs) > 0 {
+		return &BulkWriteError{Errors: errs}
+	}
+	return nil
+}
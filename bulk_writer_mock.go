@@ -39,10 +39,10 @@ func (m *MockBulkWriter) EXPECT() *MockBulkWriterMockRecorder {
 }
 
 // Create mocks base method.
-func (m *MockBulkWriter) Create(docRef *firestore.DocumentRef, data any) (*firestore.BulkWriterJob, error) {
+func (m *MockBulkWriter) Create(docRef *firestore.DocumentRef, data any) (BulkWriterJob, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "Create", docRef, data)
-	ret0, _ := ret[0].(*firestore.BulkWriterJob)
+	ret0, _ := ret[0].(BulkWriterJob)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -54,14 +54,14 @@ func (mr *MockBulkWriterMockRecorder) Create(docRef, data any) *gomock.Call {
 }
 
 // Delete mocks base method.
-func (m *MockBulkWriter) Delete(docRef *firestore.DocumentRef, preconds ...firestore.Precondition) (*firestore.BulkWriterJob, error) {
+func (m *MockBulkWriter) Delete(docRef *firestore.DocumentRef, preconds ...firestore.Precondition) (BulkWriterJob, error) {
 	m.ctrl.T.Helper()
 	varargs := []any{docRef}
 	for _, a := range preconds {
 		varargs = append(varargs, a)
 	}
 	ret := m.ctrl.Call(m, "Delete", varargs...)
-	ret0, _ := ret[0].(*firestore.BulkWriterJob)
+	ret0, _ := ret[0].(BulkWriterJob)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -98,14 +98,14 @@ func (mr *MockBulkWriterMockRecorder) Flush() *gomock.Call {
 }
 
 // Set mocks base method.
-func (m *MockBulkWriter) Set(docRef *firestore.DocumentRef, data any, opts ...firestore.SetOption) (*firestore.BulkWriterJob, error) {
+func (m *MockBulkWriter) Set(docRef *firestore.DocumentRef, data any, opts ...firestore.SetOption) (BulkWriterJob, error) {
 	m.ctrl.T.Helper()
 	varargs := []any{docRef, data}
 	for _, a := range opts {
 		varargs = append(varargs, a)
 	}
 	ret := m.ctrl.Call(m, "Set", varargs...)
-	ret0, _ := ret[0].(*firestore.BulkWriterJob)
+	ret0, _ := ret[0].(BulkWriterJob)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -118,14 +118,14 @@ func (mr *MockBulkWriterMockRecorder) Set(docRef, data any, opts ...any) *gomock
 }
 
 // Update mocks base method.
-func (m *MockBulkWriter) Update(docRef *firestore.DocumentRef, updates []firestore.Update, preconds ...firestore.Precondition) (*firestore.BulkWriterJob, error) {
+func (m *MockBulkWriter) Update(docRef *firestore.DocumentRef, updates []firestore.Update, preconds ...firestore.Precondition) (BulkWriterJob, error) {
 	m.ctrl.T.Helper()
 	varargs := []any{docRef, updates}
 	for _, a := range preconds {
 		varargs = append(varargs, a)
 	}
 	ret := m.ctrl.Call(m, "Update", varargs...)
-	ret0, _ := ret[0].(*firestore.BulkWriterJob)
+	ret0, _ := ret[0].(BulkWriterJob)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -136,3 +136,41 @@ func (mr *MockBulkWriterMockRecorder) Update(docRef, updates any, preconds ...an
 	varargs := append([]any{docRef, updates}, preconds...)
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockBulkWriter)(nil).Update), varargs...)
 }
+
+// MockBulkWriterJob is a mock of BulkWriterJob interface.
+type MockBulkWriterJob struct {
+	ctrl     *gomock.Controller
+	recorder *MockBulkWriterJobMockRecorder
+}
+
+// MockBulkWriterJobMockRecorder is the mock recorder for MockBulkWriterJob.
+type MockBulkWriterJobMockRecorder struct {
+	mock *MockBulkWriterJob
+}
+
+// NewMockBulkWriterJob creates a new mock instance.
+func NewMockBulkWriterJob(ctrl *gomock.Controller) *MockBulkWriterJob {
+	mock := &MockBulkWriterJob{ctrl: ctrl}
+	mock.recorder = &MockBulkWriterJobMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockBulkWriterJob) EXPECT() *MockBulkWriterJobMockRecorder {
+	return m.recorder
+}
+
+// Results mocks base method.
+func (m *MockBulkWriterJob) Results() (*firestore.WriteResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Results")
+	ret0, _ := ret[0].(*firestore.WriteResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Results indicates an expected call of Results.
+func (mr *MockBulkWriterJobMockRecorder) Results() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Results", reflect.TypeOf((*MockBulkWriterJob)(nil).Results))
+}
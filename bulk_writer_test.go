@@ -2,9 +2,12 @@ package firestore
 
 import (
 	"context"
+	"errors"
+	"strings"
 	"testing"
 
 	"cloud.google.com/go/firestore"
+	"go.uber.org/mock/gomock"
 )
 
 func TestBulkWriterWrapper_Create(t *testing.T) {
@@ -417,3 +420,70 @@ func TestBulkWriterWrapper_ComplexOperations(t *testing.T) {
 		_ = wrapper.End
 	})
 }
+
+func TestNewBulkWriterJob(t *testing.T) {
+	boom := errors.New("boom")
+	job, err := newBulkWriterJob(nil, boom)
+	if job != nil || err != boom {
+		t.Errorf("newBulkWriterJob(nil, boom) = %v, %v, want nil interface and boom", job, err)
+	}
+	var _ BulkWriterJob = (*bulkWriterJobWrapper)(nil)
+}
+
+func TestEndBulkWriter(t *testing.T) {
+	boom := errors.New("boom")
+	type result struct {
+		path string
+		err  error
+	}
+	tests := []struct {
+		name     string
+		results  []result
+		wantErrs []string
+	}{
+		{name: "all succeed", results: []result{{"users/a", nil}, {"users/b", nil}}},
+		{name: "some fail", results: []result{{"users/a", nil}, {"users/b", boom}, {"users/c", boom}}, wantErrs: []string{"users/b", "users/c"}},
+		{name: "same document twice", results: []result{{"users/a", boom}, {"users/b", nil}, {"users/a", boom}}, wantErrs: []string{"users/a", "users/a"}},
+		{name: "no jobs", results: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			bw := NewMockBulkWriter(ctrl)
+			ended := false
+			bw.EXPECT().End().Do(func() { ended = true })
+
+			writes := []BulkWrite{{Path: "users/skipped"}}
+			for _, r := range tt.results {
+				job := NewMockBulkWriterJob(ctrl)
+				job.EXPECT().Results().DoAndReturn(func() (*firestore.WriteResult, error) {
+					if !ended {
+						t.Errorf("Results() of %s called before End()", r.path)
+					}
+					return nil, r.err
+				})
+				writes = append(writes, BulkWrite{Path: r.path, Job: job})
+			}
+
+			err := EndBulkWriter(bw, writes)
+			if len(tt.wantErrs) == 0 {
+				if err != nil {
+					t.Errorf("EndBulkWriter() error = %v, want nil", err)
+				}
+				return
+			}
+			var bwErr *BulkWriteError
+			if !errors.As(err, &bwErr) || len(bwErr.Errors) != len(tt.wantErrs) {
+				t.Fatalf("EndBulkWriter() error = %v, want BulkWriteError for %v", err, tt.wantErrs)
+			}
+			for i, path := range tt.wantErrs {
+				if f := bwErr.Errors[i]; f.Path != path || f.Err != boom || !strings.Contains(err.Error(), path) {
+					t.Errorf("failure %d = %v, want %s: boom, message %q", i, f, path, err)
+				}
+			}
+			if !errors.Is(err, boom) {
+				t.Error("errors.Is(err, boom) = false, want true")
+			}
+		})
+	}
+}
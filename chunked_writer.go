@@ -0,0 +1,145 @@
+package firestore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"cloud.google.com/go/firestore"
+)
+
+// MaxWritesPerBatch is the largest number of writes Firestore accepts in a
+// single WriteBatch commit.
+const MaxWritesPerBatch = 500
+
+// ChunkedWriterOptions configures a ChunkedWriter. The zero value (or nil)
+// commits chunks of MaxWritesPerBatch writes one after another.
+type ChunkedWriterOptions struct {
+	// ChunkSize is the number of writes per WriteBatch, at most
+	// MaxWritesPerBatch. Zero means MaxWritesPerBatch.
+	ChunkSize int
+	// Parallelism is the number of chunks committed concurrently. Zero or
+	// one commits them sequentially.
+	Parallelism int
+}
+
+// ChunkResult is the outcome of committing one chunk.
+type ChunkResult struct {
+	// Start is the index of the chunk's first write, in call order.
+	Start int
+	// Results are the write results of the chunk, nil if it failed.
+	Results []*firestore.WriteResult
+	// Err is the commit error of the chunk.
+	Err error
+}
+
+// ChunkedWriter collects any number of writes and commits them through
+// FirestoreClient.Batch in chunks that stay within Firestore's write limit:
+//
+//	w := NewChunkedWriter(client, &ChunkedWriterOptions{Parallelism: 4})
+//	for _, u := range users {
+//		w.Set(client.Doc("users/"+u.ID).Reference(), u)
+//	}
+//	chunks, err := w.Commit(ctx)
+//
+// Each chunk is committed atomically, but the write set as a whole is NOT: when
+// a chunk fails, the other chunks are still committed (or were already), so
+// callers must be able to retry or reconcile the failed chunks, whose writes
+// can be found from ChunkResult.Start.
+//
+// A ChunkedWriter is not safe for concurrent use.
+type ChunkedWriter struct {
+	client      FirestoreClient
+	chunkSize   int
+	parallelism int
+	writes      []func(WriteBatch)
+}
+
+// NewChunkedWriter returns a ChunkedWriter committing through client.
+func NewChunkedWriter(client FirestoreClient, opts *ChunkedWriterOptions) *ChunkedWriter {
+	w := &ChunkedWriter{client: client, chunkSize: MaxWritesPerBatch, parallelism: 1}
+	if opts != nil {
+		if opts.ChunkSize > 0 && opts.ChunkSize < MaxWritesPerBatch {
+			w.chunkSize = opts.ChunkSize
+		}
+		if opts.Parallelism > 1 {
+			w.parallelism = opts.Parallelism
+		}
+	}
+	return w
+}
+
+// Create queues a Create of docRef.
+func (w *ChunkedWriter) Create(docRef *firestore.DocumentRef, data interface{}) *ChunkedWriter {
+	w.writes = append(w.writes, func(b WriteBatch) { b.Create(docRef, data) })
+	return w
+}
+
+// Set queues a Set of docRef.
+func (w *ChunkedWriter) Set(docRef *firestore.DocumentRef, data interface{}, opts ...firestore.SetOption) *ChunkedWriter {
+	w.writes = append(w.writes, func(b WriteBatch) { b.Set(docRef, data, opts...) })
+	return w
+}
+
+// Update queues an Update of docRef.
+func (w *ChunkedWriter) Update(docRef *firestore.DocumentRef, updates []firestore.Update, preconds ...firestore.Precondition) *ChunkedWriter {
+	w.writes = append(w.writes, func(b WriteBatch) { b.Update(docRef, updates, preconds...) })
+	return w
+}
+
+// Delete queues a Delete of docRef.
+func (w *ChunkedWriter) Delete(docRef *firestore.DocumentRef, preconds ...firestore.Precondition) *ChunkedWriter {
+	w.writes = append(w.writes, func(b WriteBatch) { b.Delete(docRef, preconds...) })
+	return w
+}
+
+// Len returns the number of queued writes.
+func (w *ChunkedWriter) Len() int {
+	return len(w.writes)
+}
+
+// Commit commits the queued writes in chunks and clears the queue. It returns
+// one ChunkResult per chunk, in write order, and an error joining the errors
+// of all failed chunks. Chunks not yet started when ctx is done fail with
+// ctx.Err().
+func (w *ChunkedWriter) Commit(ctx context.Context) ([]ChunkResult, error) {
+	writes := w.writes
+	w.writes = nil
+
+	chunks := make([]ChunkResult, 0, (len(writes)+w.chunkSize-1)/w.chunkSize)
+	for start := 0; start < len(writes); start += w.chunkSize {
+		chunks = append(chunks, ChunkResult{Start: start})
+	}
+
+	sem := make(chan struct{}, w.parallelism)
+	var wg sync.WaitGroup
+	for i := range chunks {
+		c := &chunks[i]
+		end := min(c.Start+w.chunkSize, len(writes))
+		if err := ctx.Err(); err != nil {
+			c.Err = err
+			continue
+		}
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			b := w.client.Batch()
+			for _, write := range writes[c.Start:end] {
+				write(b)
+			}
+			c.Results, c.Err = b.Commit(ctx)
+		}()
+	}
+	wg.Wait()
+
+	var errs []error
+	for _, c := range chunks {
+		if c.Err != nil {
+			end := min(c.Start+w.chunkSize, len(writes))
+			errs = append(errs, fmt.Errorf("go-firestore-mock: chunk of writes %d-%d: %w", c.Start, end-1, c.Err))
+		}
+	}
+	return chunks, errors.Join(errs...)
+}
@@ -0,0 +1,136 @@
+package firestore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"cloud.google.com/go/firestore"
+	"go.uber.org/mock/gomock"
+)
+
+// recordingBatch is a WriteBatch that records the IDs of the documents written
+// and fails Commit for batches containing failID.
+type recordingBatch struct {
+	ids    []string
+	failID string
+}
+
+func (b *recordingBatch) add(ref *firestore.DocumentRef) WriteBatch {
+	b.ids = append(b.ids, ref.ID)
+	return b
+}
+
+func (b *recordingBatch) Create(ref *firestore.DocumentRef, _ interface{}) WriteBatch {
+	return b.add(ref)
+}
+func (b *recordingBatch) Set(ref *firestore.DocumentRef, _ interface{}, _ ...firestore.SetOption) WriteBatch {
+	return b.add(ref)
+}
+func (b *recordingBatch) Update(ref *firestore.DocumentRef, _ []firestore.Update, _ ...firestore.Precondition) WriteBatch {
+	return b.add(ref)
+}
+func (b *recordingBatch) Delete(ref *firestore.DocumentRef, _ ...firestore.Precondition) WriteBatch {
+	return b.add(ref)
+}
+
+func (b *recordingBatch) Commit(context.Context) ([]*firestore.WriteResult, error) {
+	for _, id := range b.ids {
+		if id == b.failID {
+			return nil, errors.New("commit failed")
+		}
+	}
+	return make([]*firestore.WriteResult, len(b.ids)), nil
+}
+
+func TestChunkedWriter_Commit(t *testing.T) {
+	tests := []struct {
+		name       string
+		writes     int
+		opts       *ChunkedWriterOptions
+		failID     string
+		wantSizes  []int
+		wantFailed int
+	}{
+		{name: "single chunk", writes: 3, wantSizes: []int{3}},
+		{name: "default chunk size", writes: 1201, wantSizes: []int{500, 500, 201}},
+		{name: "chunk size capped", writes: 600, opts: &ChunkedWriterOptions{ChunkSize: 1000}, wantSizes: []int{500, 100}},
+		{name: "parallel with failure", writes: 10, opts: &ChunkedWriterOptions{ChunkSize: 3, Parallelism: 3}, failID: "d4", wantSizes: []int{3, 3, 3, 1}, wantFailed: 1},
+		{name: "empty", writes: 0, wantSizes: []int{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			client := NewMockFirestoreClient(ctrl)
+			var mu sync.Mutex
+			var batches []*recordingBatch
+			client.EXPECT().Batch().DoAndReturn(func() WriteBatch {
+				mu.Lock()
+				defer mu.Unlock()
+				b := &recordingBatch{failID: tt.failID}
+				batches = append(batches, b)
+				return b
+			}).AnyTimes()
+
+			w := NewChunkedWriter(client, tt.opts)
+			for i := 0; i < tt.writes; i++ {
+				ref := &firestore.DocumentRef{ID: fmt.Sprintf("d%d", i)}
+				switch i % 4 {
+				case 0:
+					w.Create(ref, map[string]any{})
+				case 1:
+					w.Set(ref, map[string]any{})
+				case 2:
+					w.Update(ref, []firestore.Update{{Path: "a", Value: 1}})
+				default:
+					w.Delete(ref)
+				}
+			}
+			if w.Len() != tt.writes {
+				t.Fatalf("Len() = %d, want %d", w.Len(), tt.writes)
+			}
+
+			chunks, err := w.Commit(context.Background())
+			if (err != nil) != (tt.wantFailed > 0) {
+				t.Fatalf("Commit() error = %v, want failures: %d", err, tt.wantFailed)
+			}
+			if len(chunks) != len(tt.wantSizes) || len(batches) != len(tt.wantSizes) {
+				t.Fatalf("Commit() = %d chunks in %d batches, want %d", len(chunks), len(batches), len(tt.wantSizes))
+			}
+			failed, start := 0, 0
+			for i, c := range chunks {
+				if c.Start != start {
+					t.Errorf("chunk %d Start = %d, want %d", i, c.Start, start)
+				}
+				if c.Err != nil {
+					failed++
+				} else if len(c.Results) != tt.wantSizes[i] {
+					t.Errorf("chunk %d has %d results, want %d", i, len(c.Results), tt.wantSizes[i])
+				}
+				start += tt.wantSizes[i]
+			}
+			if failed != tt.wantFailed {
+				t.Errorf("failed chunks = %d, want %d", failed, tt.wantFailed)
+			}
+			if w.Len() != 0 {
+				t.Errorf("Len() after Commit = %d, want 0", w.Len())
+			}
+		})
+	}
+}
+
+func TestChunkedWriter_CancelledContext(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	client := NewMockFirestoreClient(ctrl) // Batch must not be called
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	w := NewChunkedWriter(client, nil)
+	w.Delete(&firestore.DocumentRef{ID: "a"})
+	chunks, err := w.Commit(ctx)
+	if !errors.Is(err, context.Canceled) || len(chunks) != 1 || !errors.Is(chunks[0].Err, context.Canceled) {
+		t.Errorf("Commit() = %+v, %v, want canceled chunk", chunks, err)
+	}
+}
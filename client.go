@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"cloud.google.com/go/firestore"
+	"github.com/akmalsyrf/go-firestore-mock/internal/fsvalue"
 )
 
 //go:generate mockgen -source=client.go -destination=client_mock.go -package=firestore
@@ -31,7 +32,10 @@ func (w *firebaseClientWrapper) Collection(path string) CollectionRef {
 }
 
 func (w *firebaseClientWrapper) CollectionGroup(collectionID string) Query {
-	return &queryWrapper{q: w.client.CollectionGroup(collectionID).Query}
+	return &queryWrapper{
+		q:    w.client.CollectionGroup(collectionID).Query,
+		spec: QuerySpec{Collection: collectionID, CollectionGroup: true},
+	}
 }
 
 func (w *firebaseClientWrapper) Doc(path string) DocumentRef {
@@ -110,62 +114,67 @@ type Query interface {
 	NewAggregationQuery() AggregationQuery
 }
 
-type queryWrapper struct{ q firestore.Query }
+// queryWrapper wraps firestore.Query and records the chain of calls in spec
+// (see Describe).
+type queryWrapper struct {
+	q    firestore.Query
+	spec QuerySpec
+}
 
 func (w *queryWrapper) Where(path string, op string, value any) Query {
-	return &queryWrapper{q: w.q.Where(path, op, value)}
+	return &queryWrapper{q: w.q.Where(path, op, value), spec: w.spec.where(path, op, value)}
 }
 
 func (w *queryWrapper) WherePath(fp firestore.FieldPath, op string, value any) Query {
-	return &queryWrapper{q: w.q.WherePath(fp, op, value)}
+	return &queryWrapper{q: w.q.WherePath(fp, op, value), spec: w.spec.where(fsvalue.FieldPathString(fp), op, value)}
 }
 
 func (w *queryWrapper) WhereEntity(ef firestore.EntityFilter) Query {
-	return &queryWrapper{q: w.q.WhereEntity(ef)}
+	return &queryWrapper{q: w.q.WhereEntity(ef), spec: w.spec.whereEntity(ef)}
 }
 
 func (w *queryWrapper) OrderBy(path string, dir firestore.Direction) Query {
-	return &queryWrapper{q: w.q.OrderBy(path, dir)}
+	return &queryWrapper{q: w.q.OrderBy(path, dir), spec: w.spec.orderBy(path, dir)}
 }
 
 func (w *queryWrapper) OrderByPath(fp firestore.FieldPath, dir firestore.Direction) Query {
-	return &queryWrapper{q: w.q.OrderByPath(fp, dir)}
+	return &queryWrapper{q: w.q.OrderByPath(fp, dir), spec: w.spec.orderBy(fsvalue.FieldPathString(fp), dir)}
 }
 
 func (w *queryWrapper) Limit(n int) Query {
-	return &queryWrapper{q: w.q.Limit(n)}
+	return &queryWrapper{q: w.q.Limit(n), spec: w.spec.limit(n, false)}
 }
 
 func (w *queryWrapper) LimitToLast(n int) Query {
-	return &queryWrapper{q: w.q.LimitToLast(n)}
+	return &queryWrapper{q: w.q.LimitToLast(n), spec: w.spec.limit(n, true)}
 }
 
 func (w *queryWrapper) Offset(n int) Query {
-	return &queryWrapper{q: w.q.Offset(n)}
+	return &queryWrapper{q: w.q.Offset(n), spec: w.spec.offset(n)}
 }
 
 func (w *queryWrapper) StartAt(docSnapshotOrFieldValues ...any) Query {
-	return &queryWrapper{q: w.q.StartAt(docSnapshotOrFieldValues...)}
+	return &queryWrapper{q: w.q.StartAt(docSnapshotOrFieldValues...), spec: w.spec.startAt(docSnapshotOrFieldValues, true)}
 }
 
 func (w *queryWrapper) StartAfter(docSnapshotOrFieldValues ...any) Query {
-	return &queryWrapper{q: w.q.StartAfter(docSnapshotOrFieldValues...)}
+	return &queryWrapper{q: w.q.StartAfter(docSnapshotOrFieldValues...), spec: w.spec.startAt(docSnapshotOrFieldValues, false)}
 }
 
 func (w *queryWrapper) EndAt(docSnapshotOrFieldValues ...any) Query {
-	return &queryWrapper{q: w.q.EndAt(docSnapshotOrFieldValues...)}
+	return &queryWrapper{q: w.q.EndAt(docSnapshotOrFieldValues...), spec: w.spec.endAt(docSnapshotOrFieldValues, true)}
 }
 
 func (w *queryWrapper) EndBefore(docSnapshotOrFieldValues ...any) Query {
-	return &queryWrapper{q: w.q.EndBefore(docSnapshotOrFieldValues...)}
+	return &queryWrapper{q: w.q.EndBefore(docSnapshotOrFieldValues...), spec: w.spec.endAt(docSnapshotOrFieldValues, false)}
 }
 
 func (w *queryWrapper) Select(paths ...string) Query {
-	return &queryWrapper{q: w.q.Select(paths...)}
+	return &queryWrapper{q: w.q.Select(paths...), spec: w.spec.selectPaths(paths)}
 }
 
 func (w *queryWrapper) SelectPaths(fieldPaths ...firestore.FieldPath) Query {
-	return &queryWrapper{q: w.q.SelectPaths(fieldPaths...)}
+	return &queryWrapper{q: w.q.SelectPaths(fieldPaths...), spec: w.spec.selectPaths(fieldPathStrings(fieldPaths))}
 }
 
 func (w *queryWrapper) Documents(ctx context.Context) DocumentIterator {
@@ -180,6 +189,10 @@ func (w *queryWrapper) NewAggregationQuery() AggregationQuery {
 	return &aggregationQueryWrapper{aq: w.q.NewAggregationQuery()}
 }
 
+func (w *queryWrapper) querySpec() QuerySpec {
+	return w.spec.clone()
+}
+
 // documentIteratorWrapper wraps real firestore.DocumentIterator
 type documentIteratorWrapper struct {
 	iter *firestore.DocumentIterator
@@ -202,20 +215,20 @@ type bulkWriterWrapper struct {
 	bw *firestore.BulkWriter
 }
 
-func (w *bulkWriterWrapper) Create(docRef *firestore.DocumentRef, data interface{}) (*firestore.BulkWriterJob, error) {
-	return w.bw.Create(docRef, data)
+func (w *bulkWriterWrapper) Create(docRef *firestore.DocumentRef, data interface{}) (BulkWriterJob, error) {
+	return newBulkWriterJob(w.bw.Create(docRef, data))
 }
 
-func (w *bulkWriterWrapper) Set(docRef *firestore.DocumentRef, data interface{}, opts ...firestore.SetOption) (*firestore.BulkWriterJob, error) {
-	return w.bw.Set(docRef, data, opts...)
+func (w *bulkWriterWrapper) Set(docRef *firestore.DocumentRef, data interface{}, opts ...firestore.SetOption) (BulkWriterJob, error) {
+	return newBulkWriterJob(w.bw.Set(docRef, data, opts...))
 }
 
-func (w *bulkWriterWrapper) Update(docRef *firestore.DocumentRef, updates []firestore.Update, preconds ...firestore.Precondition) (*firestore.BulkWriterJob, error) {
-	return w.bw.Update(docRef, updates, preconds...)
+func (w *bulkWriterWrapper) Update(docRef *firestore.DocumentRef, updates []firestore.Update, preconds ...firestore.Precondition) (BulkWriterJob, error) {
+	return newBulkWriterJob(w.bw.Update(docRef, updates, preconds...))
 }
 
-func (w *bulkWriterWrapper) Delete(docRef *firestore.DocumentRef, preconds ...firestore.Precondition) (*firestore.BulkWriterJob, error) {
-	return w.bw.Delete(docRef, preconds...)
+func (w *bulkWriterWrapper) Delete(docRef *firestore.DocumentRef, preconds ...firestore.Precondition) (BulkWriterJob, error) {
+	return newBulkWriterJob(w.bw.Delete(docRef, preconds...))
 }
 
 func (w *bulkWriterWrapper) Flush() {
@@ -0,0 +1,72 @@
+// Package example shows fsmockgen at work: Promote was run once against a
+// recording client, and promote_mocks_test.go was generated from the
+// recording to test it with mocks.
+package example
+
+//go:generate go run .. -in testdata/promote.jsonl -out promote_mocks_test.go -package example -func newPromoteClient
+
+import (
+	"context"
+	"fmt"
+
+	"cloud.google.com/go/firestore"
+	gofirestoremock "github.com/akmalsyrf/go-firestore-mock"
+)
+
+// Promote adds the users old enough for the team to it, bumps the global
+// promotion counter and returns the size of the team.
+func Promote(ctx context.Context, client gofirestoremock.FirestoreClient, teamID string) (int64, error) {
+	team, err := client.Doc("teams/" + teamID).Get(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("get team: %w", err)
+	}
+	minAge, err := team.DataAt("minAge")
+	if err != nil {
+		return 0, fmt.Errorf("team %s: %w", teamID, err)
+	}
+
+	users, err := client.Collection("users").Where("age", ">=", minAge).Documents(ctx).GetAll()
+	if err != nil {
+		return 0, fmt.Errorf("query users: %w", err)
+	}
+	batch := client.Batch()
+	for _, u := range users {
+		batch = batch.Update(u.Ref, []firestore.Update{{Path: "team", Value: teamID}})
+	}
+	if _, err := batch.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("update users: %w", err)
+	}
+	if _, err := client.Doc("teams/"+teamID).Set(ctx, map[string]any{"promoted": len(users)}, firestore.MergeAll); err != nil {
+		return 0, fmt.Errorf("update team: %w", err)
+	}
+
+	stats := client.Doc("stats/promotions").Reference()
+	err = client.RunTransaction(ctx, func(ctx context.Context, tx gofirestoremock.Transaction) error {
+		snap, err := tx.Get(stats)
+		if err != nil {
+			return err
+		}
+		var total int64
+		if snap.Exists() {
+			n, err := snap.DataAt("total")
+			if err != nil {
+				return err
+			}
+			total = n.(int64)
+		}
+		return tx.Set(stats, map[string]any{"total": total + int64(len(users))})
+	})
+	if err != nil {
+		return 0, fmt.Errorf("count promotions: %w", err)
+	}
+
+	res, err := client.Collection("users").Where("team", "==", teamID).NewAggregationQuery().WithCount("n").Get(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("count team: %w", err)
+	}
+	n, err := res.Count("n")
+	if err != nil {
+		return 0, err
+	}
+	return *n, nil
+}
@@ -0,0 +1,24 @@
+package example
+
+import (
+	"context"
+	"testing"
+
+	"go.uber.org/mock/gomock"
+)
+
+func TestPromote(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	client := newPromoteClient(t, ctrl)
+
+	n, err := Promote(context.Background(), client, "t1")
+	if err != nil {
+		t.Fatalf("Promote() error = %v", err)
+	}
+	if n != 2 {
+		t.Errorf("Promote() = %d, want 2", n)
+	}
+	if err := client.Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+}
@@ -0,0 +1,129 @@
+// Code generated by fsmockgen from promote.jsonl. DO NOT EDIT.
+
+package example
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	gofirestoremock "github.com/akmalsyrf/go-firestore-mock"
+	"go.uber.org/mock/gomock"
+)
+
+// newPromoteClient returns a MockFirestoreClient expecting the calls recorded in
+// promote.jsonl and returning the recorded results.
+//
+// The query builders (Where, OrderBy, Limit, ...) expect gomock.Any()
+// arguments: the recording only keeps a description of each query, so the
+// queries built by the code under test are not checked. Their results are
+// served in the recorded order; the comment above each expectation quotes
+// the recorded query.
+func newPromoteClient(t testing.TB, ctrl *gomock.Controller) *gofirestoremock.MockFirestoreClient {
+	t.Helper()
+	snap := func(path string, data map[string]any) *firestore.DocumentSnapshot {
+		s, err := gofirestoremock.SnapshotFromData(path, data)
+		if err != nil {
+			t.Fatalf("snapshot of %s: %v", path, err)
+		}
+		return s
+	}
+	count := func(n int64) *int64 { return &n }
+
+	client := gofirestoremock.NewMockFirestoreClient(ctrl)
+	teamsColl := gofirestoremock.NewMockCollectionRef(ctrl)
+	client.EXPECT().Collection("teams").Return(teamsColl).AnyTimes()
+	teamsColl.EXPECT().ID().Return("teams").AnyTimes()
+	teamsColl.EXPECT().Path().Return("projects/test-project/databases/(default)/documents/teams").AnyTimes()
+	teamsT1Doc := gofirestoremock.NewMockDocumentRef(ctrl)
+	client.EXPECT().Doc("teams/t1").Return(teamsT1Doc).AnyTimes()
+	teamsColl.EXPECT().Doc("t1").Return(teamsT1Doc).AnyTimes()
+	teamsT1Doc.EXPECT().ID().Return("t1").AnyTimes()
+	teamsT1Doc.EXPECT().Path().Return("projects/test-project/databases/(default)/documents/teams/t1").AnyTimes()
+	teamsT1Doc.EXPECT().Reference().Return(&firestore.DocumentRef{ID: "t1", Path: "projects/test-project/databases/(default)/documents/teams/t1"}).AnyTimes()
+	usersColl := gofirestoremock.NewMockCollectionRef(ctrl)
+	client.EXPECT().Collection("users").Return(usersColl).AnyTimes()
+	usersColl.EXPECT().ID().Return("users").AnyTimes()
+	usersColl.EXPECT().Path().Return("projects/test-project/databases/(default)/documents/users").AnyTimes()
+	usersQuery := gofirestoremock.NewMockQuery(ctrl)
+	usersColl.EXPECT().Where(gomock.Any(), gomock.Any(), gomock.Any()).Return(usersQuery).AnyTimes()
+	usersColl.EXPECT().WherePath(gomock.Any(), gomock.Any(), gomock.Any()).Return(usersQuery).AnyTimes()
+	usersColl.EXPECT().WhereEntity(gomock.Any()).Return(usersQuery).AnyTimes()
+	usersColl.EXPECT().OrderBy(gomock.Any(), gomock.Any()).Return(usersQuery).AnyTimes()
+	usersColl.EXPECT().OrderByPath(gomock.Any(), gomock.Any()).Return(usersQuery).AnyTimes()
+	usersColl.EXPECT().Limit(gomock.Any()).Return(usersQuery).AnyTimes()
+	usersColl.EXPECT().LimitToLast(gomock.Any()).Return(usersQuery).AnyTimes()
+	usersColl.EXPECT().Offset(gomock.Any()).Return(usersQuery).AnyTimes()
+	usersColl.EXPECT().StartAt(gomock.Any()).Return(usersQuery).AnyTimes()
+	usersColl.EXPECT().StartAfter(gomock.Any()).Return(usersQuery).AnyTimes()
+	usersColl.EXPECT().EndAt(gomock.Any()).Return(usersQuery).AnyTimes()
+	usersColl.EXPECT().EndBefore(gomock.Any()).Return(usersQuery).AnyTimes()
+	usersColl.EXPECT().Select(gomock.Any()).Return(usersQuery).AnyTimes()
+	usersColl.EXPECT().SelectPaths(gomock.Any()).Return(usersQuery).AnyTimes()
+	usersQuery.EXPECT().Where(gomock.Any(), gomock.Any(), gomock.Any()).Return(usersQuery).AnyTimes()
+	usersQuery.EXPECT().WherePath(gomock.Any(), gomock.Any(), gomock.Any()).Return(usersQuery).AnyTimes()
+	usersQuery.EXPECT().WhereEntity(gomock.Any()).Return(usersQuery).AnyTimes()
+	usersQuery.EXPECT().OrderBy(gomock.Any(), gomock.Any()).Return(usersQuery).AnyTimes()
+	usersQuery.EXPECT().OrderByPath(gomock.Any(), gomock.Any()).Return(usersQuery).AnyTimes()
+	usersQuery.EXPECT().Limit(gomock.Any()).Return(usersQuery).AnyTimes()
+	usersQuery.EXPECT().LimitToLast(gomock.Any()).Return(usersQuery).AnyTimes()
+	usersQuery.EXPECT().Offset(gomock.Any()).Return(usersQuery).AnyTimes()
+	usersQuery.EXPECT().StartAt(gomock.Any()).Return(usersQuery).AnyTimes()
+	usersQuery.EXPECT().StartAfter(gomock.Any()).Return(usersQuery).AnyTimes()
+	usersQuery.EXPECT().EndAt(gomock.Any()).Return(usersQuery).AnyTimes()
+	usersQuery.EXPECT().EndBefore(gomock.Any()).Return(usersQuery).AnyTimes()
+	usersQuery.EXPECT().Select(gomock.Any()).Return(usersQuery).AnyTimes()
+	usersQuery.EXPECT().SelectPaths(gomock.Any()).Return(usersQuery).AnyTimes()
+	batch := gofirestoremock.NewMockWriteBatch(ctrl)
+	usersU1Doc := gofirestoremock.NewMockDocumentRef(ctrl)
+	client.EXPECT().Doc("users/u1").Return(usersU1Doc).AnyTimes()
+	usersColl.EXPECT().Doc("u1").Return(usersU1Doc).AnyTimes()
+	usersU1Doc.EXPECT().ID().Return("u1").AnyTimes()
+	usersU1Doc.EXPECT().Path().Return("projects/test-project/databases/(default)/documents/users/u1").AnyTimes()
+	usersU1Doc.EXPECT().Reference().Return(&firestore.DocumentRef{ID: "u1", Path: "projects/test-project/databases/(default)/documents/users/u1"}).AnyTimes()
+	usersU2Doc := gofirestoremock.NewMockDocumentRef(ctrl)
+	client.EXPECT().Doc("users/u2").Return(usersU2Doc).AnyTimes()
+	usersColl.EXPECT().Doc("u2").Return(usersU2Doc).AnyTimes()
+	usersU2Doc.EXPECT().ID().Return("u2").AnyTimes()
+	usersU2Doc.EXPECT().Path().Return("projects/test-project/databases/(default)/documents/users/u2").AnyTimes()
+	usersU2Doc.EXPECT().Reference().Return(&firestore.DocumentRef{ID: "u2", Path: "projects/test-project/databases/(default)/documents/users/u2"}).AnyTimes()
+	tx := gofirestoremock.NewMockTransaction(ctrl)
+	statsColl := gofirestoremock.NewMockCollectionRef(ctrl)
+	client.EXPECT().Collection("stats").Return(statsColl).AnyTimes()
+	statsColl.EXPECT().ID().Return("stats").AnyTimes()
+	statsColl.EXPECT().Path().Return("projects/test-project/databases/(default)/documents/stats").AnyTimes()
+	statsPromotionsDoc := gofirestoremock.NewMockDocumentRef(ctrl)
+	client.EXPECT().Doc("stats/promotions").Return(statsPromotionsDoc).AnyTimes()
+	statsColl.EXPECT().Doc("promotions").Return(statsPromotionsDoc).AnyTimes()
+	statsPromotionsDoc.EXPECT().ID().Return("promotions").AnyTimes()
+	statsPromotionsDoc.EXPECT().Path().Return("projects/test-project/databases/(default)/documents/stats/promotions").AnyTimes()
+	statsPromotionsDoc.EXPECT().Reference().Return(&firestore.DocumentRef{ID: "promotions", Path: "projects/test-project/databases/(default)/documents/stats/promotions"}).AnyTimes()
+	aggregation := gofirestoremock.NewMockAggregationQuery(ctrl)
+	aggregation.EXPECT().WithCount(gomock.Any()).Return(aggregation).AnyTimes()
+	aggregationResult := gofirestoremock.NewMockAggregationResult(ctrl)
+	aggregationResult.EXPECT().Count("n").Return(count(2), nil).AnyTimes()
+
+	teamsT1Doc.EXPECT().Get(gomock.Any()).Return(gofirestoremock.NewDocumentSnapshot(snap("projects/test-project/databases/(default)/documents/teams/t1", map[string]any{"minAge": int64(18), "name": "Core"})), nil)
+	// #2 users where age >= 18
+	usersQuery.EXPECT().Documents(gomock.Any()).Return(gofirestoremock.NewDocumentIteratorFromSlice([]*firestore.DocumentSnapshot{snap("projects/test-project/databases/(default)/documents/users/u1", map[string]any{"age": int64(36), "name": "Ada", "tags": []any{"admin"}}), snap("projects/test-project/databases/(default)/documents/users/u2", map[string]any{"age": int64(20), "name": "Linus", "score": float64(9.5)})}, nil))
+	client.EXPECT().Batch().Return(batch)
+	batch.EXPECT().Update(gofirestoremock.RecordedArg(`"users/u1"`), gofirestoremock.RecordedArg(`[{"FieldPath":[],"Path":"team","Value":"t1"}]`), gofirestoremock.RecordedArg(`[]`)).Return(batch)
+	batch.EXPECT().Update(gofirestoremock.RecordedArg(`"users/u2"`), gofirestoremock.RecordedArg(`[{"FieldPath":[],"Path":"team","Value":"t1"}]`), gofirestoremock.RecordedArg(`[]`)).Return(batch)
+	batch.EXPECT().Commit(gomock.Any()).Return([]*firestore.WriteResult{{UpdateTime: time.Date(2026, 10, 19, 3, 11, 17, 465489668, time.UTC)}, {UpdateTime: time.Date(2026, 10, 19, 3, 11, 17, 465489668, time.UTC)}}, nil)
+	teamsT1Doc.EXPECT().Set(gomock.Any(), gofirestoremock.RecordedArg(`{"promoted":2}`), gofirestoremock.RecordedArg(`["MergeAll"]`)).Return(&firestore.WriteResult{UpdateTime: time.Date(2026, 10, 19, 3, 11, 17, 465725387, time.UTC)}, nil)
+	tx.EXPECT().Get(gofirestoremock.RecordedArg(`"stats/promotions"`)).Return(gofirestoremock.NewDocumentSnapshot(snap("projects/test-project/databases/(default)/documents/stats/promotions", map[string]any{"total": int64(5)})), nil)
+	tx.EXPECT().Set(gofirestoremock.RecordedArg(`"stats/promotions"`), gofirestoremock.RecordedArg(`{"total":7}`), gofirestoremock.RecordedArg(`[]`)).Return(nil)
+	client.EXPECT().RunTransaction(gomock.Any(), gomock.Any(), gofirestoremock.RecordedArg(`[]`)).DoAndReturn(
+		func(ctx context.Context, f func(context.Context, gofirestoremock.Transaction) error, _ ...firestore.TransactionOption) error {
+			if err := f(ctx, tx); err != nil {
+				return err
+			}
+			return nil
+		})
+	// #11 users where team == "t1"
+	usersQuery.EXPECT().NewAggregationQuery().Return(aggregation)
+	aggregation.EXPECT().Get(gomock.Any()).Return(aggregationResult, nil)
+	client.EXPECT().Close().Return(nil)
+	return client
+}
@@ -0,0 +1,830 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	pb "cloud.google.com/go/firestore/apiv1/firestorepb"
+	gofirestoremock "github.com/akmalsyrf/go-firestore-mock"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+const modulePath = "github.com/akmalsyrf/go-firestore-mock"
+
+type options struct {
+	Package string
+	Func    string
+	Source  string // name of the recording, for the header comment
+}
+
+// queryBuilders are the Query methods returning a Query, with their number of
+// parameters.
+var queryBuilders = []struct {
+	name   string
+	params int
+}{
+	{"Where", 3}, {"WherePath", 3}, {"WhereEntity", 1},
+	{"OrderBy", 2}, {"OrderByPath", 2},
+	{"Limit", 1}, {"LimitToLast", 1}, {"Offset", 1},
+	{"StartAt", 1}, {"StartAfter", 1}, {"EndAt", 1}, {"EndBefore", 1},
+	{"Select", 1}, {"SelectPaths", 1},
+}
+
+// generator accumulates the generated function body: decls creates the mocks
+// and their navigation expectations, exps the expectations of the recorded
+// operations.
+type generator struct {
+	root    string // projects/{p}/databases/{d}/documents
+	decls   bytes.Buffer
+	exps    bytes.Buffer
+	imports map[string]bool
+	names   map[string]bool
+
+	colls   map[string]string // collection path -> mock
+	docs    map[string]string // document path -> mock
+	queries map[string]string // collection path or group ID -> MockQuery
+	groups  map[string]string // collection group ID -> MockQuery
+
+	bulkWriter string
+	jobs       map[string][]string // document path -> mock jobs not yet resolved
+	tx         string              // transaction being recorded
+	iters      map[string]*iterGroup
+	iterOrder  []*iterGroup
+
+	usesSnap, usesCount bool
+}
+
+// iterGroup collects the Next calls of one recorded iterator.
+type iterGroup struct {
+	key     string
+	first   gofirestoremock.RecordEntry
+	tx      string
+	entries []gofirestoremock.RecordEntry
+	done    bool
+}
+
+func generate(entries []gofirestoremock.RecordEntry, opts options) ([]byte, error) {
+	g := &generator{
+		root:    recordingRoot(entries),
+		imports: map[string]bool{"testing": true, "go.uber.org/mock/gomock": true, modulePath: true},
+		names:   map[string]bool{"t": true, "ctrl": true, "client": true, "snap": true, "count": true},
+		colls:   map[string]string{},
+		docs:    map[string]string{},
+		queries: map[string]string{},
+		groups:  map[string]string{},
+		jobs:    map[string][]string{},
+		iters:   map[string]*iterGroup{},
+	}
+	for _, e := range entries {
+		if err := g.entry(e); err != nil {
+			return nil, fmt.Errorf("entry #%d (%s %s): %w", e.Seq, e.Op, e.Path, err)
+		}
+	}
+	for _, it := range g.iterOrder {
+		if !it.done {
+			if err := g.flushIter(it); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return g.file(opts)
+}
+
+// recordingRoot returns the database root of the first full path recorded.
+func recordingRoot(entries []gofirestoremock.RecordEntry) string {
+	for _, e := range entries {
+		if e.Result == nil {
+			continue
+		}
+		paths := []string{e.Result.Ref, e.Result.Collection}
+		if e.Result.Doc != nil {
+			paths = append(paths, e.Result.Doc.Path)
+		}
+		for _, d := range e.Result.Docs {
+			paths = append(paths, d.Path)
+		}
+		for _, p := range paths {
+			if i := strings.Index(p, "/documents/"); strings.HasPrefix(p, "projects/") && i >= 0 {
+				return p[:i+len("/documents")]
+			}
+		}
+	}
+	return "projects/test-project/databases/(default)/documents"
+}
+
+func (g *generator) file(opts options) ([]byte, error) {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by fsmockgen from %s. DO NOT EDIT.\n\npackage %s\n\nimport (\n", opts.Source, opts.Package)
+	paths := make([]string, 0, len(g.imports))
+	for p := range g.imports {
+		paths = append(paths, p)
+	}
+	// standard library first, as goimports groups them
+	sort.Slice(paths, func(i, j int) bool {
+		si, sj := !strings.Contains(paths[i], "."), !strings.Contains(paths[j], ".")
+		if si != sj {
+			return si
+		}
+		return paths[i] < paths[j]
+	})
+	for i, p := range paths {
+		if i > 0 && !strings.Contains(paths[i-1], ".") && strings.Contains(p, ".") {
+			b.WriteString("\n")
+		}
+		if p == modulePath {
+			fmt.Fprintf(&b, "\tgofirestoremock %q\n", p)
+		} else {
+			fmt.Fprintf(&b, "\t%q\n", p)
+		}
+	}
+	b.WriteString(")\n\n")
+	fmt.Fprintf(&b, "// %s returns a MockFirestoreClient expecting the calls recorded in\n// %s and returning the recorded results.\n", opts.Func, opts.Source)
+	if len(g.queries) > 0 || len(g.groups) > 0 {
+		// Recordings describe queries as text, so their builders cannot be
+		// expected with the recorded arguments.
+		b.WriteString("//\n// The query builders (Where, OrderBy, Limit, ...) expect gomock.Any()\n" +
+			"// arguments: the recording only keeps a description of each query, so the\n" +
+			"// queries built by the code under test are not checked. Their results are\n" +
+			"// served in the recorded order; the comment above each expectation quotes\n" +
+			"// the recorded query.\n")
+	}
+	fmt.Fprintf(&b, "func %s(t testing.TB, ctrl *gomock.Controller) *gofirestoremock.MockFirestoreClient {\n\tt.Helper()\n", opts.Func)
+	if g.usesSnap {
+		b.WriteString(`snap := func(path string, data map[string]any) *firestore.DocumentSnapshot {
+		s, err := gofirestoremock.SnapshotFromData(path, data)
+		if err != nil {
+			t.Fatalf("snapshot of %s: %v", path, err)
+		}
+		return s
+	}
+`)
+	}
+	if g.usesCount {
+		b.WriteString("count := func(n int64) *int64 { return &n }\n")
+	}
+	b.WriteString("\nclient := gofirestoremock.NewMockFirestoreClient(ctrl)\n")
+	b.Write(g.decls.Bytes())
+	b.WriteString("\n")
+	b.Write(g.exps.Bytes())
+	b.WriteString("return client\n}\n")
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("format generated code: %w\n%s", err, b.Bytes())
+	}
+	return src, nil
+}
+
+func (g *generator) decl(format string, args ...any) { fmt.Fprintf(&g.decls, format+"\n", args...) }
+
+func (g *generator) exp(format string, args ...any) { fmt.Fprintf(&g.exps, format+"\n", args...) }
+
+func (g *generator) entry(e gofirestoremock.RecordEntry) error {
+	args, err := splitArgs(e.Args)
+	if err != nil {
+		return err
+	}
+	res := e.Result
+	if res == nil {
+		res = &gofirestoremock.RecordedResult{}
+	}
+	switch e.Op {
+	case "DocumentRef.Get":
+		g.exp("%s.EXPECT().Get(gomock.Any()).Return(%s, %s)", g.doc(e.Path), g.wrappedSnap(res.Doc), g.err(e.Error))
+	case "DocumentRef.Create", "DocumentRef.Set", "DocumentRef.Update", "DocumentRef.Delete":
+		method := strings.TrimPrefix(e.Op, "DocumentRef.")
+		g.exp("%s.EXPECT().%s(%s).Return(%s, %s)", g.doc(e.Path), method, g.matchers("gomock.Any()", args), g.writeResult(res.WriteTime), g.err(e.Error))
+	case "CollectionRef.Add":
+		ref := "nil"
+		if res.Ref != "" {
+			ref = g.refLit(res.Ref)
+		}
+		g.exp("%s.EXPECT().Add(%s).Return(%s, %s, %s)", g.coll(e.Path), g.matchers("gomock.Any()", args), ref, g.writeResult(res.WriteTime), g.err(e.Error))
+	case "FirestoreClient.GetAll":
+		g.exp("client.EXPECT().GetAll(%s).Return(%s, %s)", g.matchers("gomock.Any()", args), g.wrappedSnaps(res.Docs), g.err(e.Error))
+	case "FirestoreClient.Close":
+		g.exp("client.EXPECT().Close().Return(%s)", g.err(e.Error))
+	case "WriteBatch.Commit":
+		return g.batch(e, args, res)
+	case "BulkWriter.Create", "BulkWriter.Set", "BulkWriter.Update", "BulkWriter.Delete":
+		g.bulkWrite(e, args)
+	case "BulkWriterJob.Results":
+		jobs := g.jobs[e.Path]
+		if len(jobs) == 0 {
+			g.exp("// #%d BulkWriterJob.Results %s: no matching write recorded", e.Seq, e.Path)
+			return nil
+		}
+		g.jobs[e.Path] = jobs[1:]
+		g.exp("%s.EXPECT().Results().Return(%s, %s)", jobs[0], g.writeResult(res.WriteTime), g.err(e.Error))
+	case "Transaction.Get", "Transaction.GetAll", "Transaction.Create", "Transaction.Set", "Transaction.Update", "Transaction.Delete":
+		g.txOp(e, args, res)
+	case "Transaction.Commit":
+		// runs inside RunTransaction; nothing to expect
+	case "FirestoreClient.RunTransaction":
+		g.runTransaction(e, args)
+	case "AggregationQuery.Get":
+		g.aggregation(e, res)
+	case "DocumentIterator.Next", "DocumentRefIterator.Next", "CollectionIterator.Next",
+		"DocumentSnapshotIterator.Next", "QuerySnapshotIterator.Next":
+		return g.iterNext(e)
+	case "DocumentIterator.GetAll":
+		g.iterGetAll(e, res)
+	default:
+		g.exp("// #%d %s %s: not supported by fsmockgen", e.Seq, e.Op, e.Path)
+	}
+	return nil
+}
+
+// matchers renders RecordedArg matchers for args, after the given leading
+// matchers.
+func (g *generator) matchers(leading string, args []json.RawMessage) string {
+	var parts []string
+	if leading != "" {
+		parts = append(parts, leading)
+	}
+	for _, a := range args {
+		parts = append(parts, argMatcher(string(a)))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func argMatcher(encoded string) string {
+	if strings.Contains(encoded, "`") {
+		return "gofirestoremock.RecordedArg(" + strconv.Quote(encoded) + ")"
+	}
+	return "gofirestoremock.RecordedArg(`" + encoded + "`)"
+}
+
+// refMatcher matches a *firestore.DocumentRef to the document at path, whose
+// mock it declares for the caller to get the reference from.
+func (g *generator) refMatcher(path string) string {
+	g.doc(path)
+	return argMatcher(strconv.Quote(path))
+}
+
+func splitArgs(raw json.RawMessage) ([]json.RawMessage, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	var args []json.RawMessage
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, fmt.Errorf("decode args: %w", err)
+	}
+	for i, a := range args {
+		var b bytes.Buffer
+		if err := json.Compact(&b, a); err != nil {
+			return nil, err
+		}
+		args[i] = b.Bytes()
+	}
+	return args, nil
+}
+
+func (g *generator) batch(e gofirestoremock.RecordEntry, args []json.RawMessage, res *gofirestoremock.RecordedResult) error {
+	batch := g.name("batch")
+	g.decl("%s := gofirestoremock.NewMockWriteBatch(ctrl)", batch)
+	g.exp("client.EXPECT().Batch().Return(%s)", batch)
+	if len(args) > 0 {
+		var writes []struct {
+			Op   string
+			Path string
+			Args []json.RawMessage
+		}
+		if err := json.Unmarshal(args[0], &writes); err != nil {
+			return fmt.Errorf("decode batch writes: %w", err)
+		}
+		for _, w := range writes {
+			wargs, err := splitArgs(mustJSON(w.Args))
+			if err != nil {
+				return err
+			}
+			g.exp("%s.EXPECT().%s(%s).Return(%s)", batch, w.Op, g.matchers(g.refMatcher(w.Path), wargs), batch)
+		}
+	}
+	wrs := "nil"
+	if res.WriteTimes != nil {
+		g.imports["cloud.google.com/go/firestore"] = true
+		parts := make([]string, len(res.WriteTimes))
+		for i, t := range res.WriteTimes {
+			parts[i] = "{UpdateTime: " + g.timeLit(t) + "}"
+		}
+		wrs = "[]*firestore.WriteResult{" + strings.Join(parts, ", ") + "}"
+	}
+	g.exp("%s.EXPECT().Commit(gomock.Any()).Return(%s, %s)", batch, wrs, g.err(e.Error))
+	return nil
+}
+
+func (g *generator) bulkWrite(e gofirestoremock.RecordEntry, args []json.RawMessage) {
+	if g.bulkWriter == "" {
+		g.bulkWriter = g.name("bulkWriter")
+		g.decl("%s := gofirestoremock.NewMockBulkWriter(ctrl)", g.bulkWriter)
+		g.decl("client.EXPECT().BulkWriter(gomock.Any()).Return(%s).AnyTimes()", g.bulkWriter)
+		g.decl("%s.EXPECT().Flush().AnyTimes()", g.bulkWriter)
+		g.decl("%s.EXPECT().End().AnyTimes()", g.bulkWriter)
+	}
+	method := strings.TrimPrefix(e.Op, "BulkWriter.")
+	job := "nil"
+	if e.Error == nil {
+		job = g.name("job")
+		g.decl("%s := gofirestoremock.NewMockBulkWriterJob(ctrl)", job)
+		g.jobs[e.Path] = append(g.jobs[e.Path], job)
+	}
+	g.exp("%s.EXPECT().%s(%s).Return(%s, %s)", g.bulkWriter, method, g.matchers(g.refMatcher(e.Path), args), job, g.err(e.Error))
+}
+
+// transaction returns the MockTransaction of the transaction being recorded.
+func (g *generator) transaction() string {
+	if g.tx == "" {
+		g.tx = g.name("tx")
+		g.decl("%s := gofirestoremock.NewMockTransaction(ctrl)", g.tx)
+	}
+	return g.tx
+}
+
+func (g *generator) txOp(e gofirestoremock.RecordEntry, args []json.RawMessage, res *gofirestoremock.RecordedResult) {
+	tx := g.transaction()
+	method := strings.TrimPrefix(e.Op, "Transaction.")
+	switch method {
+	case "Get":
+		g.exp("%s.EXPECT().Get(%s).Return(%s, %s)", tx, g.refMatcher(e.Path), g.wrappedSnap(res.Doc), g.err(e.Error))
+	case "GetAll":
+		g.exp("%s.EXPECT().GetAll(%s).Return(%s, %s)", tx, g.matchers("", args), g.wrappedSnaps(res.Docs), g.err(e.Error))
+	default:
+		g.exp("%s.EXPECT().%s(%s).Return(%s)", tx, method, g.matchers(g.refMatcher(e.Path), args), g.err(e.Error))
+	}
+}
+
+func (g *generator) runTransaction(e gofirestoremock.RecordEntry, args []json.RawMessage) {
+	tx := g.transaction()
+	g.tx = ""
+	g.imports["context"] = true
+	g.imports["cloud.google.com/go/firestore"] = true
+	g.exp(`client.EXPECT().RunTransaction(%s).DoAndReturn(
+		func(ctx context.Context, f func(context.Context, gofirestoremock.Transaction) error, _ ...firestore.TransactionOption) error {
+			if err := f(ctx, %s); err != nil {
+				return err
+			}
+			return %s
+		})`, g.matchers("gomock.Any(), gomock.Any()", args), tx, g.err(e.Error))
+}
+
+func (g *generator) aggregation(e gofirestoremock.RecordEntry, res *gofirestoremock.RecordedResult) {
+	src := g.querySource(e.Path, e.Query)
+	aq := g.name("aggregation")
+	g.decl("%s := gofirestoremock.NewMockAggregationQuery(ctrl)", aq)
+	g.decl("%s.EXPECT().WithCount(gomock.Any()).Return(%s).AnyTimes()", aq, aq)
+	result := "nil"
+	if e.Error == nil {
+		result = g.name("aggregationResult")
+		g.decl("%s := gofirestoremock.NewMockAggregationResult(ctrl)", result)
+		aliases := make([]string, 0, len(res.Counts))
+		for alias := range res.Counts {
+			aliases = append(aliases, alias)
+		}
+		sort.Strings(aliases)
+		for _, alias := range aliases {
+			g.usesCount = true
+			g.decl("%s.EXPECT().Count(%q).Return(count(%d), nil).AnyTimes()", result, alias, res.Counts[alias])
+		}
+	}
+	g.exp("// #%d %s", e.Seq, e.Query)
+	g.exp("%s.EXPECT().NewAggregationQuery().Return(%s)", src, aq)
+	g.exp("%s.EXPECT().Get(gomock.Any()).Return(%s, %s)", aq, result, g.err(e.Error))
+}
+
+// iterGetAll expects the query of e to return an iterator serving the
+// documents (or the error) recorded for its GetAll.
+func (g *generator) iterGetAll(e gofirestoremock.RecordEntry, res *gofirestoremock.RecordedResult) {
+	snaps := make([]string, len(res.Docs))
+	for i := range res.Docs {
+		snaps[i] = g.snap(&res.Docs[i])
+	}
+	g.imports["cloud.google.com/go/firestore"] = true
+	iter := fmt.Sprintf("gofirestoremock.NewDocumentIteratorFromSlice([]*firestore.DocumentSnapshot{%s}, %s)", strings.Join(snaps, ", "), g.err(e.Error))
+	g.exp("// #%d %s", e.Seq, e.Query)
+	src := g.querySource(e.Path, e.Query)
+	if g.tx != "" {
+		src = g.tx
+	}
+	g.exp("%s.EXPECT().Documents(gomock.Any()).Return(%s)", src, iter)
+}
+
+// iterNext adds e to the iterator it belongs to: a Call of 1 starts a new
+// iterator, later calls continue the open one with the same method, path and
+// query. Iterators are generated once they end.
+func (g *generator) iterNext(e gofirestoremock.RecordEntry) error {
+	key := e.Op + "\x00" + e.Path + "\x00" + e.Query
+	it := g.iters[key]
+	if e.Call == 1 || it == nil || it.done {
+		if it != nil && !it.done {
+			if err := g.flushIter(it); err != nil {
+				return err
+			}
+		}
+		it = &iterGroup{key: key, first: e, tx: g.tx}
+		g.iters[key] = it
+		g.iterOrder = append(g.iterOrder, it)
+	}
+	it.entries = append(it.entries, e)
+	if e.Error != nil {
+		return g.flushIter(it)
+	}
+	return nil
+}
+
+func (g *generator) flushIter(it *iterGroup) error {
+	it.done = true
+	e := it.first
+	var last *gofirestoremock.RecordedError
+	var results []*gofirestoremock.RecordedResult
+	for _, n := range it.entries {
+		if n.Error != nil {
+			last = n.Error
+			break
+		}
+		if n.Result != nil {
+			results = append(results, n.Result)
+		}
+	}
+	errLit := "nil"
+	if last != nil && !last.Done {
+		errLit = g.err(last)
+	}
+	g.imports["cloud.google.com/go/firestore"] = true
+	switch e.Op {
+	case "DocumentIterator.Next":
+		snaps := make([]string, len(results))
+		for i, r := range results {
+			snaps[i] = g.snap(r.Doc)
+		}
+		iter := fmt.Sprintf("gofirestoremock.NewDocumentIteratorFromSlice([]*firestore.DocumentSnapshot{%s}, %s)", strings.Join(snaps, ", "), errLit)
+		g.exp("// #%d %s", e.Seq, e.Query)
+		src := g.querySource(e.Path, e.Query) // declared for the caller to build the query
+		if it.tx != "" {
+			src = it.tx
+		}
+		g.exp("%s.EXPECT().Documents(gomock.Any()).Return(%s)", src, iter)
+	case "DocumentRefIterator.Next":
+		refs := make([]string, len(results))
+		for i, r := range results {
+			refs[i] = g.refLit(r.Ref)
+		}
+		iter := fmt.Sprintf("gofirestoremock.NewDocumentRefIteratorFromSlice([]*firestore.DocumentRef{%s}, %s)", strings.Join(refs, ", "), errLit)
+		src := g.coll(e.Path)
+		if it.tx != "" {
+			src = it.tx
+		}
+		g.exp("%s.EXPECT().DocumentRefs(gomock.Any()).Return(%s)", src, iter)
+	case "CollectionIterator.Next":
+		colls := make([]string, len(results))
+		for i, r := range results {
+			colls[i] = g.collRefLit(r.Collection)
+		}
+		iter := fmt.Sprintf("gofirestoremock.NewCollectionIteratorFromSlice([]*firestore.CollectionRef{%s}, %s)", strings.Join(colls, ", "), errLit)
+		owner := "client"
+		if e.Path != "" {
+			owner = g.doc(e.Path)
+		}
+		g.exp("%s.EXPECT().Collections(gomock.Any()).Return(%s)", owner, iter)
+	case "DocumentSnapshotIterator.Next":
+		iter := g.name("snapshots")
+		g.decl("%s := gofirestoremock.NewMockDocumentSnapshotIterator(ctrl)", iter)
+		g.decl("%s.EXPECT().Stop().AnyTimes()", iter)
+		g.exp("%s.EXPECT().Snapshots(gomock.Any()).Return(%s)", g.doc(e.Path), iter)
+		for _, n := range it.entries {
+			if n.Error != nil && n.Error.Done {
+				g.imports["google.golang.org/api/iterator"] = true
+				g.exp("%s.EXPECT().Next().Return(nil, iterator.Done)", iter)
+				continue
+			}
+			var doc *gofirestoremock.RecordedDoc
+			if n.Result != nil {
+				doc = n.Result.Doc
+			}
+			g.exp("%s.EXPECT().Next().Return(%s, %s)", iter, g.wrappedSnap(doc), g.err(n.Error))
+		}
+	default:
+		g.exp("// #%d %s %s: not supported by fsmockgen", e.Seq, e.Op, e.Path)
+	}
+	return nil
+}
+
+// querySource returns the mock whose Documents / NewAggregationQuery serves
+// a query on path described by query.
+func (g *generator) querySource(path, query string) string {
+	if strings.HasPrefix(query, "collection group ") {
+		return g.group(path)
+	}
+	if query == "" || query == path {
+		return g.coll(path)
+	}
+	return g.query(path)
+}
+
+func (g *generator) name(base string) string {
+	name := base
+	for i := 2; g.names[name]; i++ {
+		name = base + strconv.Itoa(i)
+	}
+	g.names[name] = true
+	return name
+}
+
+// pathName turns a path into a lowerCamelCase identifier prefix.
+func pathName(path string) string {
+	var b strings.Builder
+	upper := false
+	for _, r := range path {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = b.Len() > 0
+			continue
+		}
+		if r > unicode.MaxASCII {
+			continue
+		}
+		if b.Len() == 0 {
+			if unicode.IsDigit(r) {
+				b.WriteString("x")
+			}
+			b.WriteRune(unicode.ToLower(r))
+		} else if upper {
+			b.WriteRune(unicode.ToUpper(r))
+		} else {
+			b.WriteRune(r)
+		}
+		upper = false
+	}
+	if b.Len() == 0 {
+		return "root"
+	}
+	return b.String()
+}
+
+func (g *generator) coll(path string) string {
+	if v, ok := g.colls[path]; ok {
+		return v
+	}
+	v := g.name(pathName(path) + "Coll")
+	g.colls[path] = v
+	g.decl("%s := gofirestoremock.NewMockCollectionRef(ctrl)", v)
+	g.decl("client.EXPECT().Collection(%q).Return(%s).AnyTimes()", path, v)
+	if i := strings.LastIndex(path, "/"); i >= 0 {
+		g.decl("%s.EXPECT().Collection(%q).Return(%s).AnyTimes()", g.doc(path[:i]), path[i+1:], v)
+	}
+	g.decl("%s.EXPECT().ID().Return(%q).AnyTimes()", v, lastSegment(path))
+	g.decl("%s.EXPECT().Path().Return(%q).AnyTimes()", v, g.full(path))
+	return v
+}
+
+func (g *generator) doc(path string) string {
+	if v, ok := g.docs[path]; ok {
+		return v
+	}
+	i := strings.LastIndex(path, "/")
+	if i < 0 {
+		return "nil /* invalid document path " + strconv.Quote(path) + " */"
+	}
+	coll := g.coll(path[:i])
+	v := g.name(pathName(path) + "Doc")
+	g.docs[path] = v
+	g.decl("%s := gofirestoremock.NewMockDocumentRef(ctrl)", v)
+	g.decl("client.EXPECT().Doc(%q).Return(%s).AnyTimes()", path, v)
+	g.decl("%s.EXPECT().Doc(%q).Return(%s).AnyTimes()", coll, path[i+1:], v)
+	g.decl("%s.EXPECT().ID().Return(%q).AnyTimes()", v, path[i+1:])
+	g.decl("%s.EXPECT().Path().Return(%q).AnyTimes()", v, g.full(path))
+	g.decl("%s.EXPECT().Reference().Return(%s).AnyTimes()", v, g.refLit(g.full(path)))
+	return v
+}
+
+// query returns the MockQuery returned by every query builder of the
+// collection at path.
+func (g *generator) query(path string) string {
+	if v, ok := g.queries[path]; ok {
+		return v
+	}
+	coll := g.coll(path)
+	v := g.name(pathName(path) + "Query")
+	g.queries[path] = v
+	g.decl("%s := gofirestoremock.NewMockQuery(ctrl)", v)
+	g.builders(coll, v)
+	g.builders(v, v)
+	return v
+}
+
+// group returns the MockQuery of the collection group with the given ID.
+func (g *generator) group(id string) string {
+	if v, ok := g.groups[id]; ok {
+		return v
+	}
+	v := g.name(pathName(id) + "Group")
+	g.groups[id] = v
+	g.decl("%s := gofirestoremock.NewMockQuery(ctrl)", v)
+	g.decl("client.EXPECT().CollectionGroup(%q).Return(%s).AnyTimes()", id, v)
+	g.builders(v, v)
+	return v
+}
+
+func (g *generator) builders(recv, q string) {
+	for _, m := range queryBuilders {
+		anys := strings.TrimSuffix(strings.Repeat("gomock.Any(), ", m.params), ", ")
+		g.decl("%s.EXPECT().%s(%s).Return(%s).AnyTimes()", recv, m.name, anys, q)
+	}
+}
+
+func lastSegment(path string) string {
+	return path[strings.LastIndex(path, "/")+1:]
+}
+
+// full returns the resource name of path, which may already be one.
+func (g *generator) full(path string) string {
+	if strings.HasPrefix(path, "projects/") {
+		return path
+	}
+	return g.root + "/" + path
+}
+
+func (g *generator) refLit(path string) string {
+	g.imports["cloud.google.com/go/firestore"] = true
+	path = g.full(path)
+	return fmt.Sprintf("&firestore.DocumentRef{ID: %q, Path: %q}", lastSegment(path), path)
+}
+
+func (g *generator) collRefLit(path string) string {
+	g.imports["cloud.google.com/go/firestore"] = true
+	path = g.full(path)
+	return fmt.Sprintf("&firestore.CollectionRef{ID: %q, Path: %q}", lastSegment(path), path)
+}
+
+func (g *generator) writeResult(t *time.Time) string {
+	if t == nil {
+		return "nil"
+	}
+	g.imports["cloud.google.com/go/firestore"] = true
+	return "&firestore.WriteResult{UpdateTime: " + g.timeLit(*t) + "}"
+}
+
+func (g *generator) timeLit(t time.Time) string {
+	g.imports["time"] = true
+	t = t.UTC()
+	return fmt.Sprintf("time.Date(%d, %d, %d, %d, %d, %d, %d, time.UTC)", t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond())
+}
+
+func (g *generator) err(e *gofirestoremock.RecordedError) string {
+	switch {
+	case e == nil:
+		return "nil"
+	case e.Done:
+		g.imports["google.golang.org/api/iterator"] = true
+		return "iterator.Done"
+	case e.Code == "":
+		g.imports["errors"] = true
+		return fmt.Sprintf("errors.New(%q)", e.Message)
+	}
+	g.imports["google.golang.org/grpc/codes"] = true
+	g.imports["google.golang.org/grpc/status"] = true
+	return fmt.Sprintf("status.Error(codes.%s, %q)", e.Code, e.Message)
+}
+
+// snap renders a *firestore.DocumentSnapshot of doc.
+func (g *generator) snap(doc *gofirestoremock.RecordedDoc) string {
+	if doc == nil {
+		return "nil"
+	}
+	g.usesSnap = true
+	g.imports["cloud.google.com/go/firestore"] = true
+	if !doc.Exists {
+		return fmt.Sprintf("snap(%q, nil)", doc.Path)
+	}
+	fields, err := g.fieldsLit(doc.Fields)
+	if err != nil {
+		return fmt.Sprintf("nil /* %s: %v */", doc.Path, err)
+	}
+	return fmt.Sprintf("snap(%q, %s)", doc.Path, fields)
+}
+
+// wrappedSnap renders a DocumentSnapshot of doc.
+func (g *generator) wrappedSnap(doc *gofirestoremock.RecordedDoc) string {
+	if doc == nil {
+		return "nil"
+	}
+	return "gofirestoremock.NewDocumentSnapshot(" + g.snap(doc) + ")"
+}
+
+func (g *generator) wrappedSnaps(docs []gofirestoremock.RecordedDoc) string {
+	if docs == nil {
+		return "nil"
+	}
+	parts := make([]string, len(docs))
+	for i := range docs {
+		parts[i] = g.wrappedSnap(&docs[i])
+	}
+	return "[]gofirestoremock.DocumentSnapshot{" + strings.Join(parts, ", ") + "}"
+}
+
+func (g *generator) fieldsLit(fields map[string]json.RawMessage) (string, error) {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		v := &pb.Value{}
+		if err := protojson.Unmarshal(fields[k], v); err != nil {
+			return "", fmt.Errorf("field %q: %w", k, err)
+		}
+		parts[i] = strconv.Quote(k) + ": " + g.valueLit(v)
+	}
+	return "map[string]any{" + strings.Join(parts, ", ") + "}", nil
+}
+
+// valueLit renders v as the Go value DocumentSnapshot.Data returns for it.
+func (g *generator) valueLit(v *pb.Value) string {
+	switch v := v.ValueType.(type) {
+	case *pb.Value_NullValue:
+		return "nil"
+	case *pb.Value_BooleanValue:
+		return strconv.FormatBool(v.BooleanValue)
+	case *pb.Value_IntegerValue:
+		return fmt.Sprintf("int64(%d)", v.IntegerValue)
+	case *pb.Value_DoubleValue:
+		return g.floatLit(v.DoubleValue)
+	case *pb.Value_TimestampValue:
+		return g.timeLit(v.TimestampValue.AsTime())
+	case *pb.Value_StringValue:
+		return strconv.Quote(v.StringValue)
+	case *pb.Value_BytesValue:
+		return "[]byte(" + strconv.Quote(string(v.BytesValue)) + ")"
+	case *pb.Value_ReferenceValue:
+		return g.refLit(v.ReferenceValue)
+	case *pb.Value_GeoPointValue:
+		g.imports["google.golang.org/genproto/googleapis/type/latlng"] = true
+		return fmt.Sprintf("&latlng.LatLng{Latitude: %s, Longitude: %s}",
+			g.numberLit(v.GeoPointValue.Latitude),
+			g.numberLit(v.GeoPointValue.Longitude))
+	case *pb.Value_ArrayValue:
+		parts := make([]string, len(v.ArrayValue.GetValues()))
+		for i, x := range v.ArrayValue.GetValues() {
+			parts[i] = g.valueLit(x)
+		}
+		return "[]any{" + strings.Join(parts, ", ") + "}"
+	case *pb.Value_MapValue:
+		fields := v.MapValue.GetFields()
+		if fields["__type__"].GetStringValue() == "__vector__" {
+			g.imports["cloud.google.com/go/firestore"] = true
+			vals := fields["value"].GetArrayValue().GetValues()
+			parts := make([]string, len(vals))
+			for i, x := range vals {
+				parts[i] = g.numberLit(x.GetDoubleValue())
+			}
+			return "firestore.Vector64{" + strings.Join(parts, ", ") + "}"
+		}
+		keys := make([]string, 0, len(fields))
+		for k := range fields {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		parts := make([]string, len(keys))
+		for i, k := range keys {
+			parts[i] = strconv.Quote(k) + ": " + g.valueLit(fields[k])
+		}
+		return "map[string]any{" + strings.Join(parts, ", ") + "}"
+	}
+	return "nil"
+}
+
+// floatLit renders f as a float64 value in an untyped context.
+func (g *generator) floatLit(f float64) string {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return g.numberLit(f)
+	}
+	return "float64(" + g.numberLit(f) + ")"
+}
+
+// numberLit renders f for a float64 field or element.
+func (g *generator) numberLit(f float64) string {
+	switch {
+	case math.IsNaN(f):
+		g.imports["math"] = true
+		return "math.NaN()"
+	case math.IsInf(f, 1):
+		g.imports["math"] = true
+		return "math.Inf(1)"
+	case math.IsInf(f, -1):
+		g.imports["math"] = true
+		return "math.Inf(-1)"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+func mustJSON(v any) json.RawMessage {
+	b, _ := json.Marshal(v)
+	return b
+}
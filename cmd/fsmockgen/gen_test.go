@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	pb "cloud.google.com/go/firestore/apiv1/firestorepb"
+	gofirestoremock "github.com/akmalsyrf/go-firestore-mock"
+	"google.golang.org/genproto/googleapis/type/latlng"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestGenerate_ExampleUpToDate(t *testing.T) {
+	f, err := os.Open("example/testdata/promote.jsonl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	entries, err := gofirestoremock.ReadRecording(f)
+	if err != nil {
+		t.Fatalf("ReadRecording() error = %v", err)
+	}
+	got, err := generate(entries, options{Package: "example", Func: "newPromoteClient", Source: "promote.jsonl"})
+	if err != nil {
+		t.Fatalf("generate() error = %v", err)
+	}
+	want, err := os.ReadFile("example/promote_mocks_test.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("example/promote_mocks_test.go is stale; run go generate ./cmd/fsmockgen/example")
+	}
+}
+
+func TestGenerate_Operations(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		log   string
+		wants []string
+	}{
+		{
+			name: "recorded errors",
+			log: `{"seq":1,"op":"DocumentRef.Delete","path":"users/u1","call":1,"args":[[]],"error":{"code":"PermissionDenied","message":"nope"}}
+{"seq":2,"op":"DocumentRef.Create","path":"users/u1","call":1,"args":[{}],"error":{"message":"plain"}}`,
+			wants: []string{
+				"usersU1Doc.EXPECT().Delete(gomock.Any(), gofirestoremock.RecordedArg(`[]`)).Return(nil, status.Error(codes.PermissionDenied, \"nope\"))",
+				"usersU1Doc.EXPECT().Create(gomock.Any(), gofirestoremock.RecordedArg(`{}`)).Return(nil, errors.New(\"plain\"))",
+				`client.EXPECT().Doc("users/u1").Return(usersU1Doc).AnyTimes()`,
+			},
+		},
+		{
+			name: "bulk writer",
+			log: `{"seq":1,"op":"BulkWriter.Delete","path":"users/u1","call":1,"args":[[]]}
+{"seq":2,"op":"BulkWriterJob.Results","path":"users/u1","call":1,"result":{"writeTime":"2024-05-01T12:00:00Z"}}`,
+			wants: []string{
+				"bulkWriter.EXPECT().Delete(gofirestoremock.RecordedArg(`\"users/u1\"`), gofirestoremock.RecordedArg(`[]`)).Return(job, nil)",
+				"job.EXPECT().Results().Return(&firestore.WriteResult{UpdateTime: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}, nil)",
+			},
+		},
+		{
+			name: "collection group iterator ending in an error",
+			log: `{"seq":1,"op":"DocumentIterator.Next","path":"posts","call":1,"query":"collection group posts where n > 1","result":{"doc":{"path":"projects/p/databases/(default)/documents/users/u1/posts/a","exists":true,"fields":{"n":{"integerValue":"2"}}}}}
+{"seq":2,"op":"DocumentIterator.Next","path":"posts","call":2,"query":"collection group posts where n > 1","error":{"code":"Unavailable","message":"down"}}`,
+			wants: []string{
+				`client.EXPECT().CollectionGroup("posts").Return(postsGroup).AnyTimes()`,
+				`postsGroup.EXPECT().Documents(gomock.Any()).Return(gofirestoremock.NewDocumentIteratorFromSlice([]*firestore.DocumentSnapshot{snap("projects/p/databases/(default)/documents/users/u1/posts/a", map[string]any{"n": int64(2)})}, status.Error(codes.Unavailable, "down")))`,
+			},
+		},
+		{
+			name: "iterator GetAll",
+			log:  `{"seq":1,"op":"DocumentIterator.GetAll","path":"users","call":1,"query":"users order by age desc limit to last 1","result":{"docs":[{"path":"projects/p/databases/(default)/documents/users/u1","exists":true,"fields":{"age":{"integerValue":"30"}}}]}}`,
+			wants: []string{
+				"// The query builders (Where, OrderBy, Limit, ...) expect gomock.Any()",
+				"// #1 users order by age desc limit to last 1",
+				`usersQuery.EXPECT().Documents(gomock.Any()).Return(gofirestoremock.NewDocumentIteratorFromSlice([]*firestore.DocumentSnapshot{snap("projects/p/databases/(default)/documents/users/u1", map[string]any{"age": int64(30)})}, nil))`,
+			},
+		},
+		{
+			name: "document refs of a subcollection",
+			log: `{"seq":1,"op":"DocumentRefIterator.Next","path":"users/u1/posts","call":1,"result":{"ref":"projects/p/databases/(default)/documents/users/u1/posts/a"}}
+{"seq":2,"op":"DocumentRefIterator.Next","path":"users/u1/posts","call":2,"error":{"done":true}}`,
+			wants: []string{
+				`usersU1Doc.EXPECT().Collection("posts").Return(usersU1PostsColl).AnyTimes()`,
+				`usersU1PostsColl.EXPECT().DocumentRefs(gomock.Any()).Return(gofirestoremock.NewDocumentRefIteratorFromSlice([]*firestore.DocumentRef{&firestore.DocumentRef{ID: "a", Path: "projects/p/databases/(default)/documents/users/u1/posts/a"}}, nil))`,
+			},
+		},
+		{
+			name: "add",
+			log:  `{"seq":1,"op":"CollectionRef.Add","path":"users","call":1,"args":[{"at":"` + at.Format(time.RFC3339Nano) + `"}],"result":{"ref":"projects/p/databases/(default)/documents/users/x1"}}`,
+			wants: []string{
+				"usersColl.EXPECT().Add(gomock.Any(), gofirestoremock.RecordedArg(`{\"at\":\"2024-05-01T12:00:00Z\"}`)).Return(&firestore.DocumentRef{ID: \"x1\", Path: \"projects/p/databases/(default)/documents/users/x1\"}, nil, nil)",
+			},
+		},
+		{
+			name:  "unsupported operation",
+			log:   `{"seq":1,"op":"QuerySnapshotIterator.Next","path":"users","call":1,"query":"users"}`,
+			wants: []string{"// #1 QuerySnapshotIterator.Next users: not supported by fsmockgen"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := gofirestoremock.ReadRecording(strings.NewReader(tt.log))
+			if err != nil {
+				t.Fatalf("ReadRecording() error = %v", err)
+			}
+			src, err := generate(entries, options{Package: "p", Func: "newClient", Source: "test.jsonl"})
+			if err != nil {
+				t.Fatalf("generate() error = %v", err)
+			}
+			for _, want := range tt.wants {
+				if !strings.Contains(string(src), want) {
+					t.Errorf("generated code does not contain\n%s\n\n%s", want, src)
+				}
+			}
+		})
+	}
+}
+
+func TestValueLit(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 30, 0, 5, time.UTC)
+	tests := []struct {
+		name string
+		v    *pb.Value
+		want string
+	}{
+		{"null", &pb.Value{ValueType: &pb.Value_NullValue{}}, "nil"},
+		{"bool", &pb.Value{ValueType: &pb.Value_BooleanValue{BooleanValue: true}}, "true"},
+		{"integer", &pb.Value{ValueType: &pb.Value_IntegerValue{IntegerValue: -3}}, "int64(-3)"},
+		{"double", &pb.Value{ValueType: &pb.Value_DoubleValue{DoubleValue: 1.5}}, "float64(1.5)"},
+		{"timestamp", &pb.Value{ValueType: &pb.Value_TimestampValue{TimestampValue: timestamppb.New(at)}}, "time.Date(2024, 5, 1, 12, 30, 0, 5, time.UTC)"},
+		{"string", &pb.Value{ValueType: &pb.Value_StringValue{StringValue: "a\"b"}}, `"a\"b"`},
+		{"bytes", &pb.Value{ValueType: &pb.Value_BytesValue{BytesValue: []byte{0, 'x'}}}, `[]byte("\x00x")`},
+		{"reference", &pb.Value{ValueType: &pb.Value_ReferenceValue{ReferenceValue: "projects/p/databases/(default)/documents/users/u1"}}, `&firestore.DocumentRef{ID: "u1", Path: "projects/p/databases/(default)/documents/users/u1"}`},
+		{"geo point", &pb.Value{ValueType: &pb.Value_GeoPointValue{GeoPointValue: &latlng.LatLng{Latitude: 1, Longitude: -2.5}}}, "&latlng.LatLng{Latitude: 1, Longitude: -2.5}"},
+		{
+			name: "array and map",
+			v: &pb.Value{ValueType: &pb.Value_ArrayValue{ArrayValue: &pb.ArrayValue{Values: []*pb.Value{
+				{ValueType: &pb.Value_MapValue{MapValue: &pb.MapValue{Fields: map[string]*pb.Value{
+					"b": {ValueType: &pb.Value_StringValue{StringValue: "x"}},
+					"a": {ValueType: &pb.Value_IntegerValue{IntegerValue: 1}},
+				}}}},
+			}}}},
+			want: `[]any{map[string]any{"a": int64(1), "b": "x"}}`,
+		},
+		{
+			name: "vector",
+			v: &pb.Value{ValueType: &pb.Value_MapValue{MapValue: &pb.MapValue{Fields: map[string]*pb.Value{
+				"__type__": {ValueType: &pb.Value_StringValue{StringValue: "__vector__"}},
+				"value": {ValueType: &pb.Value_ArrayValue{ArrayValue: &pb.ArrayValue{Values: []*pb.Value{
+					{ValueType: &pb.Value_DoubleValue{DoubleValue: 0.5}},
+					{ValueType: &pb.Value_DoubleValue{DoubleValue: 2}},
+				}}}},
+			}}}},
+			want: "firestore.Vector64{0.5, 2}",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := &generator{imports: map[string]bool{}, root: "projects/p/databases/(default)/documents"}
+			if got := g.valueLit(tt.v); got != tt.want {
+				t.Errorf("valueLit() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+// Command fsmockgen generates gomock expectations from a session recorded with
+// Record: a function returning a MockFirestoreClient whose collections,
+// documents, queries, batches, bulk writers and transactions expect the
+// recorded calls, with the recorded arguments, and return the recorded
+// results.
+//
+// Usage:
+//
+//	fsmockgen -in testdata/checkout.jsonl -out checkout_mocks_test.go -package checkout -func newCheckoutClient
+//
+// The generated function has the signature
+//
+//	func newCheckoutClient(t testing.TB, ctrl *gomock.Controller) *gofirestoremock.MockFirestoreClient
+//
+// Arguments are matched with gofirestoremock.RecordedArg. Navigation
+// (Collection, Doc, Path, ID, Reference) and query building (Where, OrderBy,
+// ...) are allowed any number of times; the queries themselves are not
+// checked, their iterators are served in the recorded order. Snapshots are
+// built with gofirestoremock.SnapshotFromData and lose their recorded times.
+// QuerySnapshotIterator results are not generated.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	gofirestoremock "github.com/akmalsyrf/go-firestore-mock"
+)
+
+func main() {
+	in := flag.String("in", "-", "recorded session (JSON lines written by Record); - for stdin")
+	out := flag.String("out", "-", "generated Go file; - for stdout")
+	pkg := flag.String("package", "", "package of the generated file (default: the name of the -out directory)")
+	fn := flag.String("func", "newRecordedClient", "name of the generated function")
+	flag.Parse()
+
+	if err := run(*in, *out, *pkg, *fn); err != nil {
+		fmt.Fprintln(os.Stderr, "fsmockgen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(in, out, pkg, fn string) error {
+	var r io.Reader = os.Stdin
+	if in != "-" {
+		f, err := os.Open(in)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	entries, err := gofirestoremock.ReadRecording(r)
+	if err != nil {
+		return err
+	}
+	if pkg == "" {
+		pkg = "main"
+		if out != "-" {
+			if abs, err := filepath.Abs(out); err == nil {
+				pkg = filepath.Base(filepath.Dir(abs))
+			}
+		}
+	}
+	src, err := generate(entries, options{Package: pkg, Func: fn, Source: filepath.Base(in)})
+	if err != nil {
+		return err
+	}
+	if out == "-" {
+		_, err = os.Stdout.Write(src)
+		return err
+	}
+	return os.WriteFile(out, src, 0o644)
+}
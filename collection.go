@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"cloud.google.com/go/firestore"
+	"github.com/akmalsyrf/go-firestore-mock/internal/fsvalue"
 )
 
 // CollectionRef abstracts Firestore collection behavior used by repos.
@@ -33,15 +34,15 @@ func (w *collectionRefWrapper) Add(ctx context.Context, data any) (*firestore.Do
 }
 
 func (w *collectionRefWrapper) Where(path string, op string, value any) Query {
-	return &queryWrapper{q: w.ref.Where(path, op, value)}
+	return &queryWrapper{q: w.ref.Where(path, op, value), spec: w.querySpec().where(path, op, value)}
 }
 
 func (w *collectionRefWrapper) WherePath(fp firestore.FieldPath, op string, value any) Query {
-	return &queryWrapper{q: w.ref.WherePath(fp, op, value)}
+	return &queryWrapper{q: w.ref.WherePath(fp, op, value), spec: w.querySpec().where(fsvalue.FieldPathString(fp), op, value)}
 }
 
 func (w *collectionRefWrapper) WhereEntity(ef firestore.EntityFilter) Query {
-	return &queryWrapper{q: w.ref.WhereEntity(ef)}
+	return &queryWrapper{q: w.ref.WhereEntity(ef), spec: w.querySpec().whereEntity(ef)}
 }
 
 func (w *collectionRefWrapper) Documents(ctx context.Context) DocumentIterator {
@@ -49,47 +50,47 @@ func (w *collectionRefWrapper) Documents(ctx context.Context) DocumentIterator {
 }
 
 func (w *collectionRefWrapper) OrderBy(path string, dir firestore.Direction) Query {
-	return &queryWrapper{q: w.ref.OrderBy(path, dir)}
+	return &queryWrapper{q: w.ref.OrderBy(path, dir), spec: w.querySpec().orderBy(path, dir)}
 }
 
 func (w *collectionRefWrapper) OrderByPath(fp firestore.FieldPath, dir firestore.Direction) Query {
-	return &queryWrapper{q: w.ref.OrderByPath(fp, dir)}
+	return &queryWrapper{q: w.ref.OrderByPath(fp, dir), spec: w.querySpec().orderBy(fsvalue.FieldPathString(fp), dir)}
 }
 
 func (w *collectionRefWrapper) Limit(n int) Query {
-	return &queryWrapper{q: w.ref.Limit(n)}
+	return &queryWrapper{q: w.ref.Limit(n), spec: w.querySpec().limit(n, false)}
 }
 
 func (w *collectionRefWrapper) LimitToLast(n int) Query {
-	return &queryWrapper{q: w.ref.LimitToLast(n)}
+	return &queryWrapper{q: w.ref.LimitToLast(n), spec: w.querySpec().limit(n, true)}
 }
 
 func (w *collectionRefWrapper) Offset(n int) Query {
-	return &queryWrapper{q: w.ref.Offset(n)}
+	return &queryWrapper{q: w.ref.Offset(n), spec: w.querySpec().offset(n)}
 }
 
 func (w *collectionRefWrapper) StartAt(docSnapshotOrFieldValues ...any) Query {
-	return &queryWrapper{q: w.ref.StartAt(docSnapshotOrFieldValues...)}
+	return &queryWrapper{q: w.ref.StartAt(docSnapshotOrFieldValues...), spec: w.querySpec().startAt(docSnapshotOrFieldValues, true)}
 }
 
 func (w *collectionRefWrapper) StartAfter(docSnapshotOrFieldValues ...any) Query {
-	return &queryWrapper{q: w.ref.StartAfter(docSnapshotOrFieldValues...)}
+	return &queryWrapper{q: w.ref.StartAfter(docSnapshotOrFieldValues...), spec: w.querySpec().startAt(docSnapshotOrFieldValues, false)}
 }
 
 func (w *collectionRefWrapper) EndAt(docSnapshotOrFieldValues ...any) Query {
-	return &queryWrapper{q: w.ref.EndAt(docSnapshotOrFieldValues...)}
+	return &queryWrapper{q: w.ref.EndAt(docSnapshotOrFieldValues...), spec: w.querySpec().endAt(docSnapshotOrFieldValues, true)}
 }
 
 func (w *collectionRefWrapper) EndBefore(docSnapshotOrFieldValues ...any) Query {
-	return &queryWrapper{q: w.ref.EndBefore(docSnapshotOrFieldValues...)}
+	return &queryWrapper{q: w.ref.EndBefore(docSnapshotOrFieldValues...), spec: w.querySpec().endAt(docSnapshotOrFieldValues, false)}
 }
 
 func (w *collectionRefWrapper) Select(paths ...string) Query {
-	return &queryWrapper{q: w.ref.Select(paths...)}
+	return &queryWrapper{q: w.ref.Select(paths...), spec: w.querySpec().selectPaths(paths)}
 }
 
 func (w *collectionRefWrapper) SelectPaths(fieldPaths ...firestore.FieldPath) Query {
-	return &queryWrapper{q: w.ref.SelectPaths(fieldPaths...)}
+	return &queryWrapper{q: w.ref.SelectPaths(fieldPaths...), spec: w.querySpec().selectPaths(fieldPathStrings(fieldPaths))}
 }
 
 func (w *collectionRefWrapper) Snapshots(ctx context.Context) QuerySnapshotIterator {
@@ -126,3 +127,7 @@ func (w *collectionRefWrapper) ID() string {
 func (w *collectionRefWrapper) Path() string {
 	return w.ref.Path
 }
+
+func (w *collectionRefWrapper) querySpec() QuerySpec {
+	return collectionSpec(w.ref)
+}
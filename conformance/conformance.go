@@ -0,0 +1,270 @@
+// Package conformance is a suite of scenarios checking that a
+// gofirestoremock.FirestoreClient behaves like Firestore: document reads and
+// writes, merges, field transforms, queries with every operator, ordering
+// across value types, cursors, limits, aggregations, batched writes,
+// transactions and listeners.
+//
+// The expected results are those of Firestore. Run the suite against a fake
+// to find where it departs from Firestore, and against NewFirestoreClient on
+// an SDK client connected to the emulator (or another stand-in server) to
+// check the expectations themselves:
+//
+//	func TestConformance(t *testing.T) {
+//		conformance.Run(t, func(t *testing.T) gofirestoremock.FirestoreClient {
+//			return newFakeClient(t)
+//		})
+//	}
+//
+// Besides hand-written scenarios, the suite generates tables: every filter
+// operator against values of every ordered type, orderings with limits and
+// offsets, cursors in both directions, counts and value round trips.
+//
+// Compare runs the scenarios against a reference and a candidate client and
+// reports where their results differ, e.g. to check a fake against the
+// emulator on the scenarios it supports.
+//
+// TestEmulator checks the expectations against the emulator; it skips
+// unless FIRESTORE_EMULATOR_HOST is set, which the conformance workflow of
+// this repository does. Run it against the emulator after changing
+// scenarios.
+package conformance
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	gofirestoremock "github.com/akmalsyrf/go-firestore-mock"
+	"github.com/akmalsyrf/go-firestore-mock/internal/fsvalue"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ClientFactory returns the client a scenario runs against. It is called
+// once per scenario; clients may share a database, since every scenario
+// works under collections of its own. Register any cleanup with t.Cleanup.
+type ClientFactory func(t *testing.T) gofirestoremock.FirestoreClient
+
+// scenario is a named check of the suite.
+type scenario struct {
+	name string
+	run  func(e *env)
+}
+
+// scenarios returns the scenarios of the suite, by area.
+func scenarios() []scenario {
+	var all []scenario
+	for _, area := range [][]scenario{
+		documentScenarios(), transformScenarios(), roundTripScenarios(),
+		queryScenarios(), filterMatrixScenarios(), orderMatrixScenarios(), cursorScenarios(), countScenarios(),
+		writeScenarios(), listenScenarios(),
+	} {
+		all = append(all, area...)
+	}
+	return all
+}
+
+// Run runs every scenario of the suite as a subtest of t, against clients
+// returned by newClient.
+func Run(t *testing.T, newClient ClientFactory) {
+	t.Helper()
+	run := fmt.Sprintf("%x", time.Now().UnixNano())
+	for _, s := range scenarios() {
+		t.Run(s.name, func(t *testing.T) {
+			runScenario(t, s, newClient, run)
+		})
+	}
+}
+
+// Compare runs the scenarios of the suite whose names start with one of
+// prefixes (all of them if there are none) against both clients, e.g. the
+// emulator as reference and a fake as candidate, and reports where the
+// candidate observes other results than the reference: documents read, query
+// results, counts and error codes. Each scenario runs as a subtest of t with
+// "reference" and "candidate" subtests, which also check the expectations of
+// the suite.
+func Compare(t *testing.T, reference, candidate ClientFactory, prefixes ...string) {
+	t.Helper()
+	run := fmt.Sprintf("%x", time.Now().UnixNano())
+	for _, s := range scenarios() {
+		if len(prefixes) > 0 && !slices.ContainsFunc(prefixes, func(p string) bool { return strings.HasPrefix(s.name, p) }) {
+			continue
+		}
+		t.Run(s.name, func(t *testing.T) {
+			var want, got []string
+			t.Run("reference", func(t *testing.T) { want = runScenario(t, s, reference, run) })
+			t.Run("candidate", func(t *testing.T) { got = runScenario(t, s, candidate, run) })
+			if want == nil || got == nil {
+				return // a run stopped early and reported why
+			}
+			for i := range max(len(want), len(got)) {
+				w, g := "(none)", "(none)"
+				if i < len(want) {
+					w = want[i]
+				}
+				if i < len(got) {
+					g = got[i]
+				}
+				if w != g {
+					t.Errorf("observation %d: candidate %s, reference %s", i+1, g, w)
+				}
+			}
+		})
+	}
+}
+
+// runScenario runs s against a client returned by newClient and returns what
+// it observed, or nil if it stopped early.
+func runScenario(t *testing.T, s scenario, newClient ClientFactory, run string) []string {
+	e := &env{
+		t:        t,
+		ctx:      t.Context(),
+		client:   newClient(t),
+		coll:     fmt.Sprintf("conformance-%s-%d", run, seq.Add(1)),
+		observed: []string{},
+	}
+	s.run(e)
+	return e.observed
+}
+
+// seq numbers the collections of the scenarios.
+var seq atomic.Int64
+
+// env is the state of a running scenario.
+type env struct {
+	t      *testing.T
+	ctx    context.Context
+	client gofirestoremock.FirestoreClient
+	// coll is the ID of the root collection of the scenario, unique to it.
+	coll string
+	// observed holds the results checked by the want helpers, in order, for
+	// Compare.
+	observed []string
+}
+
+// observe records a result checked by the scenario, with the root
+// collection ID replaced by "$coll" to compare it across runs.
+func (e *env) observe(format string, args ...any) {
+	e.observed = append(e.observed, strings.ReplaceAll(fmt.Sprintf(format, args...), e.coll, "$coll"))
+}
+
+// collection returns the root collection of the scenario.
+func (e *env) collection() gofirestoremock.CollectionRef {
+	return e.client.Collection(e.coll)
+}
+
+// doc returns the document id of the root collection of the scenario.
+func (e *env) doc(id string) gofirestoremock.DocumentRef {
+	return e.collection().Doc(id)
+}
+
+// set writes the documents of docs, by ID, to the root collection.
+func (e *env) set(docs map[string]map[string]any) {
+	e.t.Helper()
+	for id, data := range docs {
+		if _, err := e.doc(id).Set(e.ctx, data); err != nil {
+			e.t.Fatalf("Set %s: %v", id, err)
+		}
+	}
+}
+
+// get returns the data of the document id, or nil if it does not exist.
+func (e *env) get(id string) map[string]any {
+	e.t.Helper()
+	snap, err := e.doc(id).Get(e.ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil
+	}
+	if err != nil {
+		e.t.Fatalf("Get %s: %v", id, err)
+	}
+	if !snap.Exists() {
+		return nil
+	}
+	return snap.Data()
+}
+
+// wantDoc checks that the document id holds exactly want, or does not
+// exist if want is nil. It observes the data read only when it differs from
+// want, which may hold server-assigned values such as commit times.
+func (e *env) wantDoc(id string, want map[string]any) {
+	e.t.Helper()
+	got := e.get(id)
+	switch {
+	case got == nil && want == nil:
+		e.observe("document: missing")
+	case got == nil:
+		e.observe("document: missing")
+		e.t.Errorf("%s does not exist, want %s", id, fsvalue.Format(want))
+	case want == nil:
+		e.observe("document: %s", fsvalue.Format(got))
+		e.t.Errorf("%s = %s, want missing", id, fsvalue.Format(got))
+	default:
+		changes := gofirestoremock.Diff(gofirestoremock.Snapshot{id: want}, gofirestoremock.Snapshot{id: got})
+		if len(changes) > 0 {
+			e.observe("document: %s", fsvalue.Format(got))
+			e.t.Errorf("%s differs (-want +got):\n%s", id, gofirestoremock.FormatChanges(changes))
+		} else {
+			e.observe("document: as wanted")
+		}
+	}
+}
+
+// wantCode checks that err has the gRPC code want.
+func (e *env) wantCode(op string, err error, want codes.Code) {
+	e.t.Helper()
+	got := status.Code(err)
+	e.observe("%s: code %v", op, got)
+	if got != want {
+		e.t.Errorf("%s: error %v (code %v), want code %v", op, err, got, want)
+	}
+}
+
+// snapshots returns the results of q, read with GetAll, which the SDK needs
+// for LimitToLast queries.
+func (e *env) snapshots(q gofirestoremock.Query) []*firestore.DocumentSnapshot {
+	e.t.Helper()
+	snaps, err := q.Documents(e.ctx).GetAll()
+	if err != nil {
+		e.t.Fatalf("running query: %v", err)
+	}
+	return snaps
+}
+
+// wantIDs checks that q returns the documents want, in order.
+func (e *env) wantIDs(q gofirestoremock.Query, want ...string) {
+	e.t.Helper()
+	got := []string{}
+	for _, snap := range e.snapshots(q) {
+		got = append(got, snap.Ref.ID)
+	}
+	if want == nil {
+		want = []string{}
+	}
+	e.observe("query: %v", got)
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		e.t.Errorf("query returned %v, want %v", got, want)
+	}
+}
+
+// wantCount checks that q counts want documents.
+func (e *env) wantCount(q gofirestoremock.Query, want int64) {
+	e.t.Helper()
+	res, err := q.NewAggregationQuery().WithCount("n").Get(e.ctx)
+	if err != nil {
+		e.t.Fatalf("Get: %v", err)
+	}
+	n, err := res.Count("n")
+	if err != nil {
+		e.t.Fatalf("Count: %v", err)
+	}
+	e.observe("count: %d", *n)
+	if *n != want {
+		e.t.Errorf("count = %d, want %d", *n, want)
+	}
+}
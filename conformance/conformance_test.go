@@ -0,0 +1,40 @@
+package conformance
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/firestore"
+	gofirestoremock "github.com/akmalsyrf/go-firestore-mock"
+)
+
+// TestEmulator runs the suite against the SDK wrapper connected to the
+// emulator at FIRESTORE_EMULATOR_HOST, checking the expectations of the
+// suite against Firestore.
+func TestEmulator(t *testing.T) {
+	if os.Getenv("FIRESTORE_EMULATOR_HOST") == "" {
+		t.Skip("FIRESTORE_EMULATOR_HOST is not set")
+	}
+	Run(t, func(t *testing.T) gofirestoremock.FirestoreClient {
+		client, err := firestore.NewClient(t.Context(), "conformance-test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { client.Close() })
+		return gofirestoremock.NewFirestoreClient(client)
+	})
+}
+
+func TestScenarioNames(t *testing.T) {
+	seen := map[string]bool{}
+	for _, s := range scenarios() {
+		if seen[s.name] {
+			t.Errorf("duplicate scenario %q", s.name)
+		}
+		seen[s.name] = true
+		if area, _, ok := strings.Cut(s.name, "/"); !ok || area == "" {
+			t.Errorf("scenario %q is not named area/name", s.name)
+		}
+	}
+}
@@ -0,0 +1,250 @@
+package conformance
+
+import (
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+	"google.golang.org/genproto/googleapis/type/latlng"
+	"google.golang.org/grpc/codes"
+)
+
+func documentScenarios() []scenario {
+	return []scenario{
+		{"document/create and get every type", func(e *env) {
+			at := time.Date(2024, 5, 1, 10, 30, 0, 123456000, time.UTC)
+			data := map[string]any{
+				"null":   nil,
+				"bool":   true,
+				"int":    7,
+				"float":  1.5,
+				"string": "héllo",
+				"time":   at,
+				"bytes":  []byte("hi"),
+				"geo":    &latlng.LatLng{Latitude: 51.5, Longitude: -0.12},
+				"ref":    e.doc("other").Reference(),
+				"array":  []any{1, "a", []byte{0}},
+				"map":    map[string]any{"nested": map[string]any{"deep": 1.25}},
+				"vector": firestore.Vector64{1, 2.5},
+			}
+			if _, err := e.doc("d").Create(e.ctx, data); err != nil {
+				e.t.Fatalf("Create: %v", err)
+			}
+			e.wantDoc("d", data)
+		}},
+		{"document/create existing", func(e *env) {
+			e.set(map[string]map[string]any{"d": {"a": 1}})
+			_, err := e.doc("d").Create(e.ctx, map[string]any{"a": 2})
+			e.wantCode("Create", err, codes.AlreadyExists)
+			e.wantDoc("d", map[string]any{"a": 1})
+		}},
+		{"document/get missing", func(e *env) {
+			_, err := e.doc("missing").Get(e.ctx)
+			e.wantCode("Get", err, codes.NotFound)
+		}},
+		{"document/set struct", func(e *env) {
+			type user struct {
+				Name    string    `firestore:"name"`
+				Age     int       `firestore:"age,omitempty"`
+				Skipped string    `firestore:"-"`
+				Joined  time.Time `firestore:"joined,serverTimestamp"`
+			}
+			if _, err := e.doc("d").Set(e.ctx, user{Name: "Ada", Skipped: "x"}); err != nil {
+				e.t.Fatalf("Set: %v", err)
+			}
+			got := e.get("d")
+			if _, ok := got["joined"].(time.Time); !ok {
+				e.t.Errorf("joined = %v, want a server timestamp", got["joined"])
+			}
+			delete(got, "joined")
+			if len(got) != 1 || got["name"] != "Ada" {
+				e.t.Errorf("data = %v, want {name: Ada} and joined", got)
+			}
+		}},
+		{"document/set replaces", func(e *env) {
+			e.set(map[string]map[string]any{"d": {"a": 1, "b": 2}})
+			e.set(map[string]map[string]any{"d": {"a": 3}})
+			e.wantDoc("d", map[string]any{"a": 3})
+		}},
+		{"document/set merge all", func(e *env) {
+			e.set(map[string]map[string]any{"d": {"a": 1, "m": map[string]any{"x": 1, "y": 2}}})
+			if _, err := e.doc("d").Set(e.ctx, map[string]any{"m": map[string]any{"x": 5}, "c": 3}, firestore.MergeAll); err != nil {
+				e.t.Fatalf("Set: %v", err)
+			}
+			e.wantDoc("d", map[string]any{"a": 1, "m": map[string]any{"x": 5, "y": 2}, "c": 3})
+		}},
+		{"document/set merge fields", func(e *env) {
+			e.set(map[string]map[string]any{"d": {"a": 1, "b": 2}})
+			if _, err := e.doc("d").Set(e.ctx, map[string]any{"a": 5, "b": 9}, firestore.Merge([]string{"a"})); err != nil {
+				e.t.Fatalf("Set: %v", err)
+			}
+			e.wantDoc("d", map[string]any{"a": 5, "b": 2})
+		}},
+		{"document/set merge creates", func(e *env) {
+			if _, err := e.doc("d").Set(e.ctx, map[string]any{"a": 1}, firestore.MergeAll); err != nil {
+				e.t.Fatalf("Set: %v", err)
+			}
+			e.wantDoc("d", map[string]any{"a": 1})
+		}},
+		{"document/update nested field", func(e *env) {
+			e.set(map[string]map[string]any{"d": {"m": map[string]any{"x": 1, "y": 2}}})
+			if _, err := e.doc("d").Update(e.ctx, []firestore.Update{{Path: "m.x", Value: 5}, {Path: "n", Value: "new"}}); err != nil {
+				e.t.Fatalf("Update: %v", err)
+			}
+			e.wantDoc("d", map[string]any{"m": map[string]any{"x": 5, "y": 2}, "n": "new"})
+		}},
+		{"document/update field path with dots", func(e *env) {
+			e.set(map[string]map[string]any{"d": {"a.b": 1}})
+			if _, err := e.doc("d").Update(e.ctx, []firestore.Update{{FieldPath: firestore.FieldPath{"a.b"}, Value: 2}}); err != nil {
+				e.t.Fatalf("Update: %v", err)
+			}
+			e.wantDoc("d", map[string]any{"a.b": 2})
+		}},
+		{"document/update missing", func(e *env) {
+			_, err := e.doc("missing").Update(e.ctx, []firestore.Update{{Path: "a", Value: 1}})
+			e.wantCode("Update", err, codes.NotFound)
+			e.wantDoc("missing", nil)
+		}},
+		{"document/update delete field", func(e *env) {
+			e.set(map[string]map[string]any{"d": {"a": 1, "b": 2}})
+			if _, err := e.doc("d").Update(e.ctx, []firestore.Update{{Path: "b", Value: firestore.Delete}}); err != nil {
+				e.t.Fatalf("Update: %v", err)
+			}
+			e.wantDoc("d", map[string]any{"a": 1})
+		}},
+		{"document/update stale precondition", func(e *env) {
+			e.set(map[string]map[string]any{"d": {"a": 1}})
+			stale := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+			_, err := e.doc("d").Update(e.ctx, []firestore.Update{{Path: "a", Value: 2}}, firestore.LastUpdateTime(stale))
+			e.wantCode("Update", err, codes.FailedPrecondition)
+			e.wantDoc("d", map[string]any{"a": 1})
+		}},
+		{"document/delete", func(e *env) {
+			e.set(map[string]map[string]any{"d": {"a": 1}})
+			if _, err := e.doc("d").Delete(e.ctx); err != nil {
+				e.t.Fatalf("Delete: %v", err)
+			}
+			e.wantDoc("d", nil)
+		}},
+		{"document/delete missing", func(e *env) {
+			if _, err := e.doc("missing").Delete(e.ctx); err != nil {
+				e.t.Errorf("Delete: %v, want nil", err)
+			}
+		}},
+		{"document/delete missing with exists precondition", func(e *env) {
+			_, err := e.doc("missing").Delete(e.ctx, firestore.Exists)
+			e.wantCode("Delete", err, codes.NotFound)
+		}},
+		{"document/add generates id", func(e *env) {
+			ref, _, err := e.collection().Add(e.ctx, map[string]any{"a": 1})
+			if err != nil {
+				e.t.Fatalf("Add: %v", err)
+			}
+			if len(ref.ID) != 20 {
+				e.t.Errorf("generated ID %q, want 20 characters", ref.ID)
+			}
+			e.wantDoc(ref.ID, map[string]any{"a": 1})
+		}},
+		{"document/get all", func(e *env) {
+			e.set(map[string]map[string]any{"a": {"n": 1}, "b": {"n": 2}})
+			refs := []*firestore.DocumentRef{e.doc("b").Reference(), e.doc("missing").Reference(), e.doc("a").Reference()}
+			snaps, err := e.client.GetAll(e.ctx, refs)
+			if err != nil {
+				e.t.Fatalf("GetAll: %v", err)
+			}
+			if len(snaps) != 3 {
+				e.t.Fatalf("GetAll returned %d snapshots, want 3", len(snaps))
+			}
+			for i, want := range []bool{true, false, true} {
+				if snaps[i].Exists() != want {
+					e.t.Errorf("snapshot %d (%s): Exists() = %v, want %v", i, refs[i].ID, snaps[i].Exists(), want)
+				}
+			}
+		}},
+		{"document/subcollections", func(e *env) {
+			if _, err := e.doc("p").Collection("c1").Doc("x").Set(e.ctx, map[string]any{"a": 1}); err != nil {
+				e.t.Fatalf("Set: %v", err)
+			}
+			if _, err := e.doc("p").Collection("c2").Doc("y").Set(e.ctx, map[string]any{"a": 1}); err != nil {
+				e.t.Fatalf("Set: %v", err)
+			}
+			// The parent document does not exist but lists its subcollections.
+			e.wantDoc("p", nil)
+			it := e.doc("p").Collections(e.ctx)
+			defer it.Stop()
+			var ids []string
+			for {
+				coll, err := it.Next()
+				if err == iterator.Done {
+					break
+				}
+				if err != nil {
+					e.t.Fatalf("Collections: %v", err)
+				}
+				ids = append(ids, coll.ID)
+			}
+			if len(ids) != 2 || ids[0] != "c1" || ids[1] != "c2" {
+				e.t.Errorf("collections = %v, want [c1 c2]", ids)
+			}
+		}},
+	}
+}
+
+func transformScenarios() []scenario {
+	return []scenario{
+		{"transform/increment integer", func(e *env) {
+			e.set(map[string]map[string]any{"d": {"n": 1}})
+			update(e, "d", firestore.Update{Path: "n", Value: firestore.Increment(2)})
+			e.wantDoc("d", map[string]any{"n": 3})
+		}},
+		{"transform/increment integer by float", func(e *env) {
+			e.set(map[string]map[string]any{"d": {"n": 1}})
+			update(e, "d", firestore.Update{Path: "n", Value: firestore.Increment(0.5)})
+			e.wantDoc("d", map[string]any{"n": 1.5})
+		}},
+		{"transform/increment missing field", func(e *env) {
+			e.set(map[string]map[string]any{"d": {}})
+			update(e, "d", firestore.Update{Path: "n", Value: firestore.Increment(4)})
+			e.wantDoc("d", map[string]any{"n": 4})
+		}},
+		{"transform/increment non-number", func(e *env) {
+			e.set(map[string]map[string]any{"d": {"n": "x"}})
+			update(e, "d", firestore.Update{Path: "n", Value: firestore.Increment(4)})
+			e.wantDoc("d", map[string]any{"n": 4})
+		}},
+		{"transform/array union", func(e *env) {
+			e.set(map[string]map[string]any{"d": {"tags": []any{"a", "b"}}})
+			update(e, "d", firestore.Update{Path: "tags", Value: firestore.ArrayUnion("b", "c", "c")})
+			e.wantDoc("d", map[string]any{"tags": []any{"a", "b", "c"}})
+		}},
+		{"transform/array remove", func(e *env) {
+			e.set(map[string]map[string]any{"d": {"tags": []any{"a", "b", "a", 1}}})
+			update(e, "d", firestore.Update{Path: "tags", Value: firestore.ArrayRemove("a", 1)})
+			e.wantDoc("d", map[string]any{"tags": []any{"b"}})
+		}},
+		{"transform/server timestamp", func(e *env) {
+			e.set(map[string]map[string]any{"d": {}})
+			wr, err := e.doc("d").Update(e.ctx, []firestore.Update{{Path: "at", Value: firestore.ServerTimestamp}})
+			if err != nil {
+				e.t.Fatalf("Update: %v", err)
+			}
+			e.wantDoc("d", map[string]any{"at": wr.UpdateTime})
+		}},
+		{"transform/set with transforms", func(e *env) {
+			e.set(map[string]map[string]any{"d": {"n": 1, "tags": []any{"a"}, "old": true}})
+			data := map[string]any{"n": firestore.Increment(1), "tags": firestore.ArrayUnion("b")}
+			if _, err := e.doc("d").Set(e.ctx, data, firestore.MergeAll); err != nil {
+				e.t.Fatalf("Set: %v", err)
+			}
+			e.wantDoc("d", map[string]any{"n": 2, "tags": []any{"a", "b"}, "old": true})
+		}},
+	}
+}
+
+// update applies updates to the document id of the root collection.
+func update(e *env, id string, updates ...firestore.Update) {
+	e.t.Helper()
+	if _, err := e.doc(id).Update(e.ctx, updates); err != nil {
+		e.t.Fatalf("Update %s: %v", id, err)
+	}
+}
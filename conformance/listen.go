@@ -0,0 +1,83 @@
+package conformance
+
+import (
+	"cloud.google.com/go/firestore"
+)
+
+func listenScenarios() []scenario {
+	return []scenario{
+		{"listen/document", func(e *env) {
+			it := e.doc("d").Snapshots(e.ctx)
+			defer it.Stop()
+			snap, err := it.Next()
+			if err != nil {
+				e.t.Fatalf("Next: %v", err)
+			}
+			if snap.Exists() {
+				e.t.Errorf("first snapshot exists, want missing")
+			}
+			e.set(map[string]map[string]any{"d": {"a": 1}})
+			if snap, err = it.Next(); err != nil {
+				e.t.Fatalf("Next: %v", err)
+			}
+			if !snap.Exists() || snap.Data()["a"] != int64(1) {
+				e.t.Errorf("second snapshot: exists %v, data %v; want {a: 1}", snap.Exists(), snap.Data())
+			}
+			if _, err := e.doc("d").Delete(e.ctx); err != nil {
+				e.t.Fatalf("Delete: %v", err)
+			}
+			if snap, err = it.Next(); err != nil {
+				e.t.Fatalf("Next: %v", err)
+			}
+			if snap.Exists() {
+				e.t.Errorf("snapshot after Delete exists, want missing")
+			}
+		}},
+		{"listen/query", func(e *env) {
+			e.set(map[string]map[string]any{"a": {"n": 1}, "b": {"n": 5}})
+			it := e.collection().Where("n", ">", 2).Snapshots(e.ctx)
+			defer it.Stop()
+			qs, err := it.Next()
+			if err != nil {
+				e.t.Fatalf("Next: %v", err)
+			}
+			wantChanges(e, qs, firestore.DocumentAdded, "b")
+
+			e.set(map[string]map[string]any{"c": {"n": 3}, "a": {"n": 0}})
+			if _, err := e.doc("b").Update(e.ctx, []firestore.Update{{Path: "n", Value: 1}}); err != nil {
+				e.t.Fatalf("Update: %v", err)
+			}
+			// The writes may be reported in one or more snapshots; collect the
+			// changes until the listener has seen c added and b removed.
+			seen := map[string]firestore.DocumentChangeKind{}
+			for len(seen) < 2 {
+				if qs, err = it.Next(); err != nil {
+					e.t.Fatalf("Next: %v", err)
+				}
+				for _, c := range qs.Changes {
+					seen[c.Doc.Ref.ID] = c.Kind
+				}
+			}
+			if seen["c"] != firestore.DocumentAdded || seen["b"] != firestore.DocumentRemoved || len(seen) != 2 {
+				e.t.Errorf("changes = %v, want c added and b removed", seen)
+			}
+			if qs.Size != 1 {
+				e.t.Errorf("final snapshot has %d documents, want 1", qs.Size)
+			}
+		}},
+	}
+}
+
+// wantChanges checks that the changes of qs are all of kind and concern the
+// documents ids, in order.
+func wantChanges(e *env, qs *firestore.QuerySnapshot, kind firestore.DocumentChangeKind, ids ...string) {
+	e.t.Helper()
+	if len(qs.Changes) != len(ids) {
+		e.t.Fatalf("snapshot has %d changes, want %d", len(qs.Changes), len(ids))
+	}
+	for i, c := range qs.Changes {
+		if c.Kind != kind || c.Doc.Ref.ID != ids[i] {
+			e.t.Errorf("change %d: kind %v on %s, want kind %v on %s", i, c.Kind, c.Doc.Ref.ID, kind, ids[i])
+		}
+	}
+}
@@ -0,0 +1,362 @@
+package conformance
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	gofirestoremock "github.com/akmalsyrf/go-firestore-mock"
+	"github.com/akmalsyrf/go-firestore-mock/internal/fsvalue"
+	"google.golang.org/genproto/googleapis/type/latlng"
+)
+
+// matrixValue is a value of field v of one document of the matrix.
+type matrixValue struct {
+	id string
+	v  any
+}
+
+var (
+	t0 = time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	t1 = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	t2 = time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	t3 = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// matrixValues are the documents the generated filter and order scenarios
+// run against, in the ascending order of v: by type (null, booleans,
+// numbers, timestamps, strings, bytes), then by value and document ID.
+var matrixValues = []matrixValue{
+	{"null", nil},
+	{"false", false},
+	{"true", true},
+	{"neg", -1},
+	{"zero", 0},
+	{"one", 1},
+	{"onehalf", 1.5},
+	{"two", 2},
+	{"twoF", 2.0},
+	{"t1", t1},
+	{"t2", t2},
+	{"empty", ""},
+	{"a", "a"},
+	{"ab", "ab"},
+	{"b", "b"},
+	{"bytesA", []byte("a")},
+	{"bytesB", []byte("b")},
+}
+
+// matrixProbes are the values the generated filters compare v with.
+var matrixProbes = []any{
+	-2, -1, 0, 0.5, 1, 1.5, 2, 3,
+	"", "a", "aa", "ab", "b", "c",
+	false, true,
+	t0, t1, t2, t3,
+	[]byte("a"), []byte("b"), []byte("c"),
+}
+
+// matrixDocs returns matrixValues as documents, plus one without v.
+func matrixDocs() map[string]map[string]any {
+	docs := map[string]map[string]any{"nov": {"w": 1}}
+	for _, m := range matrixValues {
+		docs[m.id] = map[string]any{"v": m.v}
+	}
+	return docs
+}
+
+// compareSameType compares values of the same Firestore type (integers and
+// floats are both numbers); ok is false for values of different types.
+func compareSameType(a, b any) (c int, ok bool) {
+	switch a := a.(type) {
+	case bool:
+		b, ok := b.(bool)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case a == b:
+			return 0, true
+		case b:
+			return -1, true
+		}
+		return 1, true
+	case int, float64:
+		bf, ok := matrixNumber(b)
+		if !ok {
+			return 0, false
+		}
+		af, _ := matrixNumber(a)
+		return cmp.Compare(af, bf), true
+	case time.Time:
+		b, ok := b.(time.Time)
+		if !ok {
+			return 0, false
+		}
+		return a.Compare(b), true
+	case string:
+		b, ok := b.(string)
+		if !ok {
+			return 0, false
+		}
+		return strings.Compare(a, b), true
+	case []byte:
+		b, ok := b.([]byte)
+		if !ok {
+			return 0, false
+		}
+		return bytes.Compare(a, b), true
+	}
+	return 0, false
+}
+
+func matrixNumber(v any) (float64, bool) {
+	switch v := v.(type) {
+	case int:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+func equalSameType(a, b any) bool {
+	c, ok := compareSameType(a, b)
+	return ok && c == 0
+}
+
+// matrixWant returns the IDs of the matrix documents whose v satisfies
+// keep, in the ascending order of v or, if byID, of their IDs.
+func matrixWant(byID bool, keep func(v any) bool) []string {
+	var ids []string
+	for _, m := range matrixValues {
+		if keep(m.v) {
+			ids = append(ids, m.id)
+		}
+	}
+	if byID {
+		slices.Sort(ids)
+	}
+	return ids
+}
+
+// filterScenario checks that c.Where("v", op, value) over the matrix
+// returns want.
+func filterScenario(op string, value any, want []string) scenario {
+	return scenario{"filter/v " + op + " " + fsvalue.Format(value), func(e *env) {
+		e.set(matrixDocs())
+		e.wantIDs(e.collection().Where("v", op, value), want...)
+	}}
+}
+
+// inequalities are the range operators with their outcome for a comparison
+// result; range filters only match values of the type of the operand.
+var inequalities = []struct {
+	op   string
+	keep func(c int) bool
+}{
+	{"<", func(c int) bool { return c < 0 }},
+	{"<=", func(c int) bool { return c <= 0 }},
+	{">", func(c int) bool { return c > 0 }},
+	{">=", func(c int) bool { return c >= 0 }},
+}
+
+// matrixInLists are the operands of the generated in and not-in filters.
+var matrixInLists = [][]any{
+	{1},
+	{1, "a"},
+	{0, 2},
+	{2.0},
+	{true, t1},
+	{false, true},
+	{"ab", "zz"},
+	{"", []byte("a")},
+	{[]byte("b"), -1},
+	{1.5, t2, "b"},
+	{3, "c", t3},
+}
+
+func filterMatrixScenarios() []scenario {
+	var all []scenario
+	for _, p := range matrixProbes {
+		// equality filters are served by document ID
+		all = append(all, filterScenario("==", p, matrixWant(true, func(v any) bool { return equalSameType(v, p) })))
+		// != and not-in skip null and missing values, ordered by v
+		all = append(all, filterScenario("!=", p, matrixWant(false, func(v any) bool { return v != nil && !equalSameType(v, p) })))
+		for _, in := range inequalities {
+			all = append(all, filterScenario(in.op, p, matrixWant(false, func(v any) bool {
+				c, ok := compareSameType(v, p)
+				return ok && in.keep(c)
+			})))
+		}
+	}
+	all = append(all, filterScenario("==", nil, []string{"null"}))
+	for _, list := range matrixInLists {
+		member := func(v any) bool {
+			return slices.ContainsFunc(list, func(x any) bool { return equalSameType(v, x) })
+		}
+		all = append(all,
+			filterScenario("in", list, matrixWant(true, member)),
+			filterScenario("not-in", list, matrixWant(false, func(v any) bool { return v != nil && !member(v) })),
+		)
+	}
+	return all
+}
+
+func orderMatrixScenarios() []scenario {
+	asc := matrixWant(false, func(any) bool { return true })
+	desc := slices.Clone(asc)
+	slices.Reverse(desc)
+	var all []scenario
+	for n := 1; n <= len(asc); n++ {
+		all = append(all,
+			scenario{fmt.Sprintf("order/v asc limit %d", n), func(e *env) {
+				e.set(matrixDocs())
+				e.wantIDs(e.collection().OrderBy("v", firestore.Asc).Limit(n), asc[:n]...)
+			}},
+			scenario{fmt.Sprintf("order/v desc limit %d", n), func(e *env) {
+				e.set(matrixDocs())
+				e.wantIDs(e.collection().OrderBy("v", firestore.Desc).Limit(n), desc[:n]...)
+			}},
+			scenario{fmt.Sprintf("order/v asc limit to last %d", n), func(e *env) {
+				e.set(matrixDocs())
+				e.wantIDs(e.collection().OrderBy("v", firestore.Asc).LimitToLast(n), asc[len(asc)-n:]...)
+			}},
+			scenario{fmt.Sprintf("order/v asc offset %d", n), func(e *env) {
+				e.set(matrixDocs())
+				e.wantIDs(e.collection().OrderBy("v", firestore.Asc).Offset(n), asc[n:]...)
+			}},
+		)
+	}
+	return all
+}
+
+// cursorDocs are documents n1..n5 holding n = 1..5.
+func cursorDocs() map[string]map[string]any {
+	docs := map[string]map[string]any{}
+	for n := 1; n <= 5; n++ {
+		docs[fmt.Sprintf("n%d", n)] = map[string]any{"n": n}
+	}
+	return docs
+}
+
+// cursorScenarios check each cursor at values below, on, between and above
+// those of cursorDocs, in both directions.
+func cursorScenarios() []scenario {
+	cursors := []struct {
+		name string
+		at   func(q gofirestoremock.Query, v any) gofirestoremock.Query
+		// keep reports whether n is returned ascending (c is n compared
+		// with the cursor value); descending, it is reported for -c.
+		keep func(c int) bool
+	}{
+		{"start at", func(q gofirestoremock.Query, v any) gofirestoremock.Query { return q.StartAt(v) }, func(c int) bool { return c >= 0 }},
+		{"start after", func(q gofirestoremock.Query, v any) gofirestoremock.Query { return q.StartAfter(v) }, func(c int) bool { return c > 0 }},
+		{"end at", func(q gofirestoremock.Query, v any) gofirestoremock.Query { return q.EndAt(v) }, func(c int) bool { return c <= 0 }},
+		{"end before", func(q gofirestoremock.Query, v any) gofirestoremock.Query { return q.EndBefore(v) }, func(c int) bool { return c < 0 }},
+	}
+	var all []scenario
+	for _, cur := range cursors {
+		for _, v := range []any{0, 1, 2.5, 3, 5, 6} {
+			f, _ := matrixNumber(v)
+			var asc, desc []string
+			for n := 1; n <= 5; n++ {
+				if cur.keep(cmp.Compare(float64(n), f)) {
+					asc = append(asc, fmt.Sprintf("n%d", n))
+				}
+			}
+			for n := 5; n >= 1; n-- {
+				if cur.keep(-cmp.Compare(float64(n), f)) {
+					desc = append(desc, fmt.Sprintf("n%d", n))
+				}
+			}
+			all = append(all,
+				scenario{fmt.Sprintf("cursor/%s %v", cur.name, v), func(e *env) {
+					e.set(cursorDocs())
+					e.wantIDs(cur.at(e.collection().OrderBy("n", firestore.Asc), v), asc...)
+				}},
+				scenario{fmt.Sprintf("cursor/%s %v desc", cur.name, v), func(e *env) {
+					e.set(cursorDocs())
+					e.wantIDs(cur.at(e.collection().OrderBy("n", firestore.Desc), v), desc...)
+				}},
+			)
+		}
+	}
+	return all
+}
+
+// countScenarios check count aggregations over the matrix, one per range
+// filter on numbers.
+func countScenarios() []scenario {
+	var all []scenario
+	for _, p := range matrixProbes[:8] {
+		for _, in := range inequalities {
+			want := len(matrixWant(false, func(v any) bool {
+				c, ok := compareSameType(v, p)
+				return ok && in.keep(c)
+			}))
+			all = append(all, scenario{fmt.Sprintf("count/v %s %v", in.op, p), func(e *env) {
+				e.set(matrixDocs())
+				e.wantCount(e.collection().Where("v", in.op, p), int64(want))
+			}})
+		}
+	}
+	return all
+}
+
+// roundTripScenarios check that a value of each type reads back as written,
+// at the top level, nested in a map and inside an array.
+func roundTripScenarios() []scenario {
+	values := []struct {
+		name string
+		v    func(e *env) any
+	}{
+		{"null", func(*env) any { return nil }},
+		{"false", func(*env) any { return false }},
+		{"true", func(*env) any { return true }},
+		{"zero", func(*env) any { return 0 }},
+		{"max int64", func(*env) any { return int64(1<<63 - 1) }},
+		{"min int64", func(*env) any { return int64(-1 << 63) }},
+		{"float", func(*env) any { return -2.75 }},
+		{"float with integer value", func(*env) any { return 3.0 }},
+		{"empty string", func(*env) any { return "" }},
+		{"unicode string", func(*env) any { return "héllo, 世界 🌍" }},
+		{"timestamp with microseconds", func(*env) any { return time.Date(2024, 2, 29, 23, 59, 59, 999999000, time.UTC) }},
+		{"timestamp before 1970", func(*env) any { return time.Date(1901, 1, 1, 0, 0, 0, 0, time.UTC) }},
+		{"bytes", func(*env) any { return []byte{0, 1, 255} }},
+		{"geo point", func(*env) any { return &latlng.LatLng{Latitude: -33.86, Longitude: 151.21} }},
+		{"reference", func(e *env) any { return e.doc("other").Reference() }},
+		{"empty array", func(*env) any { return []any{} }},
+		{"mixed array", func(*env) any { return []any{nil, 1, "a", true} }},
+		{"empty map", func(*env) any { return map[string]any{} }},
+		{"map with dotted key", func(*env) any { return map[string]any{"a.b": 1, "c d": 2} }},
+		{"vector", func(*env) any { return firestore.Vector64{0.5, -1, 2} }},
+	}
+	var all []scenario
+	for _, val := range values {
+		all = append(all,
+			scenario{"roundtrip/" + val.name, func(e *env) {
+				data := map[string]any{"v": val.v(e)}
+				e.set(map[string]map[string]any{"d": data})
+				e.wantDoc("d", data)
+			}},
+			scenario{"roundtrip/" + val.name + " in map", func(e *env) {
+				data := map[string]any{"m": map[string]any{"v": val.v(e)}}
+				e.set(map[string]map[string]any{"d": data})
+				e.wantDoc("d", data)
+			}},
+		)
+		if !strings.HasSuffix(val.name, " array") {
+			// arrays cannot hold arrays directly
+			all = append(all, scenario{"roundtrip/" + val.name + " in array", func(e *env) {
+				data := map[string]any{"a": []any{val.v(e)}}
+				e.set(map[string]map[string]any{"d": data})
+				e.wantDoc("d", data)
+			}})
+		}
+	}
+	return all
+}
@@ -0,0 +1,189 @@
+package conformance
+
+import (
+	"math"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	gofirestoremock "github.com/akmalsyrf/go-firestore-mock"
+	"google.golang.org/genproto/googleapis/type/latlng"
+)
+
+// queryDocs are the documents the query scenarios run against.
+var queryDocs = map[string]map[string]any{
+	"a": {"n": 1, "s": "a", "tags": []any{"x"}, "g": "odd"},
+	"b": {"n": 2, "s": "b", "tags": []any{"y"}, "g": "even"},
+	"c": {"n": 3, "s": "c", "tags": []any{"x", "y"}, "g": "odd"},
+	"d": {"n": 4, "s": "d", "tags": []any{}, "g": "even"},
+	"e": {"n": 5.5, "s": "e", "tags": []any{"z"}, "g": "odd"},
+}
+
+// queryScenario seeds queryDocs and checks that the query built by q
+// returns the documents want, in order.
+func queryScenario(name string, q func(c gofirestoremock.CollectionRef) gofirestoremock.Query, want ...string) scenario {
+	return scenario{"query/" + name, func(e *env) {
+		e.set(queryDocs)
+		e.wantIDs(q(e.collection()), want...)
+	}}
+}
+
+func where(path, op string, value any) func(c gofirestoremock.CollectionRef) gofirestoremock.Query {
+	return func(c gofirestoremock.CollectionRef) gofirestoremock.Query { return c.Where(path, op, value) }
+}
+
+func byN(c gofirestoremock.CollectionRef) gofirestoremock.Query {
+	return c.OrderBy("n", firestore.Asc)
+}
+
+func queryScenarios() []scenario {
+	return []scenario{
+		queryScenario("all by id", func(c gofirestoremock.CollectionRef) gofirestoremock.Query { return c }, "a", "b", "c", "d", "e"),
+		queryScenario("==", where("n", "==", 2), "b"),
+		queryScenario("== float matches integer", where("n", "==", 2.0), "b"),
+		queryScenario("== no match", where("n", "==", "2")),
+		queryScenario("!=", where("n", "!=", 2), "a", "c", "d", "e"),
+		queryScenario("<", where("n", "<", 3), "a", "b"),
+		queryScenario("<=", where("n", "<=", 3), "a", "b", "c"),
+		queryScenario(">", where("n", ">", 3), "d", "e"),
+		queryScenario(">=", where("n", ">=", 4), "d", "e"),
+		queryScenario("> float", where("n", ">", 4.5), "e"),
+		queryScenario("> string", where("s", ">", "b"), "c", "d", "e"),
+		queryScenario("in", where("n", "in", []any{1, 4}), "a", "d"),
+		queryScenario("not-in", where("n", "not-in", []any{1, 4}), "b", "c", "e"),
+		queryScenario("array-contains", where("tags", "array-contains", "x"), "a", "c"),
+		queryScenario("array-contains-any", where("tags", "array-contains-any", []any{"y", "z"}), "b", "c", "e"),
+		queryScenario("== and >", func(c gofirestoremock.CollectionRef) gofirestoremock.Query {
+			return c.Where("g", "==", "odd").Where("n", ">", 1)
+		}, "c", "e"),
+		queryScenario("or filter", func(c gofirestoremock.CollectionRef) gofirestoremock.Query {
+			return c.WhereEntity(firestore.OrFilter{Filters: []firestore.EntityFilter{
+				firestore.PropertyFilter{Path: "n", Operator: "==", Value: 1},
+				firestore.PropertyFilter{Path: "s", Operator: "==", Value: "d"},
+			}})
+		}, "a", "d"),
+		queryScenario("document id", func(c gofirestoremock.CollectionRef) gofirestoremock.Query {
+			return c.WherePath(firestore.FieldPath{firestore.DocumentID}, ">", c.Doc("c").Reference())
+		}, "d", "e"),
+		queryScenario("order by desc and limit", func(c gofirestoremock.CollectionRef) gofirestoremock.Query {
+			return c.OrderBy("n", firestore.Desc).Limit(2)
+		}, "e", "d"),
+		queryScenario("order by string desc", func(c gofirestoremock.CollectionRef) gofirestoremock.Query {
+			return c.OrderBy("s", firestore.Desc)
+		}, "e", "d", "c", "b", "a"),
+		queryScenario("order by then id", func(c gofirestoremock.CollectionRef) gofirestoremock.Query {
+			return c.OrderBy("g", firestore.Asc)
+		}, "b", "d", "a", "c", "e"),
+		queryScenario("limit to last", func(c gofirestoremock.CollectionRef) gofirestoremock.Query {
+			return byN(c).LimitToLast(2)
+		}, "d", "e"),
+		queryScenario("offset", func(c gofirestoremock.CollectionRef) gofirestoremock.Query {
+			return byN(c).Offset(3)
+		}, "d", "e"),
+		queryScenario("offset and limit", func(c gofirestoremock.CollectionRef) gofirestoremock.Query {
+			return byN(c).Offset(1).Limit(2)
+		}, "b", "c"),
+		queryScenario("start at", func(c gofirestoremock.CollectionRef) gofirestoremock.Query {
+			return byN(c).StartAt(2)
+		}, "b", "c", "d", "e"),
+		queryScenario("start after", func(c gofirestoremock.CollectionRef) gofirestoremock.Query {
+			return byN(c).StartAfter(2)
+		}, "c", "d", "e"),
+		queryScenario("end at", func(c gofirestoremock.CollectionRef) gofirestoremock.Query {
+			return byN(c).EndAt(3)
+		}, "a", "b", "c"),
+		queryScenario("end before", func(c gofirestoremock.CollectionRef) gofirestoremock.Query {
+			return byN(c).EndBefore(3)
+		}, "a", "b"),
+		queryScenario("start at float between integers", func(c gofirestoremock.CollectionRef) gofirestoremock.Query {
+			return byN(c).StartAt(2.5).EndAt(5)
+		}, "c", "d"),
+		queryScenario("cursor on two fields", func(c gofirestoremock.CollectionRef) gofirestoremock.Query {
+			return c.OrderBy("g", firestore.Asc).OrderBy("n", firestore.Desc).StartAfter("even", 4)
+		}, "b", "e", "c", "a"),
+		queryScenario("cursor with limit to last", func(c gofirestoremock.CollectionRef) gofirestoremock.Query {
+			return byN(c).EndBefore(5.5).LimitToLast(2)
+		}, "c", "d"),
+		{"query/start after snapshot", func(e *env) {
+			e.set(queryDocs)
+			snaps := e.snapshots(byN(e.collection()).Limit(2))
+			if len(snaps) != 2 {
+				e.t.Fatalf("first page has %d documents, want 2", len(snaps))
+			}
+			e.wantIDs(byN(e.collection()).StartAfter(snaps[1]), "c", "d", "e")
+		}},
+		{"query/select", func(e *env) {
+			e.set(queryDocs)
+			snaps := e.snapshots(e.collection().Where("n", "==", 1).Select("s"))
+			if len(snaps) != 1 {
+				e.t.Fatalf("query returned %d documents, want 1", len(snaps))
+			}
+			if got := snaps[0].Data(); len(got) != 1 || got["s"] != "a" {
+				e.t.Errorf("data = %v, want {s: a}", got)
+			}
+		}},
+		{"query/count", func(e *env) {
+			e.set(queryDocs)
+			e.wantCount(e.collection().Where("g", "==", "odd"), 3)
+		}},
+		{"query/collection group", func(e *env) {
+			group := e.coll + "-items"
+			for _, p := range []string{"a", "b"} {
+				if _, err := e.doc(p).Collection(group).Doc("i"+p).Set(e.ctx, map[string]any{"p": p}); err != nil {
+					e.t.Fatalf("Set: %v", err)
+				}
+			}
+			if _, err := e.client.Collection(group).Doc("top").Set(e.ctx, map[string]any{"p": "top"}); err != nil {
+				e.t.Fatalf("Set: %v", err)
+			}
+			e.wantIDs(e.client.CollectionGroup(group).Where("p", "!=", "top"), "ia", "ib")
+			e.wantIDs(e.client.CollectionGroup(group).OrderBy("p", firestore.Desc), "top", "ib", "ia")
+		}},
+		{"query/order across types", func(e *env) {
+			e.set(map[string]map[string]any{
+				"map":     {"v": map[string]any{"a": 1}},
+				"array":   {"v": []any{1}},
+				"geo":     {"v": &latlng.LatLng{Latitude: 1, Longitude: 2}},
+				"ref":     {"v": e.doc("x").Reference()},
+				"bytes":   {"v": []byte("a")},
+				"string":  {"v": "a"},
+				"time":    {"v": time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+				"number":  {"v": 1},
+				"nan":     {"v": math.NaN()},
+				"bool":    {"v": false},
+				"null":    {"v": nil},
+				"missing": {"w": 1},
+			})
+			e.wantIDs(e.collection().OrderBy("v", firestore.Asc),
+				"null", "bool", "nan", "number", "time", "string", "bytes", "ref", "geo", "array", "map")
+		}},
+		{"query/== null and NaN", func(e *env) {
+			e.set(map[string]map[string]any{
+				"null": {"v": nil},
+				"nan":  {"v": math.NaN()},
+				"zero": {"v": 0},
+			})
+			e.wantIDs(e.collection().Where("v", "==", nil), "null")
+			e.wantIDs(e.collection().Where("v", "==", math.NaN()), "nan")
+		}},
+		{"query/inequality skips other types", func(e *env) {
+			e.set(map[string]map[string]any{
+				"int":    {"v": 5},
+				"string": {"v": "5"},
+				"bool":   {"v": true},
+			})
+			e.wantIDs(e.collection().Where("v", ">", 1), "int")
+			e.wantIDs(e.collection().Where("v", ">", ""), "string")
+		}},
+		{"query/order arrays and maps", func(e *env) {
+			e.set(map[string]map[string]any{
+				"a2":  {"v": []any{1, 2}},
+				"a1":  {"v": []any{1}},
+				"a3":  {"v": []any{0, 5}},
+				"mb":  {"v": map[string]any{"b": 1}},
+				"ma2": {"v": map[string]any{"a": 2}},
+				"ma1": {"v": map[string]any{"a": 1, "c": 1}},
+			})
+			e.wantIDs(e.collection().OrderBy("v", firestore.Asc), "a3", "a1", "a2", "ma1", "ma2", "mb")
+		}},
+	}
+}
@@ -0,0 +1,125 @@
+package conformance
+
+import (
+	"context"
+	"errors"
+
+	"cloud.google.com/go/firestore"
+	gofirestoremock "github.com/akmalsyrf/go-firestore-mock"
+	"google.golang.org/grpc/codes"
+)
+
+func writeScenarios() []scenario {
+	return []scenario{
+		{"batch/commit", func(e *env) {
+			e.set(map[string]map[string]any{"old": {"a": 1}, "upd": {"a": 1}})
+			results, err := e.client.Batch().
+				Create(e.doc("new").Reference(), map[string]any{"a": 2}).
+				Update(e.doc("upd").Reference(), []firestore.Update{{Path: "b", Value: 2}}).
+				Delete(e.doc("old").Reference()).
+				Commit(e.ctx)
+			if err != nil {
+				e.t.Fatalf("Commit: %v", err)
+			}
+			if len(results) != 3 {
+				e.t.Errorf("Commit returned %d results, want 3", len(results))
+			}
+			e.wantDoc("new", map[string]any{"a": 2})
+			e.wantDoc("upd", map[string]any{"a": 1, "b": 2})
+			e.wantDoc("old", nil)
+		}},
+		{"batch/atomic", func(e *env) {
+			_, err := e.client.Batch().
+				Set(e.doc("a").Reference(), map[string]any{"a": 1}).
+				Update(e.doc("missing").Reference(), []firestore.Update{{Path: "a", Value: 1}}).
+				Commit(e.ctx)
+			e.wantCode("Commit", err, codes.NotFound)
+			e.wantDoc("a", nil)
+		}},
+		{"transaction/read modify write", func(e *env) {
+			e.set(map[string]map[string]any{"counter": {"n": 1}})
+			ref := e.doc("counter").Reference()
+			err := e.client.RunTransaction(e.ctx, func(ctx context.Context, tx gofirestoremock.Transaction) error {
+				snap, err := tx.Get(ref)
+				if err != nil {
+					return err
+				}
+				n, err := snap.DataAt("n")
+				if err != nil {
+					return err
+				}
+				return tx.Set(ref, map[string]any{"n": n.(int64) + 1})
+			})
+			if err != nil {
+				e.t.Fatalf("RunTransaction: %v", err)
+			}
+			e.wantDoc("counter", map[string]any{"n": 2})
+		}},
+		{"transaction/query", func(e *env) {
+			e.set(queryDocs)
+			err := e.client.RunTransaction(e.ctx, func(ctx context.Context, tx gofirestoremock.Transaction) error {
+				snaps, err := tx.Documents(e.collection().Where("g", "==", "even")).GetAll()
+				if err != nil {
+					return err
+				}
+				for _, snap := range snaps {
+					if err := tx.Update(snap.Ref, []firestore.Update{{Path: "seen", Value: true}}); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				e.t.Fatalf("RunTransaction: %v", err)
+			}
+			e.wantIDs(e.collection().Where("seen", "==", true), "b", "d")
+		}},
+		{"transaction/error rolls back", func(e *env) {
+			e.set(map[string]map[string]any{"d": {"a": 1}})
+			errAbort := errors.New("abort")
+			err := e.client.RunTransaction(e.ctx, func(ctx context.Context, tx gofirestoremock.Transaction) error {
+				if err := tx.Set(e.doc("d").Reference(), map[string]any{"a": 2}); err != nil {
+					return err
+				}
+				if err := tx.Create(e.doc("new").Reference(), map[string]any{"a": 1}); err != nil {
+					return err
+				}
+				return errAbort
+			})
+			if !errors.Is(err, errAbort) {
+				e.t.Errorf("RunTransaction: %v, want %v", err, errAbort)
+			}
+			e.wantDoc("d", map[string]any{"a": 1})
+			e.wantDoc("new", nil)
+		}},
+		{"transaction/create existing", func(e *env) {
+			e.set(map[string]map[string]any{"d": {"a": 1}})
+			err := e.client.RunTransaction(e.ctx, func(ctx context.Context, tx gofirestoremock.Transaction) error {
+				return tx.Create(e.doc("d").Reference(), map[string]any{"a": 2})
+			})
+			e.wantCode("RunTransaction", err, codes.AlreadyExists)
+			e.wantDoc("d", map[string]any{"a": 1})
+		}},
+		{"transaction/read only", func(e *env) {
+			e.set(map[string]map[string]any{"a": {"n": 1}, "b": {"n": 2}})
+			var sum int64
+			err := e.client.RunTransaction(e.ctx, func(ctx context.Context, tx gofirestoremock.Transaction) error {
+				sum = 0
+				snaps, err := tx.GetAll([]*firestore.DocumentRef{e.doc("a").Reference(), e.doc("b").Reference()})
+				if err != nil {
+					return err
+				}
+				for _, snap := range snaps {
+					sum += snap.Data()["n"].(int64)
+				}
+				return nil
+			}, firestore.ReadOnly)
+			if err != nil {
+				e.t.Fatalf("RunTransaction: %v", err)
+			}
+			if sum != 3 {
+				e.t.Errorf("sum = %d, want 3", sum)
+			}
+		}},
+	}
+}
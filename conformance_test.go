@@ -0,0 +1,48 @@
+package firestore_test
+
+import (
+	"os"
+	"testing"
+
+	"cloud.google.com/go/firestore"
+	gofirestoremock "github.com/akmalsyrf/go-firestore-mock"
+	"github.com/akmalsyrf/go-firestore-mock/conformance"
+)
+
+// backendScenarios are the conformance scenarios the test backend serves: it
+// runs no filters, cursors, offsets, listeners or field transforms other than
+// server timestamps.
+var backendScenarios = []string{
+	"document/", "roundtrip/", "batch/",
+	"transaction/read", "transaction/error", "transaction/create",
+	"transform/server timestamp",
+	"query/all by id", "query/order by", "query/limit to last",
+	"order/v asc limit ", "order/v desc limit ",
+}
+
+func newBackendClient(t *testing.T) gofirestoremock.FirestoreClient {
+	return gofirestoremock.NewFirestoreClient(gofirestoremock.NewTestBackendClient(t))
+}
+
+// TestConformance_Backend runs the scenarios of backendScenarios against the
+// test backend, keeping the list accurate without an emulator.
+func TestConformance_Backend(t *testing.T) {
+	conformance.Compare(t, newBackendClient, newBackendClient, backendScenarios...)
+}
+
+// TestConformance_EmulatorBackend compares the results of the test backend
+// with those of the emulator at FIRESTORE_EMULATOR_HOST.
+func TestConformance_EmulatorBackend(t *testing.T) {
+	if os.Getenv("FIRESTORE_EMULATOR_HOST") == "" {
+		t.Skip("FIRESTORE_EMULATOR_HOST is not set")
+	}
+	emulator := func(t *testing.T) gofirestoremock.FirestoreClient {
+		client, err := firestore.NewClient(t.Context(), "conformance-test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { client.Close() })
+		return gofirestoremock.NewFirestoreClient(client)
+	}
+	conformance.Compare(t, emulator, newBackendClient, backendScenarios...)
+}
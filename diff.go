@@ -0,0 +1,257 @@
+package firestore
+
+import (
+	"context"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"cloud.google.com/go/firestore"
+	"github.com/akmalsyrf/go-firestore-mock/internal/fsvalue"
+)
+
+// Snapshot is the state of a set of documents: the data of each existing
+// document by path, relative to the database root (e.g. "users/u1").
+type Snapshot map[string]map[string]any
+
+// TakeSnapshot reads the documents under root like Dump does: root is "" for
+// the whole database, a collection path or a document path.
+func TakeSnapshot(ctx context.Context, client FirestoreClient, root string) (Snapshot, error) {
+	tree, err := dumpTree(ctx, client, root)
+	if err != nil {
+		return nil, err
+	}
+	s := Snapshot{}
+	s.add(tree)
+	return s, nil
+}
+
+func (s Snapshot) add(colls map[string]map[string]*dumpNode) {
+	for collPath, docs := range colls {
+		for id, node := range docs {
+			if node.data != nil {
+				s[collPath+"/"+id] = node.data
+			}
+			if node.colls != nil {
+				sub := make(map[string]map[string]*dumpNode, len(node.colls))
+				for collID, subDocs := range node.colls {
+					sub[collPath+"/"+id+"/"+collID] = subDocs
+				}
+				s.add(sub)
+			}
+		}
+	}
+}
+
+// ReadSnapshot reads a Dump (or a JSON fixture file). Documents that only
+// hold subcollections are not part of the snapshot; references hold paths
+// relative to the database root, and masked timestamps (see
+// IgnoreServerTimestamps) the string IgnoredValue.
+func ReadSnapshot(dump []byte) (Snapshot, error) {
+	docs, err := parseFixtures("dump.json", dump, &fixtureReader{
+		ref: func(p string) (*firestore.DocumentRef, error) {
+			return &firestore.DocumentRef{ID: path.Base(p), Path: p}, nil
+		},
+		keepIgnored: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	s := make(Snapshot, len(docs))
+	for _, d := range docs {
+		if !d.onlyCollections {
+			s[d.ref.Path] = d.data
+		}
+	}
+	return s, nil
+}
+
+// ChangeKind is the kind of a Change or FieldChange.
+type ChangeKind int
+
+const (
+	// ChangeAdded is a document or field that only exists after.
+	ChangeAdded ChangeKind = iota + 1
+	// ChangeRemoved is a document or field that only existed before.
+	ChangeRemoved
+	// ChangeModified is a document or field whose value changed.
+	ChangeModified
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case ChangeAdded:
+		return "added"
+	case ChangeRemoved:
+		return "removed"
+	case ChangeModified:
+		return "modified"
+	}
+	return "ChangeKind(" + strconv.Itoa(int(k)) + ")"
+}
+
+// Change is a document that differs between two Snapshots.
+type Change struct {
+	Kind ChangeKind
+	// Path is the document path, relative to the database root.
+	Path string
+	// Before and After are the document data; Before is nil for an added and
+	// After for a removed document.
+	Before, After map[string]any
+	// Fields lists the changed fields of a modified document, in path order.
+	Fields []FieldChange
+}
+
+// FieldChange is a field that differs between two versions of a document.
+type FieldChange struct {
+	Kind ChangeKind
+	// Path is the field path in dotted form, with backticks around
+	// components that are not simple identifiers and [i] for array elements
+	// that changed in arrays of the same length, e.g. "address.city" or
+	// "tags[1]".
+	Path string
+	// Before and After are the values; Before is unset (nil) for an added and
+	// After for a removed field.
+	Before, After any
+}
+
+// Diff returns the documents added, removed and modified between before and
+// after, in path order. Values are compared as Firestore compares them: types
+// matter (int64(1) differs from float64(1); Go integer and float kinds are
+// normalized to int64 and float64), timestamps are compared at microsecond
+// precision, NaN equals NaN, references compare by path and vectors by value.
+// Maps are compared field by field, and arrays of the same length element by
+// element.
+func Diff(before, after Snapshot) []Change {
+	paths := make([]string, 0, len(before)+len(after))
+	for p := range before {
+		paths = append(paths, p)
+	}
+	for p := range after {
+		if _, ok := before[p]; !ok {
+			paths = append(paths, p)
+		}
+	}
+	sort.Strings(paths)
+
+	var changes []Change
+	for _, p := range paths {
+		b, inBefore := before[p]
+		a, inAfter := after[p]
+		switch {
+		case !inBefore:
+			changes = append(changes, Change{Kind: ChangeAdded, Path: p, After: a})
+		case !inAfter:
+			changes = append(changes, Change{Kind: ChangeRemoved, Path: p, Before: b})
+		default:
+			var fields []FieldChange
+			diffFields(nil, b, a, &fields)
+			if len(fields) > 0 {
+				changes = append(changes, Change{Kind: ChangeModified, Path: p, Before: b, After: a, Fields: fields})
+			}
+		}
+	}
+	return changes
+}
+
+// diffPathElem is a map key or, if index >= 0, an array index of a field
+// path.
+type diffPathElem struct {
+	key   string
+	index int
+}
+
+func diffFields(prefix []diffPathElem, before, after map[string]any, out *[]FieldChange) {
+	keys := make([]string, 0, len(before)+len(after))
+	for k := range before {
+		keys = append(keys, k)
+	}
+	for k := range after {
+		if _, ok := before[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fp := append(prefix[:len(prefix):len(prefix)], diffPathElem{key: k, index: -1})
+		b, inBefore := before[k]
+		a, inAfter := after[k]
+		switch {
+		case !inBefore:
+			*out = append(*out, FieldChange{Kind: ChangeAdded, Path: diffPath(fp), After: a})
+		case !inAfter:
+			*out = append(*out, FieldChange{Kind: ChangeRemoved, Path: diffPath(fp), Before: b})
+		default:
+			diffValues(fp, b, a, out)
+		}
+	}
+}
+
+func diffValues(fp []diffPathElem, before, after any, out *[]FieldChange) {
+	b, a := fsvalue.Normalize(before), fsvalue.Normalize(after)
+	switch b := b.(type) {
+	case map[string]any:
+		if a, ok := a.(map[string]any); ok {
+			diffFields(fp, b, a, out)
+			return
+		}
+	case []any:
+		if a, ok := a.([]any); ok && len(a) == len(b) {
+			for i := range b {
+				diffValues(append(fp[:len(fp):len(fp)], diffPathElem{index: i}), b[i], a[i], out)
+			}
+			return
+		}
+	}
+	if !fsvalue.Equal(b, a) {
+		*out = append(*out, FieldChange{Kind: ChangeModified, Path: diffPath(fp), Before: before, After: after})
+	}
+}
+
+func diffPath(fp []diffPathElem) string {
+	var b strings.Builder
+	for i, e := range fp {
+		switch {
+		case e.index >= 0:
+			b.WriteString("[" + strconv.Itoa(e.index) + "]")
+		case i > 0:
+			b.WriteString("." + fsvalue.FieldPathString(firestore.FieldPath{e.key}))
+		default:
+			b.WriteString(fsvalue.FieldPathString(firestore.FieldPath{e.key}))
+		}
+	}
+	return b.String()
+}
+
+// FormatChanges renders changes in the style of cmp.Diff: "-" lines for
+// removed documents and old values, "+" lines for added documents and new
+// values, under an unmarked line naming each modified document. Integers are
+// printed as 1 and floats as 1.0.
+func FormatChanges(changes []Change) string {
+	var b strings.Builder
+	for _, c := range changes {
+		b.WriteString(c.String())
+	}
+	return b.String()
+}
+
+func (c Change) String() string {
+	switch c.Kind {
+	case ChangeAdded:
+		return "+ " + c.Path + ": " + fsvalue.Format(c.After) + "\n"
+	case ChangeRemoved:
+		return "- " + c.Path + ": " + fsvalue.Format(c.Before) + "\n"
+	}
+	var b strings.Builder
+	b.WriteString("  " + c.Path + ":\n")
+	for _, f := range c.Fields {
+		if f.Kind != ChangeAdded {
+			b.WriteString("-   " + f.Path + ": " + fsvalue.Format(f.Before) + "\n")
+		}
+		if f.Kind != ChangeRemoved {
+			b.WriteString("+   " + f.Path + ": " + fsvalue.Format(f.After) + "\n")
+		}
+	}
+	return b.String()
+}
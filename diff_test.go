@@ -0,0 +1,141 @@
+package firestore
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/genproto/googleapis/type/latlng"
+)
+
+func TestDiff(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name          string
+		before, after Snapshot
+		want          []Change
+	}{
+		{
+			name:   "equal with semantic normalization",
+			before: Snapshot{"users/u1": {"n": 1, "f": float32(0.5), "at": at, "ref": &firestore.DocumentRef{Path: testDocsRoot + "users/u2"}, "nan": math.NaN(), "v": firestore.Vector32{1}}},
+			after:  Snapshot{"users/u1": {"n": int64(1), "f": 0.5, "at": at.Add(900 * time.Nanosecond), "ref": &firestore.DocumentRef{Path: "users/u2"}, "nan": math.NaN(), "v": firestore.Vector64{1}}},
+		},
+		{
+			name:   "documents added and removed",
+			before: Snapshot{"users/u1": {"a": 1}},
+			after:  Snapshot{"users/u2": {"a": 1}},
+			want: []Change{
+				{Kind: ChangeRemoved, Path: "users/u1", Before: map[string]any{"a": 1}},
+				{Kind: ChangeAdded, Path: "users/u2", After: map[string]any{"a": 1}},
+			},
+		},
+		{
+			name: "field changes",
+			before: Snapshot{"users/u1": {
+				"n": int64(1), "gone": "x", "at": at, "geo": &latlng.LatLng{Latitude: 1},
+				"address": map[string]any{"city": "Paris", "zip": "75001"}, "tags": []any{"a", "b"}, "list": []any{1},
+			}},
+			after: Snapshot{"users/u1": {
+				"n": float64(1), "new": true, "at": at.Add(time.Microsecond), "geo": &latlng.LatLng{Latitude: 1},
+				"address": map[string]any{"city": "Lyon", "zip": "75001"}, "tags": []any{"a", "c"}, "list": []any{1, 2},
+			}},
+			want: []Change{{
+				Kind: ChangeModified, Path: "users/u1",
+				Fields: []FieldChange{
+					{Kind: ChangeModified, Path: "address.city", Before: "Paris", After: "Lyon"},
+					{Kind: ChangeModified, Path: "at", Before: at, After: at.Add(time.Microsecond)},
+					{Kind: ChangeRemoved, Path: "gone", Before: "x"},
+					{Kind: ChangeModified, Path: "list", Before: []any{1}, After: []any{1, 2}},
+					{Kind: ChangeModified, Path: "n", Before: int64(1), After: float64(1)},
+					{Kind: ChangeAdded, Path: "new", After: true},
+					{Kind: ChangeModified, Path: "tags[1]", Before: "b", After: "c"},
+				},
+			}},
+		},
+		{
+			name:   "quoted field names",
+			before: Snapshot{"users/u1": {"first name": map[string]any{"x-y": 1}}},
+			after:  Snapshot{"users/u1": {"first name": map[string]any{"x-y": 2}}},
+			want: []Change{{Kind: ChangeModified, Path: "users/u1", Fields: []FieldChange{
+				{Kind: ChangeModified, Path: "`first name`.`x-y`", Before: 1, After: 2},
+			}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Diff(tt.before, tt.after)
+			for i := range got {
+				if got[i].Kind == ChangeModified {
+					got[i].Before, got[i].After = nil, nil
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Diff() =\n%#v\nwant\n%#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatChanges(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	before := Snapshot{
+		"users/u1": {"age": 36, "name": "Ada", "tags": []any{"a"}},
+		"users/u2": {"name": "Al", "at": at},
+	}
+	after := Snapshot{
+		"users/u1": {"age": 36.0, "tags": []any{"a"}, "pic": []byte("hi"), "home": &latlng.LatLng{Latitude: 1, Longitude: 2}},
+		"users/u3": {"boss": &firestore.DocumentRef{Path: "users/u1"}, "v": firestore.Vector64{1}, "m": map[string]any{"a b": nil}},
+	}
+	want := `  users/u1:
+-   age: 36
++   age: 36.0
++   home: geo(1.0, 2.0)
+-   name: "Ada"
++   pic: bytes(aGk=)
+- users/u2: {at: 2024-05-01T12:00:00Z, name: "Al"}
++ users/u3: {boss: ref(users/u1), m: {` + "`a b`" + `: null}, v: vector[1.0]}
+`
+	if got := FormatChanges(Diff(before, after)); got != want {
+		t.Errorf("FormatChanges() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestTakeSnapshot_MatchesReadSnapshotOfDump(t *testing.T) {
+	ctx := context.Background()
+	client := newDumpClient(t, dumpFixtures)
+	taken, err := TakeSnapshot(ctx, client, "")
+	if err != nil {
+		t.Fatalf("TakeSnapshot() error = %v", err)
+	}
+	if _, ok := taken["teams/t1"]; ok {
+		t.Errorf("TakeSnapshot() holds the missing document teams/t1")
+	}
+	if _, ok := taken["users/u1/posts/p1"]; !ok {
+		t.Errorf("TakeSnapshot() misses users/u1/posts/p1: %v", taken)
+	}
+	dump, err := Dump(ctx, client, "")
+	if err != nil {
+		t.Fatalf("Dump() error = %v", err)
+	}
+	read, err := ReadSnapshot(dump)
+	if err != nil {
+		t.Fatalf("ReadSnapshot() error = %v", err)
+	}
+	if changes := Diff(read, taken); len(changes) != 0 {
+		t.Errorf("Diff(ReadSnapshot(dump), TakeSnapshot()) =\n%s", FormatChanges(changes))
+	}
+
+	if _, err := client.Doc("users/u2").Set(ctx, map[string]any{"name": "Bob"}); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	after, err := TakeSnapshot(ctx, client, "users")
+	if err != nil {
+		t.Fatalf("TakeSnapshot() error = %v", err)
+	}
+	if got, want := FormatChanges(Diff(read, after)), "- teams/t1/members/m1: {user: ref(users/u1)}\n  users/u2:\n+   name: \"Bob\"\n"; got != want {
+		t.Errorf("FormatChanges() =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -21,6 +21,12 @@ type DocumentSnapshot interface {
 	Ref() *firestore.DocumentRef
 }
 
+// NewDocumentSnapshot wraps a *firestore.DocumentSnapshot, e.g. one returned
+// by SnapshotFromData, as a DocumentSnapshot.
+func NewDocumentSnapshot(snap *firestore.DocumentSnapshot) DocumentSnapshot {
+	return &documentSnapshotWrapper{snap: snap}
+}
+
 type documentSnapshotWrapper struct {
 	snap *firestore.DocumentSnapshot
 }
@@ -0,0 +1,396 @@
+package firestore
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/akmalsyrf/go-firestore-mock/internal/fsvalue"
+	"google.golang.org/api/iterator"
+	"google.golang.org/genproto/googleapis/type/latlng"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// IgnoredValue replaces the values and IDs masked by the options of Dump.
+const IgnoredValue = "<ignored>"
+
+// DumpOption configures Dump.
+type DumpOption func(*dumpConfig)
+
+type dumpConfig struct {
+	timestampsSince time.Time
+	maskTimestamps  bool
+	maskIDs         bool
+}
+
+// IgnoreServerTimestamps masks the timestamps at or after since, typically
+// the start of the test: values set with firestore.ServerTimestamp or
+// time.Now while the test ran are dumped as {"$timestamp": "<ignored>"},
+// while the fixed times of fixtures are kept.
+func IgnoreServerTimestamps(since time.Time) DumpOption {
+	return func(c *dumpConfig) { c.maskTimestamps, c.timestampsSince = true, since }
+}
+
+// IgnoreGeneratedIDs replaces the document IDs generated by CollectionRef.Add
+// or CollectionRef.NewDoc (20 letters and digits) with "<ignored>-1",
+// "<ignored>-2", ... numbered by collection and then by document content, in
+// document keys and in references alike. Generated IDs are recognized by
+// their shape only, so IDs chosen by the caller that happen to be 20 letters
+// and digits are masked too. Documents whose content only differs by
+// generated IDs are numbered in ID order, which may change between runs. IDs
+// of a client decorated with WithDocumentIDs are stable and need no masking.
+func IgnoreGeneratedIDs() DumpOption {
+	return func(c *dumpConfig) { c.maskIDs = true }
+}
+
+// Dump returns the documents under root as canonical JSON, in the tree format
+// read by LoadFixtures: collections map document IDs to their fields, with
+// subcollections under "$collections", sorted keys, two-space indentation and
+// typed markers for timestamps, references, geo points, bytes and vectors.
+// Integers are written without and floats with a fraction or exponent (1.0),
+// so a dump loads back with the same types. root is "" for the whole
+// database, a collection path or a document path, relative to the database
+// root; the tree always starts at the database root.
+//
+// The walk uses FirestoreClient.Collections, DocumentRef.Collections,
+// CollectionRef.DocumentRefs and DocumentRef.Get. Missing documents that only
+// hold subcollections are dumped with their subcollections alone.
+func Dump(ctx context.Context, client FirestoreClient, root string, opts ...DumpOption) ([]byte, error) {
+	cfg := &dumpConfig{}
+	for _, o := range opts {
+		o(cfg)
+	}
+	tree, err := dumpTree(ctx, client, root)
+	if err != nil {
+		return nil, err
+	}
+	e := &dumpEncoder{cfg: cfg, ids: map[string]string{}}
+	if cfg.maskIDs {
+		e.maskIDs(tree)
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(e.collections(tree)); err != nil {
+		return nil, fmt.Errorf("go-firestore-mock: dump: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// dumpTree reads the documents under root, by collection path (relative to
+// the database root) and document ID.
+func dumpTree(ctx context.Context, client FirestoreClient, root string) (map[string]map[string]*dumpNode, error) {
+	d := &dumper{ctx: ctx}
+	root = strings.Trim(root, "/")
+	segs := strings.Split(root, "/")
+	switch {
+	case root == "":
+		return d.collections(client.Collections(ctx), "the database", client.Collection)
+	case len(segs)%2 == 1:
+		docs, err := d.collection(client.Collection(root))
+		if err != nil {
+			return nil, err
+		}
+		return map[string]map[string]*dumpNode{root: docs}, nil
+	}
+	node, err := d.document(client.Doc(root))
+	if err != nil {
+		return nil, err
+	}
+	docs := map[string]*dumpNode{}
+	if node != nil {
+		docs[segs[len(segs)-1]] = node
+	}
+	return map[string]map[string]*dumpNode{strings.Join(segs[:len(segs)-1], "/"): docs}, nil
+}
+
+// dumpNode is a document of a Dump; data is nil for a missing document.
+type dumpNode struct {
+	data  map[string]any
+	colls map[string]map[string]*dumpNode
+}
+
+type dumper struct {
+	ctx context.Context
+}
+
+// collections dumps the collections listed by it, opened with open.
+func (d *dumper) collections(it CollectionIterator, parent string, open func(id string) CollectionRef) (map[string]map[string]*dumpNode, error) {
+	defer it.Stop()
+	colls := map[string]map[string]*dumpNode{}
+	for {
+		coll, err := it.Next()
+		if err == iterator.Done {
+			return colls, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("go-firestore-mock: dump: listing collections of %s: %w", parent, err)
+		}
+		docs, err := d.collection(open(coll.ID))
+		if err != nil {
+			return nil, err
+		}
+		colls[coll.ID] = docs
+	}
+}
+
+func (d *dumper) collection(coll CollectionRef) (map[string]*dumpNode, error) {
+	it := coll.DocumentRefs(d.ctx)
+	docs := map[string]*dumpNode{}
+	for {
+		ref, err := it.Next()
+		if err == iterator.Done {
+			return docs, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("go-firestore-mock: dump: listing documents of %s: %w", coll.Path(), err)
+		}
+		node, err := d.document(coll.Doc(ref.ID))
+		if err != nil {
+			return nil, err
+		}
+		if node != nil {
+			docs[ref.ID] = node
+		}
+	}
+}
+
+// document dumps doc, or returns nil if it neither exists nor holds
+// subcollections.
+func (d *dumper) document(doc DocumentRef) (*dumpNode, error) {
+	node := &dumpNode{}
+	snap, err := doc.Get(d.ctx)
+	switch {
+	case status.Code(err) == codes.NotFound:
+	case err != nil:
+		return nil, fmt.Errorf("go-firestore-mock: dump: reading %s: %w", doc.Path(), err)
+	case snap != nil && snap.Exists():
+		node.data = snap.Data()
+	}
+	colls, err := d.collections(doc.Collections(d.ctx), doc.Path(), doc.Collection)
+	if err != nil {
+		return nil, err
+	}
+	if len(colls) > 0 {
+		node.colls = colls
+	}
+	if node.data == nil && node.colls == nil {
+		return nil, nil
+	}
+	return node, nil
+}
+
+// dumpEncoder turns dumpNodes into JSON values.
+type dumpEncoder struct {
+	cfg *dumpConfig
+	ids map[string]string // generated ID -> placeholder
+}
+
+// isGeneratedID reports whether id looks like an ID generated by the SDK.
+func isGeneratedID(id string) bool {
+	if len(id) != 20 {
+		return false
+	}
+	for _, r := range id {
+		if !('a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// maxMaskRounds bounds the numbering rounds of maskIDs.
+const maxMaskRounds = 8
+
+// maskIDs numbers the generated IDs in tree: collections in path order, and
+// the documents of a collection by their content. The first round compares
+// content with every generated ID masked alike, so that references to
+// documents numbered later do not make the numbering depend on the random
+// IDs; later rounds compare it with the numbers of the previous round, to
+// order documents that only differ by the documents they reference, until
+// the numbering no longer changes. Documents whose content is still equal
+// are numbered by ID.
+func (e *dumpEncoder) maskIDs(tree map[string]map[string]*dumpNode) {
+	e.collectIDs(tree)
+	for range maxMaskRounds {
+		ids := map[string]string{}
+		e.numberIDs(tree, ids)
+		if maps.Equal(ids, e.ids) {
+			break
+		}
+		e.ids = ids
+	}
+}
+
+// collectIDs masks every generated document ID in colls as IgnoredValue.
+func (e *dumpEncoder) collectIDs(colls map[string]map[string]*dumpNode) {
+	for _, docs := range colls {
+		for docID, node := range docs {
+			if isGeneratedID(docID) {
+				e.ids[docID] = IgnoredValue
+			}
+			if node.colls != nil {
+				e.collectIDs(node.colls)
+			}
+		}
+	}
+}
+
+// numberIDs adds the placeholders of the generated IDs in colls to ids.
+func (e *dumpEncoder) numberIDs(colls map[string]map[string]*dumpNode, ids map[string]string) {
+	for _, id := range sortedKeys(colls) {
+		docs := colls[id]
+		var generated []string
+		content := map[string]string{}
+		for docID, node := range docs {
+			if isGeneratedID(docID) {
+				generated = append(generated, docID)
+				b, _ := json.Marshal(e.fields(node.data))
+				content[docID] = string(b)
+			}
+		}
+		sort.Slice(generated, func(i, j int) bool {
+			if content[generated[i]] != content[generated[j]] {
+				return content[generated[i]] < content[generated[j]]
+			}
+			return generated[i] < generated[j]
+		})
+		for _, docID := range generated {
+			ids[docID] = IgnoredValue + "-" + strconv.Itoa(len(ids)+1)
+		}
+		// subcollections in the order of the dumped document IDs
+		docIDs := sortedKeys(docs)
+		masked := func(docID string) string {
+			if m, ok := ids[docID]; ok {
+				return m
+			}
+			return docID
+		}
+		sort.SliceStable(docIDs, func(i, j int) bool { return masked(docIDs[i]) < masked(docIDs[j]) })
+		for _, docID := range docIDs {
+			if docs[docID].colls != nil {
+				e.numberIDs(docs[docID].colls, ids)
+			}
+		}
+	}
+}
+
+func (e *dumpEncoder) id(id string) string {
+	if masked, ok := e.ids[id]; ok {
+		return masked
+	}
+	return id
+}
+
+func (e *dumpEncoder) collections(colls map[string]map[string]*dumpNode) map[string]any {
+	out := make(map[string]any, len(colls))
+	for id, docs := range colls {
+		m := make(map[string]any, len(docs))
+		for docID, node := range docs {
+			doc := e.fields(node.data)
+			if node.colls != nil {
+				doc[FixtureCollectionsKey] = e.collections(node.colls)
+			}
+			m[e.id(docID)] = doc
+		}
+		out[e.path(id)] = m
+	}
+	return out
+}
+
+func (e *dumpEncoder) fields(data map[string]any) map[string]any {
+	out := make(map[string]any, len(data))
+	for k, v := range data {
+		out[k] = e.value(v)
+	}
+	return out
+}
+
+// path masks the generated IDs in a relative path.
+func (e *dumpEncoder) path(p string) string {
+	if len(e.ids) == 0 {
+		return p
+	}
+	segs := strings.Split(p, "/")
+	for i, s := range segs {
+		segs[i] = e.id(s)
+	}
+	return strings.Join(segs, "/")
+}
+
+// value encodes a value of DocumentSnapshot.Data.
+func (e *dumpEncoder) value(v any) any {
+	switch v := v.(type) {
+	case int64:
+		return json.Number(strconv.FormatInt(v, 10))
+	case float64:
+		return floatValue(v)
+	case time.Time:
+		if e.cfg.maskTimestamps && !v.Before(e.cfg.timestampsSince) {
+			return map[string]any{"$timestamp": IgnoredValue}
+		}
+		return map[string]any{"$timestamp": v.UTC().Format(time.RFC3339Nano)}
+	case *firestore.DocumentRef:
+		if v == nil {
+			return nil
+		}
+		return map[string]any{"$ref": e.path(fsvalue.RelativePath(v.Path))}
+	case *latlng.LatLng:
+		if v == nil {
+			return nil
+		}
+		return map[string]any{"$geopoint": []any{floatValue(v.Latitude), floatValue(v.Longitude)}}
+	case []byte:
+		return map[string]any{"$bytes": base64.StdEncoding.EncodeToString(v)}
+	case firestore.Vector64:
+		out := make([]any, len(v))
+		for i, f := range v {
+			out[i] = floatValue(f)
+		}
+		return map[string]any{"$vector": out}
+	case firestore.Vector32:
+		out := make([]any, len(v))
+		for i, f := range v {
+			out[i] = floatValue(float64(f))
+		}
+		return map[string]any{"$vector": out}
+	case []any:
+		out := make([]any, len(v))
+		for i, x := range v {
+			out[i] = e.value(x)
+		}
+		return out
+	case map[string]any:
+		return e.fields(v)
+	}
+	return v // nil, bool, string
+}
+
+// floatValue encodes f so that it reads back as a float: with a fraction or
+// exponent, or as a $double marker for NaN and infinities.
+func floatValue(f float64) any {
+	switch {
+	case math.IsNaN(f):
+		return map[string]any{"$double": "NaN"}
+	case math.IsInf(f, 1):
+		return map[string]any{"$double": "Infinity"}
+	case math.IsInf(f, -1):
+		return map[string]any{"$double": "-Infinity"}
+	}
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".eE") {
+		s += ".0"
+	}
+	return json.Number(s)
+}
@@ -0,0 +1,266 @@
+package firestore
+
+import (
+	"context"
+	"math"
+	"regexp"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+const dumpFixtures = `
+users:
+  u1:
+    name: Ada
+    age: 36
+    score: 2.0
+    born: {$timestamp: "1815-12-10T00:00:00Z"}
+    manager: {$ref: users/u2}
+    home: {$geopoint: [51.5, -0.12]}
+    avatar: {$bytes: aGVsbG8=}
+    embedding: {$vector: [0.5, 1]}
+    tags: [a, 1, {nested: true}]
+    $collections:
+      posts:
+        p1: {title: "<b>Hello</b>"}
+  u2: {}
+teams/t1/members:
+  m1: {user: {$ref: users/u1}}
+`
+
+func newDumpClient(t *testing.T, fixtures string) FirestoreClient {
+	t.Helper()
+	client := NewFirestoreClient(newSnapshotClient(t))
+	fsys := fstest.MapFS{"f.yaml": {Data: []byte(fixtures)}}
+	if err := LoadFixtures(context.Background(), client, fsys, "f.yaml"); err != nil {
+		t.Fatalf("LoadFixtures() error = %v", err)
+	}
+	return client
+}
+
+func TestDump(t *testing.T) {
+	ctx := context.Background()
+	client := newDumpClient(t, dumpFixtures)
+
+	tests := []struct {
+		name string
+		root string
+		want string
+	}{
+		{
+			name: "document",
+			root: "users/u1/posts/p1",
+			want: `{
+  "users/u1/posts": {
+    "p1": {
+      "title": "<b>Hello</b>"
+    }
+  }
+}
+`,
+		},
+		{
+			name: "collection under a missing document",
+			root: "teams/t1/members",
+			want: `{
+  "teams/t1/members": {
+    "m1": {
+      "user": {
+        "$ref": "users/u1"
+      }
+    }
+  }
+}
+`,
+		},
+		{
+			name: "database",
+			root: "",
+			want: `{
+  "teams": {
+    "t1": {
+      "$collections": {
+        "members": {
+          "m1": {
+            "user": {
+              "$ref": "users/u1"
+            }
+          }
+        }
+      }
+    }
+  },
+  "users": {
+    "u1": {
+      "$collections": {
+        "posts": {
+          "p1": {
+            "title": "<b>Hello</b>"
+          }
+        }
+      },
+      "age": 36,
+      "avatar": {
+        "$bytes": "aGVsbG8="
+      },
+      "born": {
+        "$timestamp": "1815-12-10T00:00:00Z"
+      },
+      "embedding": {
+        "$vector": [
+          0.5,
+          1.0
+        ]
+      },
+      "home": {
+        "$geopoint": [
+          51.5,
+          -0.12
+        ]
+      },
+      "manager": {
+        "$ref": "users/u2"
+      },
+      "name": "Ada",
+      "score": 2.0,
+      "tags": [
+        "a",
+        1,
+        {
+          "nested": true
+        }
+      ]
+    },
+    "u2": {}
+  }
+}
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Dump(ctx, client, tt.root)
+			if err != nil {
+				t.Fatalf("Dump() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Dump() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDump_LoadsBack(t *testing.T) {
+	ctx := context.Background()
+	client := newDumpClient(t, dumpFixtures)
+	if _, err := client.Doc("values/special").Set(ctx, map[string]any{"nan": math.NaN(), "inf": math.Inf(-1), "big": 1e300}); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	dump, err := Dump(ctx, client, "")
+	if err != nil {
+		t.Fatalf("Dump() error = %v", err)
+	}
+
+	copied := NewFirestoreClient(newSnapshotClient(t))
+	if err := LoadFixtures(ctx, copied, fstest.MapFS{"dump.json": {Data: dump}}, "dump.json"); err != nil {
+		t.Fatalf("LoadFixtures(dump) error = %v", err)
+	}
+	again, err := Dump(ctx, copied, "")
+	if err != nil {
+		t.Fatalf("Dump() of the copy error = %v", err)
+	}
+	if string(again) != string(dump) {
+		t.Errorf("dump of the loaded dump =\n%s\nwant\n%s", again, dump)
+	}
+}
+
+func TestDump_IgnoreOptions(t *testing.T) {
+	ctx := context.Background()
+	start := time.Now()
+	client := newDumpClient(t, "")
+	orders := client.Collection("orders")
+	for _, total := range []int{20, 10} {
+		ref, _, err := orders.Add(ctx, map[string]any{"total": total, "at": time.Now(), "since": time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)})
+		if err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+		if _, err := client.Doc("receipts/r"+ref.ID[:1]).Set(ctx, map[string]any{"order": ref}); err != nil {
+			t.Fatalf("Set() error = %v", err)
+		}
+	}
+
+	got, err := Dump(ctx, client, "orders", IgnoreServerTimestamps(start), IgnoreGeneratedIDs())
+	if err != nil {
+		t.Fatalf("Dump() error = %v", err)
+	}
+	want := `{
+  "orders": {
+    "<ignored>-1": {
+      "at": {
+        "$timestamp": "<ignored>"
+      },
+      "since": {
+        "$timestamp": "2020-01-01T00:00:00Z"
+      },
+      "total": 10
+    },
+    "<ignored>-2": {
+      "at": {
+        "$timestamp": "<ignored>"
+      },
+      "since": {
+        "$timestamp": "2020-01-01T00:00:00Z"
+      },
+      "total": 20
+    }
+  }
+}
+`
+	if string(got) != want {
+		t.Errorf("Dump() =\n%s\nwant\n%s", got, want)
+	}
+
+	// references to generated IDs are masked too
+	got, err = Dump(ctx, client, "", IgnoreGeneratedIDs())
+	if err != nil {
+		t.Fatalf("Dump() error = %v", err)
+	}
+	if refs := regexp.MustCompile(`"\$ref": "orders/[^"]*"`).FindAllString(string(got), -1); len(refs) != 2 || !strings.Contains(refs[0]+refs[1], "<ignored>-") {
+		t.Errorf("references = %v, want masked orders references", refs)
+	}
+}
+
+// TestDump_IgnoreGeneratedIDs_Stable dumps the same data written with
+// different random IDs: documents that only differ by the generated
+// documents they reference, and subcollections of generated documents.
+func TestDump_IgnoreGeneratedIDs_Stable(t *testing.T) {
+	ctx := context.Background()
+	dump := func(seed uint64) string {
+		client := WithDocumentIDs(NewFirestoreClient(newSnapshotClient(t)), SeededDocumentIDs(seed))
+		for n := range 3 {
+			b, _, err := client.Collection("b").Add(ctx, map[string]any{"n": n})
+			if err != nil {
+				t.Fatalf("Add(b) error = %v", err)
+			}
+			if _, _, err := client.Collection("a").Add(ctx, map[string]any{"ref": b}); err != nil {
+				t.Fatalf("Add(a) error = %v", err)
+			}
+			if _, _, err := client.Collection("b").Doc(b.ID).Collection("items").Add(ctx, map[string]any{"n": 1}); err != nil {
+				t.Fatalf("Add(items) error = %v", err)
+			}
+		}
+		got, err := Dump(ctx, client, "", IgnoreGeneratedIDs())
+		if err != nil {
+			t.Fatalf("Dump() error = %v", err)
+		}
+		return string(got)
+	}
+	want := dump(1)
+	for seed := uint64(2); seed <= 6; seed++ {
+		if got := dump(seed); got != want {
+			t.Fatalf("Dump() with seed %d =\n%s\nwant (seed 1)\n%s", seed, got, want)
+		}
+	}
+}
@@ -0,0 +1,10 @@
+package firestore
+
+import (
+	"testing"
+
+	"cloud.google.com/go/firestore"
+)
+
+// NewTestBackendClient exports newSnapshotClient to the external tests.
+func NewTestBackendClient(t *testing.T) *firestore.Client { return newSnapshotClient(t) }
@@ -0,0 +1,111 @@
+package firestore
+
+import (
+	"context"
+	"math/rand/v2"
+	"path"
+	"sync"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// FaultRule selects operations of a WithFaults client and the error they fail
+// with. Empty fields match everything:
+//
+//	// fail 30% of DocumentRef.Set on users/* with codes.Unavailable
+//	{Method: "DocumentRef.Set", Path: "users/*", Probability: 0.3, Code: codes.Unavailable}
+//	// abort the first commit of each transaction (the SDK retries it)
+//	{Method: "Transaction.Commit", Call: 1, Code: codes.Aborted}
+//	// fail the 3rd Next of every document iterator
+//	{Method: "DocumentIterator.Next", Call: 3, Code: codes.DeadlineExceeded}
+type FaultRule struct {
+	// Method is a path.Match pattern for Op.Method, e.g. "DocumentRef.Set"
+	// or "*.Next".
+	Method string
+	// Path is a path.Match pattern for Op.Path, e.g. "users/*".
+	Path string
+	// Call, if non-zero, only matches the operation with that Op.Call.
+	Call int
+	// Probability is the fraction of matching operations that fail; zero
+	// fails all of them.
+	Probability float64
+	// Rand, if set, is the source Probability is drawn from, e.g.
+	// rand.New(rand.NewPCG(seed, 0)) for reproducible faults; it may be
+	// shared by several rules. Nil uses the global source.
+	Rand *rand.Rand
+	// Code is the gRPC status code of the injected error; zero means
+	// codes.Unavailable.
+	Code codes.Code
+	// Err, if set, is returned instead of a status error.
+	Err error
+}
+
+func (r FaultRule) matches(op Op) bool {
+	if !matchOp(r.Method, r.Path, op) || (r.Call != 0 && r.Call != op.Call) {
+		return false
+	}
+	if r.Probability <= 0 {
+		return true
+	}
+	if r.Rand == nil {
+		return rand.Float64() < r.Probability
+	}
+	faultRandMu.Lock()
+	defer faultRandMu.Unlock()
+	return r.Rand.Float64() < r.Probability
+}
+
+// faultRandMu serializes draws from FaultRule.Rand, which is not safe for
+// concurrent use.
+var faultRandMu sync.Mutex
+
+// matchOp reports whether op matches the (possibly empty) path.Match patterns
+// for its method and path.
+func matchOp(method, pattern string, op Op) bool {
+	if method != "" {
+		if ok, _ := path.Match(method, op.Method); !ok {
+			return false
+		}
+	}
+	if pattern != "" {
+		if ok, _ := path.Match(pattern, op.Path); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (r FaultRule) err(op Op) error {
+	if r.Err != nil {
+		return r.Err
+	}
+	code := r.Code
+	if code == codes.OK {
+		code = codes.Unavailable
+	}
+	return status.Errorf(code, "go-firestore-mock: injected fault in %s %s", op.Method, op.Path)
+}
+
+// WithFaults decorates client so that operations matching one of rules fail
+// with the rule's error instead of being performed; the first rule that
+// matches (and, for a Probability, fires) wins. The rules apply to every
+// collection, document, query, iterator, write batch, bulk writer and
+// transaction handed out by the returned client (see Op for the operation
+// names), so retry and error-handling paths can be tested against the real
+// wrapper as well as against mocks and fakes.
+//
+// Faults on iterators are sticky, like SDK iterator errors. Faults on
+// BulkWriter writes ("BulkWriter.Set", ...) fail the enqueue call; to fail a
+// write the way the backend does, target "BulkWriterJob.Results". Decorated
+// queries can still be passed to Describe and Transaction.Documents.
+func WithFaults(client FirestoreClient, rules ...FaultRule) FirestoreClient {
+	return intercept(client, func(_ context.Context, op Op, call func() (any, error)) (any, error) {
+		for _, r := range rules {
+			if r.matches(op) {
+				return nil, r.err(op)
+			}
+		}
+		return call()
+	})
+}
@@ -0,0 +1,193 @@
+package firestore
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"reflect"
+	"testing"
+
+	"cloud.google.com/go/firestore"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestWithFaults_DocumentOps(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	inner := NewMockFirestoreClient(ctrl)
+	user := NewMockDocumentRef(ctrl)
+	order := NewMockDocumentRef(ctrl)
+
+	inner.EXPECT().Doc("users/u1").Return(user)
+	inner.EXPECT().Doc("orders/o1").Return(order)
+	user.EXPECT().Path().Return(testDocsRoot + "users/u1").AnyTimes()
+	order.EXPECT().Path().Return(testDocsRoot + "orders/o1").AnyTimes()
+	user.EXPECT().Get(ctx).Return(nil, nil)
+	order.EXPECT().Set(ctx, map[string]any{"a": 1}).Return(nil, nil)
+
+	client := WithFaults(inner, FaultRule{Method: "DocumentRef.Set", Path: "users/*", Code: codes.Unavailable})
+
+	u := client.Doc("users/u1")
+	if _, err := u.Set(ctx, map[string]any{"a": 1}); status.Code(err) != codes.Unavailable {
+		t.Errorf("Set(users/u1) error = %v, want Unavailable", err)
+	}
+	if _, err := u.Get(ctx); err != nil {
+		t.Errorf("Get(users/u1) error = %v, want nil", err)
+	}
+	if _, err := client.Doc("orders/o1").Set(ctx, map[string]any{"a": 1}); err != nil {
+		t.Errorf("Set(orders/o1) error = %v, want nil", err)
+	}
+}
+
+func TestWithFaults_Probability(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	inner := NewMockFirestoreClient(ctrl)
+	doc := NewMockDocumentRef(ctrl)
+	inner.EXPECT().Doc(gomock.Any()).Return(doc).AnyTimes()
+	doc.EXPECT().Path().Return(testDocsRoot + "users/u1").AnyTimes()
+	doc.EXPECT().Delete(ctx).Return(nil, nil).AnyTimes()
+
+	client := WithFaults(inner, FaultRule{Method: "DocumentRef.Delete", Probability: 0.3})
+	const n = 2000
+	failed := 0
+	for i := 0; i < n; i++ {
+		if _, err := client.Doc("users/u1").Delete(ctx); err != nil {
+			failed++
+		}
+	}
+	if failed < n*2/10 || failed > n*4/10 {
+		t.Errorf("%d of %d deletes failed, want about 30%%", failed, n)
+	}
+
+	// the same seed fails the same deletes
+	faults := func(seed uint64) []bool {
+		client := WithFaults(inner, FaultRule{Method: "DocumentRef.Delete", Probability: 0.5, Rand: rand.New(rand.NewPCG(seed, 0))})
+		var got []bool
+		for i := 0; i < 50; i++ {
+			_, err := client.Doc("users/u1").Delete(ctx)
+			got = append(got, err != nil)
+		}
+		return got
+	}
+	if a, b := faults(1), faults(1); !reflect.DeepEqual(a, b) {
+		t.Errorf("seed 1 faults differ between runs:\n%v\n%v", a, b)
+	}
+	if a, b := faults(1), faults(2); reflect.DeepEqual(a, b) {
+		t.Errorf("seeds 1 and 2 fail the same deletes: %v", a)
+	}
+}
+
+func TestWithFaults_IteratorNext(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	inner := NewMockFirestoreClient(ctrl)
+	r := NewQueryResponder()
+	docs := []*firestore.DocumentSnapshot{{}, {}, {}, {}}
+	r.OnQuery(QuerySpec{Collection: "users", Limit: 10}).Return(docs...)
+	inner.EXPECT().Collection("users").Return(r.Collection("users")).AnyTimes()
+
+	client := WithFaults(inner, FaultRule{Method: "*.Next", Path: "users", Call: 3, Code: codes.DeadlineExceeded})
+	q := client.Collection("users").Limit(10)
+	if got := Describe(q); got.Collection != "users" || got.Limit != 10 {
+		t.Errorf("Describe(decorated query) = %v", got)
+	}
+
+	it := q.Documents(ctx)
+	for i := 1; i <= 4; i++ {
+		_, err := it.Next()
+		wantFault := i >= 3 // sticky after the 3rd call
+		if gotFault := status.Code(err) == codes.DeadlineExceeded; gotFault != wantFault {
+			t.Errorf("Next() #%d error = %v, want fault: %v", i, err, wantFault)
+		}
+	}
+
+	// GetAll is one "DocumentIterator.GetAll", not a series of Next
+	if got, err := q.Documents(ctx).GetAll(); err != nil || len(got) != 4 {
+		t.Errorf("GetAll() = %d docs, %v, want 4, nil", len(got), err)
+	}
+	client = WithFaults(inner, FaultRule{Method: "DocumentIterator.GetAll", Path: "users", Code: codes.DeadlineExceeded})
+	if got, err := client.Collection("users").Limit(10).Documents(ctx).GetAll(); status.Code(err) != codes.DeadlineExceeded || got != nil {
+		t.Errorf("GetAll() = %v, %v, want DeadlineExceeded", got, err)
+	}
+}
+
+func TestWithFaults_BatchAndBulkWriter(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	inner := NewMockFirestoreClient(ctrl)
+	batch := NewMockWriteBatch(ctrl)
+	bw := NewMockBulkWriter(ctrl)
+	boom := errors.New("boom")
+	ref := &firestore.DocumentRef{ID: "u1", Path: testDocsRoot + "users/u1"}
+
+	inner.EXPECT().Batch().Return(batch)
+	batch.EXPECT().Set(ref, gomock.Any()).Return(batch)
+	inner.EXPECT().BulkWriter(ctx).Return(bw)
+	bw.EXPECT().Delete(ref).Return(nil, nil)
+	bw.EXPECT().End()
+
+	client := WithFaults(inner,
+		FaultRule{Method: "WriteBatch.Commit", Err: boom},
+		FaultRule{Method: "BulkWriter.Set", Err: boom},
+		FaultRule{Method: "BulkWriterJob.Results", Path: "users/u1", Err: boom},
+	)
+	if _, err := client.Batch().Set(ref, map[string]any{}).Commit(ctx); !errors.Is(err, boom) {
+		t.Errorf("Commit() error = %v, want boom", err)
+	}
+
+	w := client.BulkWriter(ctx)
+	// a failed enqueue returns no job and never reaches the inner writer
+	if job, err := w.Set(ref, map[string]any{}); job != nil || !errors.Is(err, boom) {
+		t.Errorf("Set() = %v, %v, want nil job and boom", job, err)
+	}
+	job, err := w.Delete(ref)
+	if err != nil {
+		t.Fatalf("Delete() enqueue error = %v, want nil", err)
+	}
+	if err := EndBulkWriter(w, []BulkWrite{{Path: "users/u1", Job: job}}); !errors.Is(err, boom) {
+		t.Errorf("EndBulkWriter() error = %v, want boom", err)
+	}
+}
+
+func TestWithFaults_AbortFirstTransactionCommit(t *testing.T) {
+	ctx := context.Background()
+	client := WithFaults(NewFirestoreClient(newSnapshotClient(t)), FaultRule{Method: "Transaction.Commit", Call: 1, Code: codes.Aborted})
+
+	attempts := 0
+	err := client.RunTransaction(ctx, func(ctx context.Context, tx Transaction) error {
+		attempts++
+		return tx.Set(client.Doc("users/u1").Reference(), map[string]any{"n": attempts})
+	})
+	if err != nil {
+		t.Fatalf("RunTransaction() error = %v", err)
+	}
+	if attempts != 2 {
+		t.Errorf("transaction ran %d times, want 2 (first commit aborted)", attempts)
+	}
+}
+
+func TestWithFaults_NoRules(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	inner := NewMockFirestoreClient(ctrl)
+	coll := NewMockCollectionRef(ctrl)
+	inner.EXPECT().Collections(gomock.Any()).Return(NewCollectionIteratorFromSlice([]*firestore.CollectionRef{{ID: "users"}}, nil))
+	inner.EXPECT().Collection("users").Return(coll)
+	coll.EXPECT().Path().Return(testDocsRoot + "users").AnyTimes()
+	coll.EXPECT().DocumentRefs(gomock.Any()).Return(NewDocumentRefIteratorFromSlice(nil, nil))
+
+	client := WithFaults(inner)
+	it := client.Collections(context.Background())
+	if c, err := it.Next(); err != nil || c.ID != "users" {
+		t.Errorf("Next() = %v, %v", c, err)
+	}
+	if _, err := it.Next(); err != iterator.Done {
+		t.Errorf("Next() = %v, want iterator.Done", err)
+	}
+	if refs, err := client.Collection("users").DocumentRefs(context.Background()).GetAll(); err != nil || len(refs) != 0 {
+		t.Errorf("GetAll() = %v, %v, want empty", refs, err)
+	}
+}
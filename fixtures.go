@@ -0,0 +1,330 @@
+package firestore
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"math"
+	"path"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/genproto/googleapis/type/latlng"
+	"gopkg.in/yaml.v3"
+)
+
+// FixtureCollectionsKey is the document key holding the subcollections of a
+// fixture document.
+const FixtureCollectionsKey = "$collections"
+
+// LoadFixtures writes the documents of the fixture files in fsys matching
+// pattern (see fs.Glob) through client, with Set in batched writes of at most
+// MaxWritesPerBatch (see ChunkedWriter). Files are JSON (.json) or YAML (.yaml,
+// .yml) trees of collections, documents and subcollections:
+//
+//	users:                    # collection (or collection path)
+//	  u1:                     # document ID
+//	    name: Ada
+//	    born: {$timestamp: "1815-12-10T00:00:00Z"}
+//	    manager: {$ref: users/u2}
+//	    home: {$geopoint: [51.5, -0.12]}
+//	    avatar: {$bytes: aGVsbG8=}
+//	    $collections:         # subcollections of users/u1
+//	      posts:
+//	        p1: {title: Hello}
+//
+// A map with a single key starting with "$" is a typed marker: $timestamp (an
+// RFC 3339 time), $ref (a document path, relative to the database root),
+// $geopoint ([latitude, longitude]), $bytes (standard base64), $vector (a
+// firestore.Vector64) or $double (a float, also "NaN", "Infinity" and
+// "-Infinity"). Integers are written as int64, and so are JSON numbers
+// without a fraction or exponent; Dump writes files in this format.
+//
+// A document holding nothing but $collections is not written, only its
+// subcollections, so loading a Dump does not create the missing parents it
+// lists. Documents are written in file order, then in path order within a
+// file; the load is not atomic when it spans several batches.
+func LoadFixtures(ctx context.Context, client FirestoreClient, fsys fs.FS, pattern string) error {
+	names, err := fs.Glob(fsys, pattern)
+	if err != nil {
+		return fmt.Errorf("go-firestore-mock: fixtures %q: %w", pattern, err)
+	}
+	if len(names) == 0 {
+		return fmt.Errorf("go-firestore-mock: no fixture files match %q", pattern)
+	}
+	w := NewChunkedWriter(client, nil)
+	for _, name := range names {
+		docs, err := readFixtures(fsys, name, func(p string) (*firestore.DocumentRef, error) {
+			var ref *firestore.DocumentRef
+			if doc := client.Doc(p); doc != nil {
+				ref = doc.Reference()
+			}
+			if ref == nil {
+				return nil, fmt.Errorf("invalid document path %q", p)
+			}
+			return ref, nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, d := range docs {
+			if !d.onlyCollections {
+				w.Set(d.ref, d.data)
+			}
+		}
+	}
+	_, err = w.Commit(ctx)
+	return err
+}
+
+// fixtureDoc is a document read from a fixture file.
+type fixtureDoc struct {
+	ref  *firestore.DocumentRef
+	data map[string]any
+	// onlyCollections is set for documents holding nothing but
+	// subcollections, such as the missing documents of a Dump.
+	onlyCollections bool
+}
+
+// readFixtures reads the documents of the fixture file name, resolving
+// document paths (of documents and $ref markers) with ref.
+func readFixtures(fsys fs.FS, name string, ref func(path string) (*firestore.DocumentRef, error)) ([]fixtureDoc, error) {
+	b, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, fmt.Errorf("go-firestore-mock: fixtures: %w", err)
+	}
+	return parseFixtures(name, b, &fixtureReader{ref: ref})
+}
+
+// parseFixtures parses the fixture file name holding b with r.
+func parseFixtures(name string, b []byte, r *fixtureReader) ([]fixtureDoc, error) {
+	var tree any
+	var err error
+	switch ext := strings.ToLower(path.Ext(name)); ext {
+	case ".json":
+		dec := json.NewDecoder(bytes.NewReader(b))
+		dec.UseNumber()
+		err = dec.Decode(&tree)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(b, &tree)
+	default:
+		return nil, fmt.Errorf("go-firestore-mock: fixtures %s: unsupported file type %q", name, ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("go-firestore-mock: fixtures %s: %w", name, err)
+	}
+	if tree != nil {
+		if err := r.collections("", tree); err != nil {
+			return nil, fmt.Errorf("go-firestore-mock: fixtures %s: %w", name, err)
+		}
+	}
+	return r.docs, nil
+}
+
+type fixtureReader struct {
+	ref func(path string) (*firestore.DocumentRef, error)
+	// keepIgnored reads the values masked by Dump options as IgnoredValue
+	// instead of failing.
+	keepIgnored bool
+	docs        []fixtureDoc
+}
+
+// collections reads the collections in v, a map of collection IDs (or paths)
+// to documents, under the document at parent ("" for the root).
+func (r *fixtureReader) collections(parent string, v any) error {
+	colls, err := fixtureMap(v)
+	if err != nil {
+		return fmt.Errorf("%s: collections: %w", orRoot(parent), err)
+	}
+	for _, id := range sortedKeys(colls) {
+		collPath := path.Join(parent, id)
+		docs, err := fixtureMap(colls[id])
+		if err != nil {
+			return fmt.Errorf("%s: %w", collPath, err)
+		}
+		for _, docID := range sortedKeys(docs) {
+			if err := r.document(path.Join(collPath, docID), docs[docID]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (r *fixtureReader) document(docPath string, v any) error {
+	fields, err := fixtureMap(v)
+	if err != nil {
+		return fmt.Errorf("%s: %w", docPath, err)
+	}
+	ref, err := r.ref(docPath)
+	if err != nil {
+		return fmt.Errorf("%s: %w", docPath, err)
+	}
+	data := make(map[string]any, len(fields))
+	for k, fv := range fields {
+		if k == FixtureCollectionsKey {
+			continue
+		}
+		if data[k], err = r.value(fv); err != nil {
+			return fmt.Errorf("%s: field %q: %w", docPath, k, err)
+		}
+	}
+	sub, hasColls := fields[FixtureCollectionsKey]
+	r.docs = append(r.docs, fixtureDoc{ref: ref, data: data, onlyCollections: hasColls && len(fields) == 1})
+	if hasColls {
+		return r.collections(docPath, sub)
+	}
+	return nil
+}
+
+// value converts a decoded fixture value to a document value.
+func (r *fixtureReader) value(v any) (any, error) {
+	switch v := v.(type) {
+	case int:
+		return int64(v), nil
+	case uint64:
+		return nil, fmt.Errorf("integer %d overflows int64", v)
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n, nil
+		}
+		return v.Float64()
+	case []any:
+		out := make([]any, len(v))
+		for i, e := range v {
+			var err error
+			if out[i], err = r.value(e); err != nil {
+				return nil, fmt.Errorf("[%d]: %w", i, err)
+			}
+		}
+		return out, nil
+	case map[string]any, map[any]any:
+		m, err := fixtureMap(v)
+		if err != nil {
+			return nil, err
+		}
+		if len(m) == 1 {
+			for k, mv := range m {
+				if strings.HasPrefix(k, "$") {
+					return r.marker(k, mv)
+				}
+			}
+		}
+		out := make(map[string]any, len(m))
+		for k, mv := range m {
+			if out[k], err = r.value(mv); err != nil {
+				return nil, fmt.Errorf("%q: %w", k, err)
+			}
+		}
+		return out, nil
+	}
+	return v, nil // nil, bool, int64, float64, string, time.Time
+}
+
+func (r *fixtureReader) marker(name string, v any) (any, error) {
+	switch name {
+	case "$timestamp":
+		switch v := v.(type) {
+		case time.Time:
+			return v, nil
+		case string:
+			if r.keepIgnored && v == IgnoredValue {
+				return IgnoredValue, nil
+			}
+			t, err := time.Parse(time.RFC3339Nano, v)
+			if err != nil {
+				return nil, fmt.Errorf("$timestamp: %w", err)
+			}
+			return t, nil
+		}
+	case "$ref":
+		if p, ok := v.(string); ok {
+			return r.ref(strings.TrimPrefix(p, "/"))
+		}
+	case "$geopoint":
+		if pt, ok := v.([]any); ok && len(pt) == 2 {
+			lat, latOK := fixtureFloat(pt[0])
+			lng, lngOK := fixtureFloat(pt[1])
+			if latOK && lngOK {
+				return &latlng.LatLng{Latitude: lat, Longitude: lng}, nil
+			}
+		}
+		return nil, fmt.Errorf("$geopoint: want [latitude, longitude], got %v", v)
+	case "$bytes":
+		if s, ok := v.(string); ok {
+			b, err := base64.StdEncoding.DecodeString(s)
+			if err != nil {
+				return nil, fmt.Errorf("$bytes: %w", err)
+			}
+			return b, nil
+		}
+	case "$vector":
+		if vals, ok := v.([]any); ok {
+			vec := make(firestore.Vector64, len(vals))
+			for i, x := range vals {
+				f, ok := fixtureFloat(x)
+				if !ok {
+					return nil, fmt.Errorf("$vector: element %d is %v (%T), want a number", i, x, x)
+				}
+				vec[i] = f
+			}
+			return vec, nil
+		}
+	case "$double":
+		switch v {
+		case "NaN":
+			return math.NaN(), nil
+		case "Infinity":
+			return math.Inf(1), nil
+		case "-Infinity":
+			return math.Inf(-1), nil
+		}
+		if f, ok := fixtureFloat(v); ok {
+			return f, nil
+		}
+	default:
+		return nil, fmt.Errorf("unknown marker %s", name)
+	}
+	return nil, fmt.Errorf("%s: unexpected value %v (%T)", name, v, v)
+}
+
+// fixtureMap returns v as a map with string keys; nil is an empty map.
+func fixtureMap(v any) (map[string]any, error) {
+	switch v := v.(type) {
+	case nil:
+		return map[string]any{}, nil
+	case map[string]any:
+		return v, nil
+	case map[any]any:
+		m := make(map[string]any, len(v))
+		for k, e := range v {
+			m[fmt.Sprint(k)] = e
+		}
+		return m, nil
+	}
+	return nil, fmt.Errorf("want a map, got %v (%T)", v, v)
+}
+
+func fixtureFloat(v any) (float64, bool) {
+	switch v := v.(type) {
+	case int:
+		return float64(v), true
+	case float64:
+		return v, true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func orRoot(p string) string {
+	if p == "" {
+		return "root"
+	}
+	return p
+}
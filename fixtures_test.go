@@ -0,0 +1,163 @@
+package firestore
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/genproto/googleapis/type/latlng"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestLoadFixtures(t *testing.T) {
+	ctx := context.Background()
+	fsys := fstest.MapFS{
+		"testdata/users.yaml": {Data: []byte(`
+users:
+  u1:
+    name: Ada
+    age: 36
+    score: 9.5
+    born: {$timestamp: "1815-12-10T00:00:00Z"}
+    manager: {$ref: users/u2}
+    home: {$geopoint: [51.5, -0.12]}
+    avatar: {$bytes: aGVsbG8=}
+    tags: [a, {$timestamp: "2024-05-01T12:00:00.5Z"}]
+    $collections:
+      posts:
+        p1: {title: Hello}
+  u2:
+`)},
+		"testdata/teams.json": {Data: []byte(`{"teams": {"t1": {"size": 2, "ratio": 0.5, "lead": {"$ref": "users/u1"}, "meta": {"$note": "kept", "x": null}}}, "orgs": {"o1": {"$collections": {"members": {"m1": {}}}}}}`)},
+		"testdata/README.md":  {Data: []byte("not a fixture")},
+	}
+	client := NewFirestoreClient(newSnapshotClient(t))
+	for _, pattern := range []string{"testdata/*.yaml", "testdata/*.json"} {
+		if err := LoadFixtures(ctx, client, fsys, pattern); err != nil {
+			t.Fatalf("LoadFixtures(%s) error = %v", pattern, err)
+		}
+	}
+
+	tests := []struct {
+		path string
+		want map[string]any
+	}{
+		{
+			path: "users/u1",
+			want: map[string]any{
+				"name":    "Ada",
+				"age":     int64(36),
+				"score":   9.5,
+				"born":    time.Date(1815, 12, 10, 0, 0, 0, 0, time.UTC),
+				"manager": client.Doc("users/u2").Reference(),
+				"home":    &latlng.LatLng{Latitude: 51.5, Longitude: -0.12},
+				"avatar":  []byte("hello"),
+				"tags":    []any{"a", time.Date(2024, 5, 1, 12, 0, 0, 500000000, time.UTC)},
+			},
+		},
+		{path: "users/u1/posts/p1", want: map[string]any{"title": "Hello"}},
+		{path: "users/u2", want: map[string]any{}},
+		{
+			path: "teams/t1",
+			want: map[string]any{
+				"size":  int64(2),
+				"ratio": 0.5,
+				"lead":  client.Doc("users/u1").Reference(),
+				"meta":  map[string]any{"$note": "kept", "x": nil},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			snap, err := client.Doc(tt.path).Get(ctx)
+			if err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			got := snap.Data()
+			if home, ok := got["home"].(*latlng.LatLng); ok {
+				if home.Latitude != 51.5 || home.Longitude != -0.12 {
+					t.Errorf("home = %v, want 51.5, -0.12", home)
+				}
+				got["home"], tt.want["home"] = nil, nil
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Data() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// a document holding only subcollections is not created
+	if _, err := client.Doc("orgs/o1/members/m1").Get(ctx); err != nil {
+		t.Errorf("Get(orgs/o1/members/m1) error = %v", err)
+	}
+	if _, err := client.Doc("orgs/o1").Get(ctx); status.Code(err) != codes.NotFound {
+		t.Errorf("Get(orgs/o1) error = %v, want NotFound", err)
+	}
+}
+
+func TestLoadFixtures_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		data    string
+		pattern string
+		wantErr string
+	}{
+		{name: "no match", file: "f.yaml", data: "users: {u1: {}}", pattern: "*.json", wantErr: "no fixture files match"},
+		{name: "file type", file: "f.txt", data: "users: {u1: {}}", pattern: "*", wantErr: `unsupported file type ".txt"`},
+		{name: "syntax", file: "f.json", data: `{"users": `, pattern: "*", wantErr: "f.json"},
+		{name: "document not a map", file: "f.yaml", data: "users: {u1: 3}", pattern: "*", wantErr: "users/u1: want a map"},
+		{name: "unknown marker", file: "f.yaml", data: "users: {u1: {a: {$time: x}}}", pattern: "*", wantErr: `users/u1: field "a": unknown marker $time`},
+		{name: "bad timestamp", file: "f.yaml", data: `users: {u1: {a: {$timestamp: "yesterday"}}}`, pattern: "*", wantErr: "$timestamp"},
+		{name: "bad geopoint", file: "f.yaml", data: "users: {u1: {a: {$geopoint: [1]}}}", pattern: "*", wantErr: "$geopoint: want [latitude, longitude]"},
+		{name: "bad bytes", file: "f.yaml", data: "users: {u1: {a: {$bytes: '%%'}}}", pattern: "*", wantErr: "$bytes"},
+		{name: "bad ref", file: "f.yaml", data: "users: {u1: {a: {$ref: users}}}", pattern: "*", wantErr: `invalid document path "users"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewFirestoreClient(newOfflineClient(t))
+			fsys := fstest.MapFS{tt.file: {Data: []byte(tt.data)}}
+			err := LoadFixtures(context.Background(), client, fsys, tt.pattern)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("LoadFixtures() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadFixtures_BatchesWrites(t *testing.T) {
+	var b strings.Builder
+	b.WriteString("users:\n")
+	for i := range MaxWritesPerBatch + 1 {
+		fmt.Fprintf(&b, "  u%04d: {n: 1}\n", i)
+	}
+	ctrl := gomock.NewController(t)
+	client := NewMockFirestoreClient(ctrl)
+	offline := NewFirestoreClient(newOfflineClient(t))
+	client.EXPECT().Doc(gomock.Any()).DoAndReturn(offline.Doc).AnyTimes()
+	var sizes []int
+	for range 2 {
+		batch := NewMockWriteBatch(ctrl)
+		n := 0
+		batch.EXPECT().Set(gomock.Any(), map[string]any{"n": int64(1)}, gomock.Any()).DoAndReturn(
+			func(*firestore.DocumentRef, any, ...firestore.SetOption) WriteBatch { n++; return batch }).AnyTimes()
+		batch.EXPECT().Commit(gomock.Any()).DoAndReturn(func(context.Context) ([]*firestore.WriteResult, error) {
+			sizes = append(sizes, n)
+			return nil, nil
+		})
+		client.EXPECT().Batch().Return(batch)
+	}
+	if err := LoadFixtures(context.Background(), client, fstest.MapFS{"f.yaml": {Data: []byte(b.String())}}, "f.yaml"); err != nil {
+		t.Fatalf("LoadFixtures() error = %v", err)
+	}
+	if !reflect.DeepEqual(sizes, []int{MaxWritesPerBatch, 1}) {
+		t.Errorf("batch sizes = %v, want [%d 1]", sizes, MaxWritesPerBatch)
+	}
+}
@@ -0,0 +1,257 @@
+// Package fsmatch provides gomock matchers for Firestore arguments that do not
+// compare well with gomock.Eq: update lists, document references, set options,
+// preconditions and document data.
+//
+//	mockDoc.EXPECT().Update(gomock.Any(), fsmatch.UpdatesEq(
+//		firestore.Update{Path: "status", Value: "active"},
+//		firestore.Update{Path: "updatedAt", Value: firestore.ServerTimestamp},
+//	))
+//	mockBatch.EXPECT().Set(fsmatch.DocRefPath("users/u1"), fsmatch.DataMatches(user), fsmatch.IsMergeAll())
+//
+// Every matcher implements gomock.GotFormatter, so a mismatch reports the
+// received value in the same normalized form as the expectation, followed by
+// the individual differences.
+package fsmatch
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"cloud.google.com/go/firestore"
+	"github.com/akmalsyrf/go-firestore-mock/internal/fsvalue"
+	"go.uber.org/mock/gomock"
+)
+
+// Matcher is a gomock.Matcher that also formats the received value.
+type Matcher interface {
+	gomock.Matcher
+	gomock.GotFormatter
+}
+
+// UpdatesEq matches a []firestore.Update holding exactly the given updates, in
+// any order. Updates are keyed by field path (Path or FieldPath); values are
+// compared after normalization, so int and int64 or a struct and its map
+// form are equal. Sentinels (firestore.Delete, firestore.ServerTimestamp) and
+// transforms (firestore.Increment, ...) are compared by value.
+func UpdatesEq(want ...firestore.Update) Matcher {
+	return updatesMatcher{want: updatesByPath(want)}
+}
+
+type updatesMatcher struct {
+	want map[string]any
+}
+
+func (m updatesMatcher) Matches(x any) bool {
+	got, ok := x.([]firestore.Update)
+	if !ok {
+		return false
+	}
+	return len(m.diff(got)) == 0
+}
+
+func (m updatesMatcher) String() string {
+	return "updates equal to " + fsvalue.Format(m.want) + " (any order)"
+}
+
+func (m updatesMatcher) Got(x any) string {
+	got, ok := x.([]firestore.Update)
+	if !ok {
+		return fmt.Sprintf("%v (%T), want []firestore.Update", x, x)
+	}
+	return fsvalue.Format(updatesByPath(got)) + "\n" + strings.Join(m.diff(got), "\n")
+}
+
+func (m updatesMatcher) diff(got []firestore.Update) []string {
+	gm := updatesByPath(got)
+	var lines []string
+	if len(gm) != len(got) {
+		lines = append(lines, "duplicate field paths in updates")
+	}
+	for _, k := range unionKeys(gm, m.want) {
+		gv, inGot := gm[k]
+		wv, inWant := m.want[k]
+		switch {
+		case !inGot:
+			lines = append(lines, fmt.Sprintf("%s: missing, want %s", k, fsvalue.Format(wv)))
+		case !inWant:
+			lines = append(lines, fmt.Sprintf("%s: unexpected %s", k, fsvalue.Format(gv)))
+		case !fsvalue.Equal(gv, wv):
+			lines = append(lines, fmt.Sprintf("%s: got %s, want %s", k, fsvalue.Format(gv), fsvalue.Format(wv)))
+		}
+	}
+	return lines
+}
+
+func updatesByPath(updates []firestore.Update) map[string]any {
+	out := make(map[string]any, len(updates))
+	for _, u := range updates {
+		path := u.Path
+		if path == "" {
+			path = fsvalue.FieldPathString(u.FieldPath)
+		}
+		out[path] = normalizeUpdateValue(u.Value)
+	}
+	return out
+}
+
+// normalizeUpdateValue keeps sentinels comparable with their own values
+// instead of turning firestore.ServerTimestamp into the ignored marker.
+func normalizeUpdateValue(v any) any {
+	if v == firestore.ServerTimestamp {
+		return v
+	}
+	return normalize(v)
+}
+
+// DocRefPath matches a *firestore.DocumentRef (or any value with a
+// Path() string method, such as the DocumentRef wrapper) whose path equals
+// path. Both relative ("users/u1") and full resource paths
+// ("projects/p/databases/(default)/documents/users/u1") are accepted.
+func DocRefPath(path string) Matcher {
+	return docRefPathMatcher{path: fsvalue.RelativePath(path)}
+}
+
+type docRefPathMatcher struct {
+	path string
+}
+
+func (m docRefPathMatcher) Matches(x any) bool {
+	path, ok := docPath(x)
+	return ok && path == m.path
+}
+
+func (m docRefPathMatcher) String() string {
+	return fmt.Sprintf("document reference %q", m.path)
+}
+
+func (m docRefPathMatcher) Got(x any) string {
+	if path, ok := docPath(x); ok {
+		return fmt.Sprintf("document reference %q", path)
+	}
+	return fmt.Sprintf("%v (%T), want *firestore.DocumentRef", x, x)
+}
+
+func docPath(x any) (string, bool) {
+	switch v := x.(type) {
+	case *firestore.DocumentRef:
+		if v == nil {
+			return "", false
+		}
+		return fsvalue.RelativePath(v.Path), true
+	case interface{ Path() string }:
+		if rv := reflect.ValueOf(v); rv.Kind() == reflect.Pointer && rv.IsNil() {
+			return "", false
+		}
+		return fsvalue.RelativePath(v.Path()), true
+	}
+	return "", false
+}
+
+// IsMergeAll matches the firestore.MergeAll set option, passed either as the
+// single variadic argument or as a []firestore.SetOption.
+func IsMergeAll() Matcher {
+	return setOptionMatcher{want: firestore.MergeAll}
+}
+
+type setOptionMatcher struct {
+	want firestore.SetOption
+}
+
+func (m setOptionMatcher) Matches(x any) bool {
+	switch v := x.(type) {
+	case firestore.SetOption:
+		return reflect.DeepEqual(v, m.want)
+	case []firestore.SetOption:
+		return len(v) == 1 && reflect.DeepEqual(v[0], m.want)
+	}
+	return false
+}
+
+func (m setOptionMatcher) String() string {
+	return fmt.Sprintf("set option %v", m.want)
+}
+
+func (m setOptionMatcher) Got(x any) string {
+	return fmt.Sprintf("set option %v", x)
+}
+
+// HasPrecondition matches when the given precondition (e.g. firestore.Exists
+// or firestore.LastUpdateTime(t)) is among the received preconditions. The
+// received value may be a single firestore.Precondition or a
+// []firestore.Precondition.
+func HasPrecondition(want firestore.Precondition) Matcher {
+	return preconditionMatcher{want: want}
+}
+
+type preconditionMatcher struct {
+	want firestore.Precondition
+}
+
+func (m preconditionMatcher) Matches(x any) bool {
+	switch v := x.(type) {
+	case firestore.Precondition:
+		return reflect.DeepEqual(v, m.want)
+	case []firestore.Precondition:
+		for _, p := range v {
+			if reflect.DeepEqual(p, m.want) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (m preconditionMatcher) String() string {
+	return fmt.Sprintf("has precondition %v", m.want)
+}
+
+func (m preconditionMatcher) Got(x any) string {
+	if ps, ok := x.([]firestore.Precondition); ok {
+		parts := make([]string, len(ps))
+		for i, p := range ps {
+			parts[i] = fmt.Sprint(p)
+		}
+		sort.Strings(parts)
+		return "preconditions [" + strings.Join(parts, ", ") + "]"
+	}
+	return fmt.Sprintf("precondition %v", x)
+}
+
+// DataMatches matches document data (the data argument of Set, Create, Add,
+// ...) equal to want. Both sides may be structs (encoded by their firestore
+// tags) or maps; numbers are compared as int64 / float64 and times with
+// time.Time.Equal.
+//
+// Fields holding firestore.ServerTimestamp, and zero time.Time fields tagged
+// `firestore:",serverTimestamp"`, are ignored on both sides.
+func DataMatches(want any) Matcher {
+	return dataMatcher{want: want}
+}
+
+type dataMatcher struct {
+	want any
+}
+
+func (m dataMatcher) Matches(x any) bool {
+	return len(m.diff(x)) == 0
+}
+
+func (m dataMatcher) String() string {
+	return "data matching " + fsvalue.Format(normalize(m.want))
+}
+
+func (m dataMatcher) Got(x any) string {
+	return fsvalue.Format(normalize(x)) + "\n" + strings.Join(m.diff(x), "\n")
+}
+
+func (m dataMatcher) diff(x any) []string {
+	got, want := normalize(x), normalize(m.want)
+	gm, gok := got.(map[string]any)
+	wm, wok := want.(map[string]any)
+	if gok && wok {
+		dropServerTimestamps(gm, wm)
+	}
+	return diffValues("", got, want)
+}
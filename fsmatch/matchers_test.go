@@ -0,0 +1,268 @@
+package fsmatch
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	gofirestoremock "github.com/akmalsyrf/go-firestore-mock"
+	"go.uber.org/mock/gomock"
+)
+
+func TestUpdatesEq(t *testing.T) {
+	m := UpdatesEq(
+		firestore.Update{Path: "status", Value: "active"},
+		firestore.Update{FieldPath: firestore.FieldPath{"profile", "age"}, Value: 30},
+		firestore.Update{Path: "updatedAt", Value: firestore.ServerTimestamp},
+	)
+
+	tests := []struct {
+		name string
+		got  any
+		want bool
+	}{
+		{
+			name: "same order",
+			got: []firestore.Update{
+				{Path: "status", Value: "active"},
+				{Path: "profile.age", Value: int64(30)},
+				{Path: "updatedAt", Value: firestore.ServerTimestamp},
+			},
+			want: true,
+		},
+		{
+			name: "different order",
+			got: []firestore.Update{
+				{Path: "updatedAt", Value: firestore.ServerTimestamp},
+				{FieldPath: firestore.FieldPath{"profile", "age"}, Value: 30},
+				{Path: "status", Value: "active"},
+			},
+			want: true,
+		},
+		{
+			name: "different value",
+			got: []firestore.Update{
+				{Path: "status", Value: "inactive"},
+				{Path: "profile.age", Value: 30},
+				{Path: "updatedAt", Value: firestore.ServerTimestamp},
+			},
+			want: false,
+		},
+		{
+			name: "missing update",
+			got: []firestore.Update{
+				{Path: "status", Value: "active"},
+				{Path: "profile.age", Value: 30},
+			},
+			want: false,
+		},
+		{
+			name: "delete instead of server timestamp",
+			got: []firestore.Update{
+				{Path: "status", Value: "active"},
+				{Path: "profile.age", Value: 30},
+				{Path: "updatedAt", Value: firestore.Delete},
+			},
+			want: false,
+		},
+		{
+			name: "wrong type",
+			got:  map[string]any{"status": "active"},
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := m.Matches(tt.got); got != tt.want {
+				t.Errorf("Matches() = %v, want %v\n%s", got, tt.want, m.Got(tt.got))
+			}
+		})
+	}
+}
+
+func TestUpdatesEq_FailureMessage(t *testing.T) {
+	m := UpdatesEq(firestore.Update{Path: "status", Value: "active"}, firestore.Update{Path: "age", Value: 30})
+	got := m.Got([]firestore.Update{{Path: "status", Value: "inactive"}, {Path: "name", Value: "x"}})
+
+	for _, want := range []string{
+		`age: missing, want 30`,
+		`name: unexpected "x"`,
+		`status: got "inactive", want "active"`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Got() = %q, want it to contain %q", got, want)
+		}
+	}
+}
+
+func TestDocRefPath(t *testing.T) {
+	client := newOfflineClient(t)
+	m := DocRefPath("users/u1")
+
+	if !m.Matches(client.Doc("users/u1")) {
+		t.Error("expected match for *firestore.DocumentRef users/u1")
+	}
+	if !m.Matches(gofirestoremock.NewFirestoreClient(client).Doc("users/u1")) {
+		t.Error("expected match for DocumentRef wrapper users/u1")
+	}
+	if m.Matches(client.Doc("users/u2")) {
+		t.Error("unexpected match for users/u2")
+	}
+	if m.Matches((*firestore.DocumentRef)(nil)) {
+		t.Error("unexpected match for nil ref")
+	}
+	if !DocRefPath("projects/test-project/databases/(default)/documents/users/u1").Matches(client.Doc("users/u1")) {
+		t.Error("expected full resource path to match")
+	}
+	if got, want := m.Got(client.Doc("users/u2")), `document reference "users/u2"`; got != want {
+		t.Errorf("Got() = %q, want %q", got, want)
+	}
+}
+
+func TestIsMergeAll(t *testing.T) {
+	m := IsMergeAll()
+
+	if !m.Matches(firestore.MergeAll) {
+		t.Error("expected match for MergeAll")
+	}
+	if !m.Matches([]firestore.SetOption{firestore.MergeAll}) {
+		t.Error("expected match for []SetOption{MergeAll}")
+	}
+	if m.Matches(firestore.Merge(firestore.FieldPath{"a"})) {
+		t.Error("unexpected match for Merge(a)")
+	}
+	if m.Matches([]firestore.SetOption{}) {
+		t.Error("unexpected match for no options")
+	}
+}
+
+func TestHasPrecondition(t *testing.T) {
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	if !HasPrecondition(firestore.Exists).Matches(firestore.Exists) {
+		t.Error("expected match for Exists")
+	}
+	if !HasPrecondition(firestore.LastUpdateTime(ts)).Matches([]firestore.Precondition{firestore.LastUpdateTime(ts)}) {
+		t.Error("expected match for LastUpdateTime slice")
+	}
+	if HasPrecondition(firestore.Exists).Matches([]firestore.Precondition{}) {
+		t.Error("unexpected match for no preconditions")
+	}
+	if HasPrecondition(firestore.Exists).Matches(firestore.LastUpdateTime(ts)) {
+		t.Error("unexpected match for LastUpdateTime")
+	}
+}
+
+type user struct {
+	ID        string    `firestore:"-"`
+	Name      string    `firestore:"name"`
+	Age       int       `firestore:"age"`
+	Nickname  string    `firestore:"nickname,omitempty"`
+	UpdatedAt time.Time `firestore:"updatedAt,serverTimestamp"`
+}
+
+func TestDataMatches(t *testing.T) {
+	tests := []struct {
+		name      string
+		want, got any
+		match     bool
+	}{
+		{
+			name:  "struct vs map",
+			want:  user{ID: "u1", Name: "Ann", Age: 30},
+			got:   map[string]any{"name": "Ann", "age": 30, "updatedAt": firestore.ServerTimestamp},
+			match: true,
+		},
+		{
+			name:  "map vs struct pointer",
+			want:  map[string]any{"name": "Ann", "age": int64(30)},
+			got:   &user{Name: "Ann", Age: 30},
+			match: true,
+		},
+		{
+			name:  "ignores server timestamp in got only",
+			want:  map[string]any{"name": "Ann"},
+			got:   map[string]any{"name": "Ann", "createdAt": firestore.ServerTimestamp},
+			match: true,
+		},
+		{
+			name:  "different value",
+			want:  user{Name: "Ann", Age: 30},
+			got:   user{Name: "Ann", Age: 31},
+			match: false,
+		},
+		{
+			name:  "int is not float",
+			want:  map[string]any{"score": 1},
+			got:   map[string]any{"score": 1.0},
+			match: false,
+		},
+		{
+			name:  "nested maps",
+			want:  map[string]any{"address": map[string]any{"city": "Oslo"}},
+			got:   map[string]any{"address": map[string]string{"city": "Oslo"}},
+			match: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := DataMatches(tt.want)
+			if got := m.Matches(tt.got); got != tt.match {
+				t.Errorf("Matches() = %v, want %v\n%s\n%s", got, tt.match, m.String(), m.Got(tt.got))
+			}
+		})
+	}
+}
+
+func TestDataMatches_FailureMessage(t *testing.T) {
+	m := DataMatches(user{Name: "Ann", Age: 30})
+	got := m.Got(user{Name: "Bob", Age: 30})
+	if !strings.Contains(got, `name: got "Bob", want "Ann"`) {
+		t.Errorf("Got() = %q, want the name difference", got)
+	}
+}
+
+func TestMatchers_WithGomock(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	client := newOfflineClient(t)
+
+	mockDoc := gofirestoremock.NewMockDocumentRef(ctrl)
+	mockBatch := gofirestoremock.NewMockWriteBatch(ctrl)
+
+	mockDoc.EXPECT().
+		Update(gomock.Any(), UpdatesEq(
+			firestore.Update{Path: "b", Value: 2},
+			firestore.Update{Path: "a", Value: 1},
+		)).
+		Return(nil, nil)
+	mockDoc.EXPECT().
+		Set(gomock.Any(), DataMatches(map[string]any{"name": "Ann", "age": 30}), IsMergeAll()).
+		Return(nil, nil)
+	mockBatch.EXPECT().
+		Delete(DocRefPath("users/u1"), HasPrecondition(firestore.Exists)).
+		Return(mockBatch)
+
+	if _, err := mockDoc.Update(ctx, []firestore.Update{{Path: "a", Value: 1}, {Path: "b", Value: 2}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mockDoc.Set(ctx, user{Name: "Ann", Age: 30}, firestore.MergeAll); err != nil {
+		t.Fatal(err)
+	}
+	mockBatch.Delete(client.Doc("users/u1"), firestore.Exists)
+}
+
+func newOfflineClient(t *testing.T) *firestore.Client {
+	t.Helper()
+	t.Setenv("FIRESTORE_EMULATOR_HOST", "127.0.0.1:1")
+	client, err := firestore.NewClient(context.Background(), "test-project")
+	if err != nil {
+		t.Fatalf("firestore.NewClient: %v", err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+	return client
+}
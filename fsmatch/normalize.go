@@ -0,0 +1,240 @@
+package fsmatch
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/akmalsyrf/go-firestore-mock/internal/fsvalue"
+)
+
+var (
+	timeType   = reflect.TypeOf(time.Time{})
+	docRefType = reflect.TypeOf((*firestore.DocumentRef)(nil))
+	bytesType  = reflect.TypeOf([]byte(nil))
+)
+
+// serverTimestamp marks a value that Firestore fills in on commit: either the
+// firestore.ServerTimestamp sentinel or a zero time.Time in a struct field
+// tagged `firestore:",serverTimestamp"`.
+type serverTimestamp struct{}
+
+func (serverTimestamp) String() string { return "ServerTimestamp" }
+
+// normalize converts data passed to Set / Create / Update into the plain
+// representation Firestore stores: structs become maps keyed by their
+// firestore tag names, integers become int64, floats float64, slices []any and
+// maps map[string]any. Other values (time.Time, *firestore.DocumentRef,
+// sentinels, ...) are returned unchanged.
+func normalize(v any) any {
+	if v == nil {
+		return nil
+	}
+	if v == firestore.ServerTimestamp {
+		return serverTimestamp{}
+	}
+	return normalizeValue(reflect.ValueOf(v))
+}
+
+func normalizeValue(rv reflect.Value) any {
+	if !rv.IsValid() {
+		return nil
+	}
+	if rv.Type() == timeType || rv.Type() == docRefType || rv.Type() == bytesType || isOpaque(rv.Type()) {
+		if !rv.CanInterface() {
+			return nil
+		}
+		return rv.Interface()
+	}
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if rv.IsNil() {
+			return nil
+		}
+		if rv.Kind() == reflect.Interface {
+			return normalize(rv.Elem().Interface())
+		}
+		return normalizeValue(rv.Elem())
+	case reflect.Bool:
+		return rv.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		return rv.Float()
+	case reflect.String:
+		return rv.String()
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			return nil
+		}
+		out := make([]any, rv.Len())
+		for i := range out {
+			out[i] = normalizeValue(rv.Index(i))
+		}
+		return out
+	case reflect.Map:
+		if rv.IsNil() {
+			return nil
+		}
+		out := make(map[string]any, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			out[fmt.Sprint(iter.Key().Interface())] = normalizeValue(iter.Value())
+		}
+		return out
+	case reflect.Struct:
+		out := map[string]any{}
+		normalizeStruct(rv, out)
+		return out
+	}
+	if rv.CanInterface() {
+		return rv.Interface()
+	}
+	return nil
+}
+
+// isOpaque reports whether values of t are kept as is by normalize: types
+// declared by the Firestore SDK (sentinels such as firestore.Delete, field
+// transforms such as firestore.Increment) and protobuf messages such as
+// *latlng.LatLng.
+func isOpaque(t reflect.Type) bool {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	pkg := t.PkgPath()
+	return strings.HasPrefix(pkg, "cloud.google.com/go/firestore") || strings.HasPrefix(pkg, "google.golang.org/")
+}
+
+// normalizeStruct copies the exported fields of rv into out, following the
+// `firestore:"name,omitempty,serverTimestamp"` tag conventions of the SDK.
+// Anonymous struct fields without a name are flattened into out.
+func normalizeStruct(rv reflect.Value, out map[string]any) {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		f := rt.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, opts := parseTag(f.Tag.Get("firestore"))
+		if name == "-" {
+			continue
+		}
+		fv := rv.Field(i)
+		if f.Anonymous && name == "" {
+			ev := fv
+			if ev.Kind() == reflect.Pointer {
+				if ev.IsNil() {
+					continue
+				}
+				ev = ev.Elem()
+			}
+			if ev.Kind() == reflect.Struct && ev.Type() != timeType {
+				normalizeStruct(ev, out)
+				continue
+			}
+		}
+		if name == "" {
+			name = f.Name
+		}
+		if opts["serverTimestamp"] && fv.IsZero() {
+			out[name] = serverTimestamp{}
+			continue
+		}
+		if opts["omitempty"] && fv.IsZero() {
+			continue
+		}
+		out[name] = normalizeValue(fv)
+	}
+}
+
+func parseTag(tag string) (string, map[string]bool) {
+	parts := strings.Split(tag, ",")
+	opts := map[string]bool{}
+	for _, o := range parts[1:] {
+		opts[o] = true
+	}
+	return parts[0], opts
+}
+
+// dropServerTimestamps removes, recursively, every key of got and want whose
+// value is a server timestamp on either side.
+func dropServerTimestamps(got, want map[string]any) {
+	for k, gv := range got {
+		wv, inWant := want[k]
+		_, gts := gv.(serverTimestamp)
+		_, wts := wv.(serverTimestamp)
+		if gts || wts {
+			delete(got, k)
+			delete(want, k)
+			continue
+		}
+		gm, gok := gv.(map[string]any)
+		wm, wok := wv.(map[string]any)
+		if inWant && gok && wok {
+			dropServerTimestamps(gm, wm)
+		}
+	}
+	for k, wv := range want {
+		if _, ok := wv.(serverTimestamp); ok {
+			delete(want, k)
+		}
+	}
+}
+
+// diffValues returns one line per difference between got and want, prefixed
+// with the dotted field path.
+func diffValues(path string, got, want any) []string {
+	gm, gok := got.(map[string]any)
+	wm, wok := want.(map[string]any)
+	if gok && wok {
+		var lines []string
+		for _, k := range unionKeys(gm, wm) {
+			p := k
+			if path != "" {
+				p = path + "." + k
+			}
+			gv, inGot := gm[k]
+			wv, inWant := wm[k]
+			switch {
+			case !inGot:
+				lines = append(lines, fmt.Sprintf("%s: missing, want %s", p, fsvalue.Format(wv)))
+			case !inWant:
+				lines = append(lines, fmt.Sprintf("%s: unexpected %s", p, fsvalue.Format(gv)))
+			default:
+				lines = append(lines, diffValues(p, gv, wv)...)
+			}
+		}
+		return lines
+	}
+	if fsvalue.Equal(got, want) {
+		return nil
+	}
+	if path == "" {
+		path = "(root)"
+	}
+	return []string{fmt.Sprintf("%s: got %s, want %s", path, fsvalue.Format(got), fsvalue.Format(want))}
+}
+
+func unionKeys(a, b map[string]any) []string {
+	seen := make(map[string]bool, len(a)+len(b))
+	var keys []string
+	for k := range a {
+		if !seen[k] {
+			seen[k] = true
+			keys = append(keys, k)
+		}
+	}
+	for k := range b {
+		if !seen[k] {
+			seen[k] = true
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
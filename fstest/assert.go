@@ -0,0 +1,175 @@
+package fstest
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	gofirestoremock "github.com/akmalsyrf/go-firestore-mock"
+	"github.com/akmalsyrf/go-firestore-mock/internal/fsvalue"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// maxCountDocs bounds the documents listed by a failing AssertCount.
+const maxCountDocs = 20
+
+// AssertExists checks that the document at path (relative to the database
+// root, e.g. "users/u1") exists.
+func AssertExists(t testing.TB, client gofirestoremock.FirestoreClient, path string) {
+	t.Helper()
+	if _, ok := getDoc(t, client, path); !ok {
+		t.Errorf("fstest: %s does not exist", path)
+	}
+}
+
+// AssertMissing checks that the document at path does not exist, reporting
+// its data otherwise.
+func AssertMissing(t testing.TB, client gofirestoremock.FirestoreClient, path string) {
+	t.Helper()
+	if data, ok := getDoc(t, client, path); ok {
+		t.Errorf("fstest: %s exists, want missing\ndocument: %s", path, fsvalue.Format(data))
+	}
+}
+
+// AssertField checks that the field of the document at path equals want.
+// field is a dotted field path such as "address.city"; values are compared
+// as gofirestoremock.Diff compares them, so want may be an int for an int64
+// field but not for a float64 one. Failures show the whole document.
+func AssertField(t testing.TB, client gofirestoremock.FirestoreClient, path, field string, want any) {
+	t.Helper()
+	data, ok := getDoc(t, client, path)
+	if !ok {
+		t.Errorf("fstest: %s does not exist, want %s = %s", path, field, fsvalue.Format(want))
+		return
+	}
+	got, ok := lookupField(data, field)
+	switch {
+	case !ok:
+		t.Errorf("fstest: %s has no field %s, want %s\ndocument: %s",
+			path, field, fsvalue.Format(want), fsvalue.Format(data))
+	case !fsvalue.Equal(got, want):
+		t.Errorf("fstest: %s: %s = %s, want %s\ndocument: %s",
+			path, field, fsvalue.Format(got), fsvalue.Format(want), fsvalue.Format(data))
+	}
+}
+
+// AssertCount checks that query returns want documents, listing the
+// documents returned otherwise. The query is read with GetAll, so LimitToLast
+// queries work.
+func AssertCount(t testing.TB, query gofirestoremock.Query, want int) {
+	t.Helper()
+	snaps, err := query.Documents(t.Context()).GetAll()
+	if err != nil {
+		t.Fatalf("fstest: running query: %v", err)
+	}
+	var docs []string
+	for _, snap := range snaps {
+		docs = append(docs, fsvalue.RelativePath(snap.Ref.Path)+": "+fsvalue.Format(snap.Data()))
+	}
+	n := len(docs)
+	if n == want {
+		return
+	}
+	if n > maxCountDocs {
+		docs = append(docs[:maxCountDocs], fmt.Sprintf("... (%d more)", n-maxCountDocs))
+	}
+	msg := fmt.Sprintf("fstest: query returned %d documents, want %d", n, want)
+	if n > 0 {
+		msg += ":\n  " + strings.Join(docs, "\n  ")
+	}
+	t.Errorf("%s", msg)
+}
+
+// DocOption configures AssertDocEquals.
+type DocOption func(*docConfig)
+
+type docConfig struct {
+	ignore []string
+}
+
+// IgnoreFields leaves the given dotted field paths, such as "updatedAt" or
+// "meta.etag", out of the comparison.
+func IgnoreFields(fields ...string) DocOption {
+	return func(c *docConfig) { c.ignore = append(c.ignore, fields...) }
+}
+
+// AssertDocEquals checks that the document at path holds exactly the fields
+// of want, compared as gofirestoremock.Diff compares them, and reports the
+// differing fields (-want +got) and the whole document otherwise.
+func AssertDocEquals(t testing.TB, client gofirestoremock.FirestoreClient, path string, want map[string]any, opts ...DocOption) {
+	t.Helper()
+	cfg := &docConfig{}
+	for _, o := range opts {
+		o(cfg)
+	}
+	data, ok := getDoc(t, client, path)
+	if !ok {
+		t.Errorf("fstest: %s does not exist, want %s", path, fsvalue.Format(want))
+		return
+	}
+	got := data
+	if len(cfg.ignore) > 0 {
+		got, want = withoutFields(got, cfg.ignore), withoutFields(want, cfg.ignore)
+	}
+	changes := gofirestoremock.Diff(gofirestoremock.Snapshot{path: want}, gofirestoremock.Snapshot{path: got})
+	if len(changes) > 0 {
+		t.Errorf("fstest: %s differs (-want +got):\n%s\ndocument: %s",
+			path, strings.TrimSuffix(gofirestoremock.FormatChanges(changes), "\n"), fsvalue.Format(data))
+	}
+}
+
+// getDoc reads the document at path, reporting whether it exists.
+func getDoc(t testing.TB, client gofirestoremock.FirestoreClient, path string) (map[string]any, bool) {
+	t.Helper()
+	doc := client.Doc(path)
+	if doc == nil {
+		t.Fatalf("fstest: invalid document path %q", path)
+	}
+	snap, err := doc.Get(t.Context())
+	if status.Code(err) == codes.NotFound {
+		return nil, false
+	}
+	if err != nil {
+		t.Fatalf("fstest: reading %s: %v", path, err)
+	}
+	if snap == nil || !snap.Exists() {
+		return nil, false
+	}
+	return snap.Data(), true
+}
+
+// lookupField returns the value at the dotted field path in data.
+func lookupField(data map[string]any, field string) (any, bool) {
+	var v any = data
+	for _, key := range strings.Split(field, ".") {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if v, ok = m[key]; !ok {
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// withoutFields returns a copy of data without the dotted field paths,
+// copying only the maps it changes.
+func withoutFields(data map[string]any, fields []string) map[string]any {
+	out := make(map[string]any, len(data))
+	for k, v := range data {
+		out[k] = v
+	}
+	for _, f := range fields {
+		key, rest, nested := strings.Cut(f, ".")
+		if !nested {
+			delete(out, key)
+			continue
+		}
+		if m, ok := out[key].(map[string]any); ok {
+			out[key] = withoutFields(m, []string{rest})
+		}
+	}
+	return out
+}
@@ -0,0 +1,130 @@
+package fstest
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/firestore"
+	gofirestoremock "github.com/akmalsyrf/go-firestore-mock"
+	"go.uber.org/mock/gomock"
+)
+
+// runAssert runs assert with a fakeT and returns its failure message.
+func runAssert(t *testing.T, assert func(t testing.TB)) (msg string) {
+	t.Helper()
+	ft := &fakeT{TB: t}
+	defer func() {
+		if r := recover(); r != nil && !errors.Is(r.(error), errFatal) {
+			panic(r)
+		}
+		msg = ft.msg
+	}()
+	assert(ft)
+	return ft.msg
+}
+
+func TestAssertions(t *testing.T) {
+	client := newUsersClient(t, map[string]map[string]any{
+		"u1": {"name": "Ada", "status": "active", "age": 36, "score": 1.5,
+			"address": map[string]any{"city": "London", "zip": "N1"}, "updatedAt": "2024-01-01"},
+	})
+
+	tests := []struct {
+		name   string
+		assert func(t testing.TB)
+		want   []string // substrings of the failure message; nil for success
+	}{
+		{"exists", func(t testing.TB) { AssertExists(t, client, "users/u1") }, nil},
+		{"exists missing", func(t testing.TB) { AssertExists(t, client, "users/u9") },
+			[]string{"fstest: users/u9 does not exist"}},
+		{"missing", func(t testing.TB) { AssertMissing(t, client, "users/u9") }, nil},
+		{"missing exists", func(t testing.TB) { AssertMissing(t, client, "users/u1") },
+			[]string{"fstest: users/u1 exists, want missing", `document: {address: {city: "London", zip: "N1"}, age: 36,`}},
+		{"field", func(t testing.TB) { AssertField(t, client, "users/u1", "status", "active") }, nil},
+		{"field int", func(t testing.TB) { AssertField(t, client, "users/u1", "age", 36) }, nil},
+		{"field nested", func(t testing.TB) { AssertField(t, client, "users/u1", "address.city", "London") }, nil},
+		{"field differs", func(t testing.TB) { AssertField(t, client, "users/u1", "status", "banned") },
+			[]string{`fstest: users/u1: status = "active", want "banned"`, `status: "active"`}},
+		{"field type differs", func(t testing.TB) { AssertField(t, client, "users/u1", "score", 1) },
+			[]string{"score = 1.5, want 1\n"}},
+		{"field absent", func(t testing.TB) { AssertField(t, client, "users/u1", "address.country", "UK") },
+			[]string{`fstest: users/u1 has no field address.country, want "UK"`, "document: {"}},
+		{"field doc missing", func(t testing.TB) { AssertField(t, client, "users/u9", "status", "active") },
+			[]string{`fstest: users/u9 does not exist, want status = "active"`}},
+		{"doc equals", func(t testing.TB) {
+			AssertDocEquals(t, client, "users/u1", map[string]any{"name": "Ada", "status": "active", "age": 36, "score": 1.5,
+				"address": map[string]any{"city": "London"}}, IgnoreFields("updatedAt", "address.zip"))
+		}, nil},
+		{"doc differs", func(t testing.TB) {
+			AssertDocEquals(t, client, "users/u1", map[string]any{"name": "Ada", "status": "banned", "age": 36, "score": 1.5,
+				"address": map[string]any{"city": "Paris", "zip": "N1"}, "role": "admin"}, IgnoreFields("updatedAt"))
+		}, []string{
+			"fstest: users/u1 differs (-want +got):\n  users/u1:\n" +
+				"-   address.city: \"Paris\"\n+   address.city: \"London\"\n" +
+				"-   role: \"admin\"\n" +
+				"-   status: \"banned\"\n+   status: \"active\"\ndocument: {",
+			`updatedAt: "2024-01-01"}`,
+		}},
+		{"doc equals missing", func(t testing.TB) { AssertDocEquals(t, client, "users/u9", map[string]any{"name": "Bob"}) },
+			[]string{`fstest: users/u9 does not exist, want {name: "Bob"}`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := runAssert(t, tt.assert)
+			if tt.want == nil {
+				if msg != "" {
+					t.Errorf("unexpected failure: %s", msg)
+				}
+				return
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(msg, w) {
+					t.Errorf("failure message\n%s\ndoes not contain\n%s", msg, w)
+				}
+			}
+		})
+	}
+}
+
+func TestAssertCount(t *testing.T) {
+	var snaps []*firestore.DocumentSnapshot
+	for _, id := range []string{"u1", "u2"} {
+		snap, err := gofirestoremock.SnapshotFromData("projects/p/databases/(default)/documents/users/"+id, map[string]any{"status": "active"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		snaps = append(snaps, snap)
+	}
+	ctrl := gomock.NewController(t)
+	query := gofirestoremock.NewMockQuery(ctrl)
+	query.EXPECT().Documents(gomock.Any()).DoAndReturn(func(any) gofirestoremock.DocumentIterator {
+		return gofirestoremock.NewDocumentIteratorFromSlice(snaps, nil)
+	}).AnyTimes()
+
+	tests := []struct {
+		name string
+		want int
+		msg  string
+	}{
+		{"equal", 2, ""},
+		{"differs", 3, "fstest: query returned 2 documents, want 3:\n" +
+			"  users/u1: {status: \"active\"}\n  users/u2: {status: \"active\"}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if msg := runAssert(t, func(t testing.TB) { AssertCount(t, query, tt.want) }); msg != tt.msg {
+				t.Errorf("message = %q, want %q", msg, tt.msg)
+			}
+		})
+	}
+
+	// LimitToLast queries can only be read with GetAll; Next is not expected
+	it := gofirestoremock.NewMockDocumentIterator(ctrl)
+	it.EXPECT().GetAll().Return(snaps, nil)
+	last := gofirestoremock.NewMockQuery(ctrl)
+	last.EXPECT().Documents(gomock.Any()).Return(it)
+	if msg := runAssert(t, func(t testing.TB) { AssertCount(t, last, 2) }); msg != "" {
+		t.Errorf("GetAll: message = %q, want none", msg)
+	}
+}
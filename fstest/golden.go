@@ -0,0 +1,142 @@
+// Package fstest provides assertions on the documents reachable through a
+// gofirestoremock.FirestoreClient, for tests that run a workflow against the
+// emulator, a fake backend or a Replay and then check the resulting database.
+//
+//	gofirestoremock.LoadFixtures(ctx, client, testdata, "testdata/before.yaml")
+//	runCheckout(ctx, client)
+//	fstest.AssertGolden(t, client, "testdata/after_checkout.json",
+//		gofirestoremock.IgnoreServerTimestamps(start), gofirestoremock.IgnoreGeneratedIDs())
+//	fstest.AssertField(t, client, "orders/o1", "status", "paid")
+//
+// The package defines the -fstest.update test flag, which rewrites golden
+// files; it is namespaced so that packages defining their own -update flag
+// can import fstest.
+package fstest
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	gofirestoremock "github.com/akmalsyrf/go-firestore-mock"
+)
+
+var update = flag.Bool("fstest.update", false, "rewrite the golden files of fstest.AssertGolden")
+
+// AssertGolden dumps the whole database of client with gofirestoremock.Dump
+// and compares the dump with the golden file, reporting the changed documents
+// and fields (see gofirestoremock.Diff).
+// With -fstest.update, it writes the dump to the golden file instead (creating its
+// directory), so the expected state can be reviewed in version control.
+func AssertGolden(t testing.TB, client gofirestoremock.FirestoreClient, golden string, opts ...gofirestoremock.DumpOption) {
+	t.Helper()
+	got, err := gofirestoremock.Dump(t.Context(), client, "", opts...)
+	if err != nil {
+		t.Fatalf("fstest: dump for %s: %v", golden, err)
+	}
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(golden), 0o755); err != nil {
+			t.Fatalf("fstest: %v", err)
+		}
+		if err := os.WriteFile(golden, got, 0o644); err != nil {
+			t.Fatalf("fstest: %v", err)
+		}
+		return
+	}
+	want, err := os.ReadFile(golden)
+	if errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("fstest: golden file %s does not exist; run the test with -fstest.update to create it", golden)
+	}
+	if err != nil {
+		t.Fatalf("fstest: %v", err)
+	}
+	want = bytes.ReplaceAll(want, []byte("\r\n"), []byte("\n"))
+	if !bytes.Equal(want, got) {
+		t.Errorf("fstest: database differs from %s (-want +got):\n%s\nrun the test with -fstest.update to accept the new state", golden, goldenDiff(want, got))
+	}
+}
+
+// goldenDiff reports the changes from the golden dump want to the dump got,
+// or their differing lines if either cannot be read as a snapshot or the
+// snapshots are equal (e.g. the dumps only differ in format).
+func goldenDiff(want, got []byte) string {
+	before, err := gofirestoremock.ReadSnapshot(want)
+	if err != nil {
+		return lineDiff(string(want), string(got))
+	}
+	after, err := gofirestoremock.ReadSnapshot(got)
+	if err != nil {
+		return lineDiff(string(want), string(got))
+	}
+	if changes := gofirestoremock.Diff(before, after); len(changes) > 0 {
+		return strings.TrimSuffix(gofirestoremock.FormatChanges(changes), "\n")
+	}
+	return lineDiff(string(want), string(got))
+}
+
+// maxDiffLines bounds the lines reported by lineDiff.
+const maxDiffLines = 100
+
+// maxDiffCells bounds the size of the table lineDiff builds, the product of
+// the line counts left once the common prefix and suffix are dropped.
+const maxDiffCells = 1 << 20
+
+// lineDiff returns the lines of want missing from got ("-") and of got missing
+// from want ("+"), with the line numbers of want, in order. When the differing
+// region is too large to compare line by line, it reports the first differing
+// line instead.
+func lineDiff(want, got string) string {
+	a := strings.Split(strings.TrimSuffix(want, "\n"), "\n")
+	b := strings.Split(strings.TrimSuffix(got, "\n"), "\n")
+	// Drop the common prefix and suffix; start is the line number offset.
+	start := 0
+	for start < len(a) && start < len(b) && a[start] == b[start] {
+		start++
+	}
+	a, b = a[start:], b[start:]
+	for len(a) > 0 && len(b) > 0 && a[len(a)-1] == b[len(b)-1] {
+		a, b = a[:len(a)-1], b[:len(b)-1]
+	}
+	if len(a)*len(b) > maxDiffCells {
+		return fmt.Sprintf("%4d - %s\n%4d + %s\n     ... (%d lines differ from %d, too many to compare)",
+			start+1, a[0], start+1, b[0], len(b), len(a))
+	}
+	// lcs[i][j] is the length of the longest common subsequence of a[i:], b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	var out []string
+	i, j := 0, 0
+	for (i < len(a) || j < len(b)) && len(out) < maxDiffLines {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			i, j = i+1, j+1
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			out = append(out, fmt.Sprintf("%4d - %s", start+i+1, a[i]))
+			i++
+		default:
+			out = append(out, fmt.Sprintf("%4d + %s", start+i+1, b[j]))
+			j++
+		}
+	}
+	if i < len(a) || j < len(b) {
+		out = append(out, "     ...")
+	}
+	return strings.Join(out, "\n")
+}
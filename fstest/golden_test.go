@@ -0,0 +1,191 @@
+package fstest
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/firestore"
+	gofirestoremock "github.com/akmalsyrf/go-firestore-mock"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// newUsersClient returns a mock client whose database holds the collection
+// users with the given documents.
+func newUsersClient(t *testing.T, docs map[string]map[string]any) gofirestoremock.FirestoreClient {
+	t.Helper()
+	ctrl := gomock.NewController(t)
+	client := gofirestoremock.NewMockFirestoreClient(ctrl)
+	users := gofirestoremock.NewMockCollectionRef(ctrl)
+	client.EXPECT().Collections(gomock.Any()).DoAndReturn(func(any) gofirestoremock.CollectionIterator {
+		return gofirestoremock.NewCollectionIteratorFromSlice([]*firestore.CollectionRef{{ID: "users"}}, nil)
+	}).AnyTimes()
+	client.EXPECT().Collection("users").Return(users).AnyTimes()
+	users.EXPECT().Path().Return("projects/p/databases/(default)/documents/users").AnyTimes()
+
+	var refs []*firestore.DocumentRef
+	for id, data := range docs {
+		path := "projects/p/databases/(default)/documents/users/" + id
+		refs = append(refs, &firestore.DocumentRef{ID: id, Path: path})
+		snap, err := gofirestoremock.SnapshotFromData(path, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		doc := gofirestoremock.NewMockDocumentRef(ctrl)
+		users.EXPECT().Doc(id).Return(doc).AnyTimes()
+		client.EXPECT().Doc("users/" + id).Return(doc).AnyTimes()
+		doc.EXPECT().Path().Return(path).AnyTimes()
+		doc.EXPECT().Get(gomock.Any()).Return(gofirestoremock.NewDocumentSnapshot(snap), nil).AnyTimes()
+		doc.EXPECT().Collections(gomock.Any()).DoAndReturn(func(any) gofirestoremock.CollectionIterator {
+			return gofirestoremock.NewCollectionIteratorFromSlice(nil, nil)
+		}).AnyTimes()
+	}
+	missing := gofirestoremock.NewMockDocumentRef(ctrl)
+	missing.EXPECT().Get(gomock.Any()).Return(nil, status.Error(codes.NotFound, "not found")).AnyTimes()
+	client.EXPECT().Doc(gomock.Any()).Return(missing).AnyTimes()
+	users.EXPECT().DocumentRefs(gomock.Any()).DoAndReturn(func(any) gofirestoremock.DocumentRefIterator {
+		return gofirestoremock.NewDocumentRefIteratorFromSlice(refs, nil)
+	}).AnyTimes()
+	return client
+}
+
+func TestAssertGolden(t *testing.T) {
+	client := newUsersClient(t, map[string]map[string]any{"u1": {"name": "Ada", "age": 36}})
+	golden := filepath.Join(t.TempDir(), "testdata", "users.json")
+
+	*update = true
+	AssertGolden(t, client, golden)
+	*update = false
+	b, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("golden file not written: %v", err)
+	}
+	if want := "{\n  \"users\": {\n    \"u1\": {\n      \"age\": 36,\n      \"name\": \"Ada\"\n    }\n  }\n}\n"; string(b) != want {
+		t.Errorf("golden file =\n%s\nwant\n%s", b, want)
+	}
+	AssertGolden(t, client, golden)
+
+	changed := newUsersClient(t, map[string]map[string]any{"u1": {"name": "Ada", "age": 37}})
+	ft := &fakeT{TB: t}
+	AssertGolden(ft, changed, golden)
+	if !strings.Contains(ft.msg, "  users/u1:\n-   age: 36\n+   age: 37\n") {
+		t.Errorf("mismatch report = %q, want the changed field", ft.msg)
+	}
+
+	ft = &fakeT{TB: t}
+	func() {
+		defer func() { _ = recover() }()
+		AssertGolden(ft, client, filepath.Join(t.TempDir(), "missing.json"))
+	}()
+	if !strings.Contains(ft.msg, "run the test with -fstest.update to create it") {
+		t.Errorf("missing golden report = %q", ft.msg)
+	}
+}
+
+func TestAssertGolden_DumpError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	client := gofirestoremock.NewMockFirestoreClient(ctrl)
+	client.EXPECT().Collections(gomock.Any()).Return(gofirestoremock.NewCollectionIteratorFromSlice(nil, status.Error(codes.Unavailable, "down")))
+	ft := &fakeT{TB: t}
+	func() {
+		defer func() { _ = recover() }()
+		AssertGolden(ft, client, "unused.json")
+	}()
+	if !strings.Contains(ft.msg, "down") {
+		t.Errorf("report = %q, want the dump error", ft.msg)
+	}
+}
+
+func TestGoldenDiff(t *testing.T) {
+	tests := []struct {
+		name      string
+		want, got string
+		diff      string
+	}{
+		{
+			name: "changed documents",
+			want: `{"users": {"u1": {"at": {"$timestamp": "<ignored>"}, "n": 1}}}`,
+			got:  `{"users": {"u1": {"at": {"$timestamp": "<ignored>"}, "n": 1.0}, "u2": {}}}`,
+			diff: "  users/u1:\n-   n: 1\n+   n: 1.0\n+ users/u2: {}",
+		},
+		{
+			name: "same documents, different format",
+			want: `{"users": {"u1": {}}}`,
+			got:  "{\"users\": {\"u1\": {}}}\n",
+			diff: "",
+		},
+		{
+			name: "unreadable golden file",
+			want: "{\"users\": \n",
+			got:  "{}\n",
+			diff: "   1 - {\"users\": \n   2 + {}",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := goldenDiff([]byte(tt.want), []byte(tt.got)); got != tt.diff {
+				t.Errorf("goldenDiff() =\n%s\nwant\n%s", got, tt.diff)
+			}
+		})
+	}
+}
+
+func TestLineDiff(t *testing.T) {
+	tests := []struct {
+		name      string
+		want, got string
+		diff      string
+	}{
+		{name: "equal", want: "a\nb\n", got: "a\nb\n", diff: ""},
+		{name: "changed", want: "a\nb\nc\n", got: "a\nx\nc\n", diff: "   2 - b\n   3 + x"},
+		{name: "added at end", want: "a\n", got: "a\nb\n", diff: "   2 + b"},
+		{name: "removed at start", want: "a\nb\n", got: "b\n", diff: "   1 - a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := lineDiff(tt.want, tt.got); got != tt.diff {
+				t.Errorf("lineDiff() =\n%s\nwant\n%s", got, tt.diff)
+			}
+		})
+	}
+}
+
+func TestLineDiff_Large(t *testing.T) {
+	var want, got strings.Builder
+	want.WriteString("header\n")
+	got.WriteString("header\n")
+	for i := range 2000 {
+		fmt.Fprintf(&want, "a%d\n", i)
+		fmt.Fprintf(&got, "b%d\n", i)
+	}
+	want.WriteString("footer\n")
+	got.WriteString("footer\n")
+
+	diff := lineDiff(want.String(), got.String())
+	if wantDiff := "   2 - a0\n   2 + b0\n     ... (2000 lines differ from 2000, too many to compare)"; diff != wantDiff {
+		t.Errorf("lineDiff() =\n%s\nwant\n%s", diff, wantDiff)
+	}
+}
+
+var errFatal = errors.New("fatal")
+
+// fakeT records the failures of an assertion under test; Fatal stops it with
+// a panic, recovered by the test.
+type fakeT struct {
+	testing.TB
+	msg string
+}
+
+func (f *fakeT) Helper() {}
+
+func (f *fakeT) Errorf(format string, args ...any) { f.msg += fmt.Sprintf(format, args...) }
+
+func (f *fakeT) Fatalf(format string, args ...any) {
+	f.msg += fmt.Sprintf(format, args...)
+	panic(errFatal)
+}
@@ -0,0 +1,154 @@
+package firestore
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"sync"
+
+	"cloud.google.com/go/firestore"
+)
+
+// documentIDAlphabet is the alphabet of document IDs generated by the SDK.
+const documentIDAlphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+
+// WithDocumentIDs decorates client so that CollectionRef.NewDoc and
+// CollectionRef.Add take their document IDs from gen instead of the SDK's
+// random source, e.g. to compare dumps or goldens across runs:
+//
+//	client = WithDocumentIDs(client, SequentialDocumentIDs())
+//	ref, _, err := client.Collection("users").Add(ctx, data) // users/00000000000000000001
+//
+// NewDoc becomes Doc(gen()) and Add becomes Doc(gen()).Create, which is what
+// the SDK does with its own IDs. The generator applies to every collection
+// the client hands out, subcollections included. Operations that do not go
+// through a CollectionRef (batches, transactions, bulk writers) take
+// references built by the caller and are left alone.
+func WithDocumentIDs(client FirestoreClient, gen func() string) FirestoreClient {
+	return &idClient{FirestoreClient: client, gen: gen}
+}
+
+// SequentialDocumentIDs returns a generator of the IDs 00000000000000000001,
+// 00000000000000000002, ... Zero-padded to the 20 characters of SDK IDs, they
+// sort in creation order. It is safe for concurrent use.
+func SequentialDocumentIDs() func() string {
+	var (
+		mu sync.Mutex
+		n  uint64
+	)
+	return func() string {
+		mu.Lock()
+		defer mu.Unlock()
+		n++
+		return fmt.Sprintf("%020d", n)
+	}
+}
+
+// SeededDocumentIDs returns a generator of random 20-character IDs of the
+// SDK alphabet that yields the same sequence for the same seed. It is safe
+// for concurrent use.
+func SeededDocumentIDs(seed uint64) func() string {
+	var mu sync.Mutex
+	r := rand.New(rand.NewPCG(seed, 0))
+	return func() string {
+		mu.Lock()
+		defer mu.Unlock()
+		return documentID(r.IntN)
+	}
+}
+
+// randomDocumentID returns a 20-character ID of the alphabet Firestore uses
+// for automatically generated document IDs.
+func randomDocumentID() string {
+	return documentID(rand.IntN)
+}
+
+func documentID(intN func(int) int) string {
+	b := make([]byte, 20)
+	for i := range b {
+		b[i] = documentIDAlphabet[intN(len(documentIDAlphabet))]
+	}
+	return string(b)
+}
+
+func validDocumentID(id string) bool {
+	return id != "" && !strings.Contains(id, "/")
+}
+
+type idClient struct {
+	FirestoreClient
+	gen func() string
+}
+
+func (c *idClient) Collection(path string) CollectionRef {
+	return wrapIDCollection(c.FirestoreClient.Collection(path), c.gen)
+}
+
+func (c *idClient) Doc(path string) DocumentRef {
+	return wrapIDDoc(c.FirestoreClient.Doc(path), c.gen)
+}
+
+func (c *idClient) DocFromFullPath(fullPath string) DocumentRef {
+	return wrapIDDoc(c.FirestoreClient.DocFromFullPath(fullPath), c.gen)
+}
+
+type idCollection struct {
+	CollectionRef
+	gen func() string
+}
+
+func wrapIDCollection(c CollectionRef, gen func() string) CollectionRef {
+	if c == nil {
+		return nil
+	}
+	return &idCollection{CollectionRef: c, gen: gen}
+}
+
+func (c *idCollection) Doc(id string) DocumentRef {
+	return wrapIDDoc(c.CollectionRef.Doc(id), c.gen)
+}
+
+// NewDoc returns nil when gen returns an invalid ID, as Doc does in the SDK.
+func (c *idCollection) NewDoc() DocumentRef {
+	id := c.gen()
+	if !validDocumentID(id) {
+		return nil
+	}
+	return c.Doc(id)
+}
+
+func (c *idCollection) Add(ctx context.Context, data any) (*firestore.DocumentRef, *firestore.WriteResult, error) {
+	id := c.gen()
+	if !validDocumentID(id) {
+		return nil, nil, fmt.Errorf("go-firestore-mock: invalid generated document ID %q", id)
+	}
+	d := c.Doc(id)
+	wr, err := d.Create(ctx, data)
+	if err != nil {
+		return nil, nil, err
+	}
+	return d.Reference(), wr, nil
+}
+
+func (c *idCollection) querySpec() QuerySpec { return Describe(c.CollectionRef) }
+
+func (c *idCollection) Parent() DocumentRef {
+	return wrapIDDoc(c.CollectionRef.Parent(), c.gen)
+}
+
+type idDoc struct {
+	DocumentRef
+	gen func() string
+}
+
+func wrapIDDoc(d DocumentRef, gen func() string) DocumentRef {
+	if d == nil {
+		return nil
+	}
+	return &idDoc{DocumentRef: d, gen: gen}
+}
+
+func (d *idDoc) Collection(id string) CollectionRef {
+	return wrapIDCollection(d.DocumentRef.Collection(id), d.gen)
+}
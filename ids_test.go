@@ -0,0 +1,104 @@
+package firestore
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/akmalsyrf/go-firestore-mock/internal/fsvalue"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestWithDocumentIDs(t *testing.T) {
+	ctx := context.Background()
+	client := WithDocumentIDs(NewFirestoreClient(newSnapshotClient(t)), SequentialDocumentIDs())
+
+	ref, _, err := client.Collection("users").Add(ctx, map[string]any{"name": "Ann"})
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if got, want := ref.ID, "00000000000000000001"; got != want {
+		t.Errorf("Add() ID = %q, want %q", got, want)
+	}
+	if snap, err := client.Doc("users/00000000000000000001").Get(ctx); err != nil || snap.Data()["name"] != "Ann" {
+		t.Errorf("Get() = %v, %v, want the added document", snap, err)
+	}
+
+	tests := []struct {
+		name string
+		got  DocumentRef
+		want string
+	}{
+		{name: "NewDoc", got: client.Collection("users").NewDoc(), want: "users/00000000000000000002"},
+		{name: "subcollection", got: client.Doc("users/u1").Collection("posts").NewDoc(), want: "users/u1/posts/00000000000000000003"},
+		{name: "nested subcollection", got: client.Collection("users").Doc("u1").Collection("posts").Doc("p1").Collection("likes").NewDoc(), want: "users/u1/posts/p1/likes/00000000000000000004"},
+		{name: "parent", got: client.Collection("users").Doc("u1").Collection("posts").Parent().Collection("tags").NewDoc(), want: "users/u1/tags/00000000000000000005"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fsvalue.RelativePath(tt.got.Path()); got != tt.want {
+				t.Errorf("Path() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithDocumentIDs_Add(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	inner := NewMockFirestoreClient(ctrl)
+	users := NewMockCollectionRef(ctrl)
+	doc := NewMockDocumentRef(ctrl)
+	inner.EXPECT().Collection("users").Return(users).AnyTimes()
+
+	// Add creates, so a taken ID fails instead of overwriting
+	users.EXPECT().Doc("u1").Return(doc)
+	doc.EXPECT().Create(ctx, map[string]any{"name": "Bob"}).Return(nil, status.Error(codes.AlreadyExists, "exists"))
+	client := WithDocumentIDs(inner, func() string { return "u1" })
+	if ref, _, err := client.Collection("users").Add(ctx, map[string]any{"name": "Bob"}); status.Code(err) != codes.AlreadyExists || ref != nil {
+		t.Errorf("Add() = %v, %v, want AlreadyExists", ref, err)
+	}
+
+	client = WithDocumentIDs(inner, func() string { return "a/b" })
+	if _, _, err := client.Collection("users").Add(ctx, map[string]any{}); err == nil || !strings.Contains(err.Error(), `invalid generated document ID "a/b"`) {
+		t.Errorf("Add() error = %v, want invalid ID", err)
+	}
+	if d := client.Collection("users").NewDoc(); d != nil {
+		t.Errorf("NewDoc() = %v, want nil for an invalid ID", d)
+	}
+}
+
+func TestWithDocumentIDs_Describe(t *testing.T) {
+	users := WithDocumentIDs(NewFirestoreClient(newOfflineClient(t)), SequentialDocumentIDs()).Collection("users")
+	if got := Describe(users); got.Collection != "users" {
+		t.Errorf("Describe() = %v, want users", got)
+	}
+	if _, err := NewPaginator(users, 2, []byte("secret")); err != nil {
+		t.Errorf("NewPaginator() error = %v", err)
+	}
+}
+
+func TestSeededDocumentIDs(t *testing.T) {
+	ids := func(gen func() string) []string {
+		var got []string
+		for range 5 {
+			got = append(got, gen())
+		}
+		return got
+	}
+	a, b := ids(SeededDocumentIDs(1)), ids(SeededDocumentIDs(1))
+	if !reflect.DeepEqual(a, b) {
+		t.Errorf("seed 1 IDs differ between runs:\n%v\n%v", a, b)
+	}
+	if c := ids(SeededDocumentIDs(2)); reflect.DeepEqual(a, c) {
+		t.Errorf("seeds 1 and 2 generate the same IDs: %v", a)
+	}
+	for _, id := range a {
+		if len(id) != 20 || strings.Trim(id, documentIDAlphabet) != "" {
+			t.Errorf("ID %q is not 20 characters of the SDK alphabet", id)
+		}
+	}
+}
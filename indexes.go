@@ -0,0 +1,397 @@
+package firestore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	"cloud.google.com/go/firestore"
+	"github.com/akmalsyrf/go-firestore-mock/internal/fsvalue"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Query scopes, orders and array configs of index definitions.
+const (
+	ScopeCollection      = "COLLECTION"
+	ScopeCollectionGroup = "COLLECTION_GROUP"
+	OrderAscending       = "ASCENDING"
+	OrderDescending      = "DESCENDING"
+	ArrayContains        = "CONTAINS"
+)
+
+// Indexes is the index configuration of a database, in the format of the
+// firestore.indexes.json file deployed by the Firebase CLI.
+type Indexes struct {
+	Indexes        []IndexDefinition `json:"indexes"`
+	FieldOverrides []FieldOverride   `json:"fieldOverrides,omitempty"`
+}
+
+// IndexDefinition is a composite index.
+type IndexDefinition struct {
+	CollectionGroup string `json:"collectionGroup"`
+	// QueryScope is ScopeCollection (the default) or ScopeCollectionGroup.
+	QueryScope string       `json:"queryScope,omitempty"`
+	Fields     []IndexField `json:"fields"`
+}
+
+// IndexField is a field of a composite index, with either an Order or an
+// ArrayConfig.
+type IndexField struct {
+	FieldPath   string `json:"fieldPath"`
+	Order       string `json:"order,omitempty"`
+	ArrayConfig string `json:"arrayConfig,omitempty"`
+}
+
+// FieldOverride replaces the single-field indexes Firestore creates for a
+// field by Indexes; an empty list exempts the field from indexing.
+type FieldOverride struct {
+	CollectionGroup string               `json:"collectionGroup"`
+	FieldPath       string               `json:"fieldPath"`
+	Indexes         []FieldOverrideIndex `json:"indexes"`
+}
+
+// FieldOverrideIndex is a single-field index of a FieldOverride.
+type FieldOverrideIndex struct {
+	Order       string `json:"order,omitempty"`
+	ArrayConfig string `json:"arrayConfig,omitempty"`
+	QueryScope  string `json:"queryScope,omitempty"`
+}
+
+// LoadIndexes reads the index configuration in the file name of fsys.
+func LoadIndexes(fsys fs.FS, name string) (*Indexes, error) {
+	b, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, fmt.Errorf("go-firestore-mock: indexes: %w", err)
+	}
+	return parseIndexes("indexes "+name, b)
+}
+
+// ReadIndexes parses an index configuration in the firestore.indexes.json
+// format. Unknown keys (such as "density") are ignored.
+func ReadIndexes(b []byte) (*Indexes, error) {
+	return parseIndexes("indexes", b)
+}
+
+// parseIndexes parses b, prefixing errors with label.
+func parseIndexes(label string, b []byte) (*Indexes, error) {
+	ix := &Indexes{}
+	if err := json.Unmarshal(b, ix); err != nil {
+		return nil, fmt.Errorf("go-firestore-mock: %s: %w", label, err)
+	}
+	for i, d := range ix.Indexes {
+		if err := checkScope(d.QueryScope); err != nil {
+			return nil, fmt.Errorf("go-firestore-mock: %s: index %d: %w", label, i, err)
+		}
+		for _, f := range d.Fields {
+			if err := checkIndexField(f.FieldPath, f.Order, f.ArrayConfig); err != nil {
+				return nil, fmt.Errorf("go-firestore-mock: %s: index %d: %w", label, i, err)
+			}
+		}
+	}
+	for i, o := range ix.FieldOverrides {
+		for _, f := range o.Indexes {
+			if err := checkScope(f.QueryScope); err != nil {
+				return nil, fmt.Errorf("go-firestore-mock: %s: field override %d: %w", label, i, err)
+			}
+			if err := checkIndexField(o.FieldPath, f.Order, f.ArrayConfig); err != nil {
+				return nil, fmt.Errorf("go-firestore-mock: %s: field override %d: %w", label, i, err)
+			}
+		}
+	}
+	return ix, nil
+}
+
+func checkScope(scope string) error {
+	switch scope {
+	case "", ScopeCollection, ScopeCollectionGroup:
+		return nil
+	}
+	return fmt.Errorf("unknown query scope %q", scope)
+}
+
+func checkIndexField(fieldPath, order, arrayConfig string) error {
+	switch {
+	case fieldPath == "":
+		return fmt.Errorf("field without fieldPath")
+	case order != "" && arrayConfig != "":
+		return fmt.Errorf("field %s has both an order and an array config", fieldPath)
+	case order == OrderAscending, order == OrderDescending, order == "" && arrayConfig == ArrayContains:
+		return nil
+	case arrayConfig != "":
+		return fmt.Errorf("field %s: unknown array config %q", fieldPath, arrayConfig)
+	}
+	return fmt.Errorf("field %s: unknown order %q", fieldPath, order)
+}
+
+// Check reports whether ix holds the indexes Firestore needs to run the
+// query described by spec, and returns a codes.FailedPrecondition error
+// naming the index definition to add if it does not.
+//
+// Following Firestore, single-field indexes exist for every field in
+// collection scope unless a field override replaces them, and only through
+// field overrides in collection group scope. Queries with equality and
+// array-contains filters alone merge single-field indexes; queries that also
+// order (explicitly or through an inequality filter) on more than one field
+// need a composite index: equality fields first, then the array-contains
+// field and the order fields, in order. Orders and filters on the document
+// ID are served by every index. Queries with OrFilter are not checked.
+// A nil *Indexes accepts every query.
+func (ix *Indexes) Check(spec QuerySpec) error {
+	if ix == nil {
+		return nil
+	}
+	req, ok := requiredIndex(spec)
+	if !ok {
+		return nil
+	}
+	single := len(req.orders) == 0
+	if len(req.orders) == 1 && len(req.eq) == 0 && req.contains == "" {
+		single = true
+	}
+	if single {
+		for _, f := range req.singleFields() {
+			if !ix.hasSingleField(req.group, req.scope, f) {
+				return ix.missingSingleField(spec, req, f)
+			}
+		}
+		return nil
+	}
+	for _, d := range ix.Indexes {
+		if req.servedBy(d) {
+			return nil
+		}
+	}
+	def, _ := json.MarshalIndent(req.definition(), "", "  ")
+	return status.Errorf(codes.FailedPrecondition,
+		"go-firestore-mock: query %s requires a composite index; add it to the \"indexes\" of firestore.indexes.json:\n%s", spec, def)
+}
+
+// indexRequest is the index a query reads: its collection group and scope,
+// equality fields (sorted), array-contains field and order fields.
+type indexRequest struct {
+	group, scope string
+	eq           []string
+	contains     string
+	orders       []IndexField
+}
+
+// requiredIndex returns the index spec reads, or false if the query is not
+// checked.
+func requiredIndex(spec QuerySpec) (indexRequest, bool) {
+	req := indexRequest{group: path.Base(spec.Collection), scope: ScopeCollection}
+	if spec.CollectionGroup {
+		req.scope = ScopeCollectionGroup
+	}
+	var filters []FilterSpec
+	if !flattenFilters(spec.Filters, &filters) {
+		return req, false
+	}
+	eq := map[string]bool{}
+	var ineq []string
+	for _, f := range filters {
+		p := canonicalFieldPath(f.Path)
+		if p == firestore.DocumentID {
+			continue
+		}
+		switch f.Op {
+		case "==", "in":
+			eq[p] = true
+		case "array-contains", "array-contains-any":
+			req.contains = p
+		default:
+			ineq = append(ineq, p)
+		}
+	}
+	ordered := map[string]bool{}
+	for _, o := range spec.Orders {
+		p := canonicalFieldPath(o.Path)
+		if p == firestore.DocumentID || ordered[p] || eq[p] {
+			continue
+		}
+		ordered[p] = true
+		dir := OrderAscending
+		if o.Dir == firestore.Desc {
+			dir = OrderDescending
+		}
+		req.orders = append(req.orders, IndexField{FieldPath: p, Order: dir})
+	}
+	// Inequality fields are ordered implicitly, by path, after the explicit
+	// orders.
+	sort.Strings(ineq)
+	for _, p := range ineq {
+		if !ordered[p] && !eq[p] {
+			ordered[p] = true
+			req.orders = append(req.orders, IndexField{FieldPath: p, Order: OrderAscending})
+		}
+	}
+	req.eq = sortedKeys(eq)
+	return req, true
+}
+
+// flattenFilters appends the property filters of filters, including those of
+// AndFilters, to out; it returns false for queries with an OrFilter.
+func flattenFilters(filters []FilterSpec, out *[]FilterSpec) bool {
+	for _, f := range filters {
+		if f.Entity == nil {
+			*out = append(*out, f)
+			continue
+		}
+		if !flattenEntity(f.Entity, out) {
+			return false
+		}
+	}
+	return true
+}
+
+func flattenEntity(ef firestore.EntityFilter, out *[]FilterSpec) bool {
+	switch f := ef.(type) {
+	case firestore.PropertyFilter:
+		*out = append(*out, FilterSpec{Path: f.Path, Op: f.Operator, Value: f.Value})
+	case firestore.PropertyPathFilter:
+		*out = append(*out, FilterSpec{Path: fsvalue.FieldPathString(f.Path), Op: f.Operator, Value: f.Value})
+	case firestore.AndFilter:
+		for _, sub := range f.Filters {
+			if !flattenEntity(sub, out) {
+				return false
+			}
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+// canonicalFieldPath renders a dotted field path in the form of
+// fsvalue.FieldPathString, so that "a.b" and "a.`b`" compare equal.
+func canonicalFieldPath(p string) string {
+	if p == firestore.DocumentID {
+		return p
+	}
+	return fsvalue.FieldPathString(fsvalue.ParseFieldPath(p))
+}
+
+// singleFields returns the single-field indexes a query without composite
+// index reads.
+func (r indexRequest) singleFields() []IndexField {
+	var fields []IndexField
+	for _, p := range r.eq {
+		fields = append(fields, IndexField{FieldPath: p})
+	}
+	if r.contains != "" {
+		fields = append(fields, IndexField{FieldPath: r.contains, ArrayConfig: ArrayContains})
+	}
+	return append(fields, r.orders...)
+}
+
+// hasSingleField reports whether the single-field index f exists in scope;
+// an equality field (without order) may use either order.
+func (ix *Indexes) hasSingleField(group, scope string, f IndexField) bool {
+	for _, o := range ix.FieldOverrides {
+		if o.CollectionGroup != group || canonicalFieldPath(o.FieldPath) != f.FieldPath {
+			continue
+		}
+		for _, i := range o.Indexes {
+			if orScope(i.QueryScope) != scope || i.ArrayConfig != f.ArrayConfig {
+				continue
+			}
+			if f.Order == "" || i.Order == f.Order {
+				return true
+			}
+		}
+		return false
+	}
+	return scope == ScopeCollection
+}
+
+func (ix *Indexes) missingSingleField(spec QuerySpec, r indexRequest, f IndexField) error {
+	idx := FieldOverrideIndex{Order: f.Order, ArrayConfig: f.ArrayConfig, QueryScope: r.scope}
+	if idx.Order == "" && idx.ArrayConfig == "" {
+		idx.Order = OrderAscending
+	}
+	def, _ := json.MarshalIndent(idx, "", "  ")
+	return status.Errorf(codes.FailedPrecondition,
+		"go-firestore-mock: query %s requires a single-field index on %s in %s scope; add it to the \"indexes\" of the field override of %s.%s in firestore.indexes.json:\n%s",
+		spec, f.FieldPath, strings.ToLower(strings.ReplaceAll(r.scope, "_", " ")), r.group, f.FieldPath, def)
+}
+
+// servedBy reports whether the composite index d serves r: same collection
+// group and scope, the equality and array-contains fields first (in any
+// order; equality fields in either order), then exactly the order fields.
+// A trailing __name__ field is ignored.
+func (r indexRequest) servedBy(d IndexDefinition) bool {
+	if d.CollectionGroup != r.group || orScope(d.QueryScope) != r.scope {
+		return false
+	}
+	fields := d.Fields
+	if n := len(fields); n > 0 && fields[n-1].FieldPath == firestore.DocumentID {
+		fields = fields[:n-1]
+	}
+	prefix := len(r.eq)
+	if r.contains != "" {
+		prefix++
+	}
+	if len(fields) != prefix+len(r.orders) {
+		return false
+	}
+	want := map[string]string{}
+	for _, p := range r.eq {
+		want[p] = ""
+	}
+	if r.contains != "" {
+		want[r.contains] = ArrayContains
+	}
+	for _, f := range fields[:prefix] {
+		arrayConfig, ok := want[canonicalFieldPath(f.FieldPath)]
+		if !ok || f.ArrayConfig != arrayConfig {
+			return false
+		}
+		delete(want, canonicalFieldPath(f.FieldPath))
+	}
+	for i, f := range fields[prefix:] {
+		if canonicalFieldPath(f.FieldPath) != r.orders[i].FieldPath || f.Order != r.orders[i].Order {
+			return false
+		}
+	}
+	return true
+}
+
+// definition returns the composite index serving r.
+func (r indexRequest) definition() IndexDefinition {
+	d := IndexDefinition{CollectionGroup: r.group, QueryScope: r.scope}
+	for _, p := range r.eq {
+		d.Fields = append(d.Fields, IndexField{FieldPath: p, Order: OrderAscending})
+	}
+	if r.contains != "" {
+		d.Fields = append(d.Fields, IndexField{FieldPath: r.contains, ArrayConfig: ArrayContains})
+	}
+	d.Fields = append(d.Fields, r.orders...)
+	return d
+}
+
+func orScope(scope string) string {
+	if scope == "" {
+		return ScopeCollection
+	}
+	return scope
+}
+
+// WithIndexes decorates client so that queries needing an index missing
+// from ix (see Indexes.Check) fail with codes.FailedPrecondition, as they do
+// on Firestore but not on the emulator. The check runs on every query
+// iterator Next, listener and AggregationQuery.Get of the returned client,
+// including queries run in transactions.
+func WithIndexes(client FirestoreClient, ix *Indexes) FirestoreClient {
+	return intercept(client, func(_ context.Context, op Op, call func() (any, error)) (any, error) {
+		if op.Query != nil {
+			if err := ix.Check(*op.Query); err != nil {
+				return nil, err
+			}
+		}
+		return call()
+	})
+}
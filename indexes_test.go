@@ -0,0 +1,228 @@
+package firestore
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"cloud.google.com/go/firestore"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const testIndexes = `{
+  "indexes": [
+    {
+      "collectionGroup": "users",
+      "queryScope": "COLLECTION",
+      "fields": [
+        {"fieldPath": "status", "order": "ASCENDING"},
+        {"fieldPath": "age", "order": "DESCENDING"}
+      ]
+    },
+    {
+      "collectionGroup": "posts",
+      "queryScope": "COLLECTION_GROUP",
+      "fields": [
+        {"fieldPath": "tags", "arrayConfig": "CONTAINS"},
+        {"fieldPath": "published", "order": "ASCENDING"},
+        {"fieldPath": "__name__", "order": "ASCENDING"}
+      ]
+    }
+  ],
+  "fieldOverrides": [
+    {
+      "collectionGroup": "users",
+      "fieldPath": "bio",
+      "indexes": []
+    },
+    {
+      "collectionGroup": "posts",
+      "fieldPath": "author",
+      "indexes": [
+        {"order": "ASCENDING", "queryScope": "COLLECTION"},
+        {"order": "ASCENDING", "queryScope": "COLLECTION_GROUP"}
+      ]
+    }
+  ]
+}`
+
+func TestIndexes_Check(t *testing.T) {
+	ix, err := ReadIndexes([]byte(testIndexes))
+	if err != nil {
+		t.Fatal(err)
+	}
+	eq := func(path string, v any) FilterSpec { return FilterSpec{Path: path, Op: "==", Value: v} }
+
+	tests := []struct {
+		name string
+		spec QuerySpec
+		want string // substring of the error; empty for no error
+	}{
+		{name: "collection", spec: QuerySpec{Collection: "users"}},
+		{name: "single equality", spec: QuerySpec{Collection: "users", Filters: []FilterSpec{eq("status", "active")}}},
+		{name: "equalities merge", spec: QuerySpec{Collection: "users", Filters: []FilterSpec{eq("status", "active"), eq("role", "admin")}}},
+		{name: "single order", spec: QuerySpec{Collection: "users", Orders: []OrderSpec{{Path: "age", Dir: firestore.Desc}}}},
+		{name: "inequality and order on the same field", spec: QuerySpec{
+			Collection: "users",
+			Filters:    []FilterSpec{{Path: "age", Op: ">", Value: 18}},
+			Orders:     []OrderSpec{{Path: "age", Dir: firestore.Asc}},
+		}},
+		{name: "composite", spec: QuerySpec{
+			Collection: "users",
+			Filters:    []FilterSpec{eq("status", "active")},
+			Orders:     []OrderSpec{{Path: "age", Dir: firestore.Desc}},
+		}},
+		{name: "composite for subcollection", spec: QuerySpec{
+			Collection: "orgs/o1/users",
+			Filters:    []FilterSpec{eq("status", "active"), {Path: "age", Op: "<", Value: 30}},
+			Orders:     []OrderSpec{{Path: "age", Dir: firestore.Desc}},
+		}},
+		{name: "order on equality field is ignored", spec: QuerySpec{
+			Collection: "users",
+			Filters:    []FilterSpec{eq("status", "active")},
+			Orders:     []OrderSpec{{Path: "status", Dir: firestore.Asc}, {Path: "age", Dir: firestore.Desc}, {Path: firestore.DocumentID, Dir: firestore.Desc}},
+		}},
+		{name: "composite wrong direction", spec: QuerySpec{
+			Collection: "users",
+			Filters:    []FilterSpec{eq("status", "active")},
+			Orders:     []OrderSpec{{Path: "age", Dir: firestore.Asc}},
+		}, want: `requires a composite index; add it to the "indexes" of firestore.indexes.json:
+{
+  "collectionGroup": "users",
+  "queryScope": "COLLECTION",
+  "fields": [
+    {
+      "fieldPath": "status",
+      "order": "ASCENDING"
+    },
+    {
+      "fieldPath": "age",
+      "order": "ASCENDING"
+    }
+  ]
+}`},
+		{name: "implicit inequality order", spec: QuerySpec{
+			Collection: "users",
+			Filters:    []FilterSpec{eq("role", "admin"), {Path: "age", Op: ">=", Value: 18}},
+		}, want: `"fieldPath": "role",
+      "order": "ASCENDING"
+    },
+    {
+      "fieldPath": "age",
+      "order": "ASCENDING"`},
+		{name: "and filter", spec: QuerySpec{
+			Collection: "users",
+			Filters: []FilterSpec{{Entity: firestore.AndFilter{Filters: []firestore.EntityFilter{
+				firestore.PropertyFilter{Path: "status", Operator: "==", Value: "active"},
+				firestore.PropertyFilter{Path: "age", Operator: "<", Value: 30},
+			}}}},
+			Orders: []OrderSpec{{Path: "age", Dir: firestore.Desc}},
+		}},
+		{name: "or filter is not checked", spec: QuerySpec{
+			Collection: "users",
+			Filters: []FilterSpec{{Entity: firestore.OrFilter{Filters: []firestore.EntityFilter{
+				firestore.PropertyFilter{Path: "a", Operator: "==", Value: 1},
+				firestore.PropertyFilter{Path: "b", Operator: "==", Value: 2},
+			}}}},
+			Orders: []OrderSpec{{Path: "c", Dir: firestore.Desc}},
+		}},
+		{name: "exempted field", spec: QuerySpec{Collection: "users", Filters: []FilterSpec{eq("bio", "x")}},
+			want: `requires a single-field index on bio in collection scope; add it to the "indexes" of the field override of users.bio`},
+		{name: "collection group needs override", spec: QuerySpec{Collection: "posts", CollectionGroup: true, Filters: []FilterSpec{eq("title", "x")}},
+			want: `single-field index on title in collection group scope`},
+		{name: "collection group override", spec: QuerySpec{Collection: "posts", CollectionGroup: true, Filters: []FilterSpec{eq("author", "ada")}}},
+		{name: "collection group override order", spec: QuerySpec{Collection: "posts", CollectionGroup: true, Orders: []OrderSpec{{Path: "author", Dir: firestore.Desc}}},
+			want: `"order": "DESCENDING",
+  "queryScope": "COLLECTION_GROUP"`},
+		{name: "collection group composite", spec: QuerySpec{
+			Collection:      "posts",
+			CollectionGroup: true,
+			Filters:         []FilterSpec{{Path: "tags", Op: "array-contains", Value: "go"}},
+			Orders:          []OrderSpec{{Path: "published", Dir: firestore.Asc}},
+		}},
+		{name: "composite in other scope", spec: QuerySpec{
+			Collection: "posts",
+			Filters:    []FilterSpec{{Path: "tags", Op: "array-contains-any", Value: []string{"go"}}},
+			Orders:     []OrderSpec{{Path: "published", Dir: firestore.Asc}},
+		}, want: `"arrayConfig": "CONTAINS"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ix.Check(tt.spec)
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("Check() = %v, want nil", err)
+				}
+				return
+			}
+			if status.Code(err) != codes.FailedPrecondition || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Check() = %v, want FailedPrecondition containing\n%s", err, tt.want)
+			}
+		})
+	}
+
+	var none *Indexes
+	if err := none.Check(QuerySpec{Collection: "users", Filters: []FilterSpec{eq("a", 1)}, Orders: []OrderSpec{{Path: "b"}}}); err != nil {
+		t.Errorf("nil Indexes: Check() = %v, want nil", err)
+	}
+}
+
+func TestLoadIndexes(t *testing.T) {
+	fsys := fstest.MapFS{
+		"firestore.indexes.json": {Data: []byte(testIndexes)},
+		"bad_order.json":         {Data: []byte(`{"indexes": [{"collectionGroup": "users", "fields": [{"fieldPath": "a", "order": "UP"}]}]}`)},
+		"bad_scope.json":         {Data: []byte(`{"fieldOverrides": [{"collectionGroup": "users", "fieldPath": "a", "indexes": [{"order": "ASCENDING", "queryScope": "DATABASE"}]}]}`)},
+		"both.json":              {Data: []byte(`{"indexes": [{"collectionGroup": "users", "fields": [{"fieldPath": "a", "order": "ASCENDING", "arrayConfig": "CONTAINS"}]}]}`)},
+		"syntax.json":            {Data: []byte(`{"indexes": [`)},
+	}
+	ix, err := LoadIndexes(fsys, "firestore.indexes.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ix.Indexes) != 2 || len(ix.FieldOverrides) != 2 || len(ix.Indexes[1].Fields) != 3 {
+		t.Errorf("LoadIndexes() = %+v", ix)
+	}
+
+	for name, want := range map[string]string{
+		"bad_order.json": `go-firestore-mock: indexes bad_order.json: index 0: field a: unknown order "UP"`,
+		"bad_scope.json": `go-firestore-mock: indexes bad_scope.json: field override 0: unknown query scope "DATABASE"`,
+		"both.json":      `go-firestore-mock: indexes both.json: index 0: field a has both an order and an array config`,
+		"syntax.json":    `go-firestore-mock: indexes syntax.json: unexpected end of JSON input`,
+		"missing.json":   `go-firestore-mock: indexes: open missing.json: file does not exist`,
+	} {
+		if _, err := LoadIndexes(fsys, name); err == nil || err.Error() != want {
+			t.Errorf("LoadIndexes(%s) error = %v, want %s", name, err, want)
+		}
+	}
+}
+
+func TestWithIndexes(t *testing.T) {
+	ctx := context.Background()
+	ix, err := ReadIndexes([]byte(testIndexes))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctrl := gomock.NewController(t)
+	inner := NewMockFirestoreClient(ctrl)
+	r := NewQueryResponder()
+	r.OnQuery(QuerySpec{Collection: "users", Filters: []FilterSpec{{Path: "status", Op: "==", Value: "active"}}, Orders: []OrderSpec{{Path: "age", Dir: firestore.Desc}}}).
+		Return(&firestore.DocumentSnapshot{})
+	inner.EXPECT().Collection("users").Return(r.Collection("users")).AnyTimes()
+	client := WithIndexes(inner, ix)
+
+	indexed := client.Collection("users").Where("status", "==", "active").OrderBy("age", firestore.Desc)
+	if docs, err := indexed.Documents(ctx).GetAll(); err != nil || len(docs) != 1 {
+		t.Errorf("indexed query: GetAll() = %d docs, %v; want 1, nil", len(docs), err)
+	}
+
+	unindexed := client.Collection("users").Where("status", "==", "active").OrderBy("name", firestore.Asc)
+	if _, err := unindexed.Documents(ctx).Next(); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("unindexed query: Next() error = %v, want FailedPrecondition", err)
+	}
+	if _, err := unindexed.NewAggregationQuery().WithCount("n").Get(ctx); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("unindexed count: Get() error = %v, want FailedPrecondition", err)
+	}
+}
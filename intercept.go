@@ -0,0 +1,679 @@
+package firestore
+
+import (
+	"context"
+
+	"cloud.google.com/go/firestore"
+	"github.com/akmalsyrf/go-firestore-mock/internal/fsvalue"
+	"google.golang.org/api/iterator"
+)
+
+// Op describes one operation made through a decorated client (see WithFaults,
+// WithLatency, WithIndexes and Record).
+//
+// Method is the interface method, e.g. "DocumentRef.Set", "CollectionRef.Add",
+// "WriteBatch.Commit", "BulkWriter.Delete", "AggregationQuery.Get" or, for
+// iterators, "DocumentIterator.Next". A few methods have no direct interface
+// counterpart: "BulkWriterJob.Results" reports the outcome of a BulkWriter
+// write, "Transaction.Commit" runs after the RunTransaction function returns
+// successfully (once per attempt, inside "FirestoreClient.RunTransaction"),
+// and the GetAll of a DocumentIterator is one "DocumentIterator.GetAll" (the
+// GetAll of other iterators runs as a series of Next).
+type Op struct {
+	Method string
+	// Path is the document or collection path the operation works on,
+	// relative to the database root ("users/u1"), the collection ID for
+	// collection group queries, and empty for operations on several documents.
+	Path string
+	// Call numbers the operations of a sequence, starting at 1: the Nth Next
+	// of an iterator or the Nth commit attempt of a RunTransaction. It is 1
+	// for all other operations.
+	Call int
+	// Query is the spec of the query read by query iterators and
+	// AggregationQuery.Get, nil otherwise.
+	Query *QuerySpec
+	// Args are the arguments of the call, without the context. The writes of
+	// a WriteBatch are the Args of its Commit, as a []BatchWrite.
+	Args []any
+}
+
+// BatchWrite is a write buffered in a decorated WriteBatch.
+type BatchWrite struct {
+	// Op is "Create", "Set", "Update" or "Delete".
+	Op string
+	// Path is the document path relative to the database root.
+	Path string
+	// Args are the remaining arguments of the write.
+	Args []any
+}
+
+// interceptor runs each operation made through an intercepted client. call
+// performs the operation on the decorated value; an interceptor may call it
+// (at most once) and return its results, or return results of its own.
+type interceptor func(ctx context.Context, op Op, call func() (any, error)) (any, error)
+
+// intercept decorates client so that every collection, document, query,
+// iterator, batch, bulk writer and transaction it hands out runs its
+// operations through fn.
+func intercept(client FirestoreClient, fn interceptor) FirestoreClient {
+	return &interceptedClient{c: client, fn: fn}
+}
+
+// run performs op through fn and converts the result back to T; results of
+// another type (e.g. nil, with an error) become the zero T.
+func run[T any](ctx context.Context, fn interceptor, op Op, call func() (T, error)) (T, error) {
+	if op.Call == 0 {
+		op.Call = 1
+	}
+	res, err := fn(ctx, op, func() (any, error) { return call() })
+	v, _ := res.(T)
+	return v, err
+}
+
+type interceptedClient struct {
+	c  FirestoreClient
+	fn interceptor
+}
+
+func (w *interceptedClient) Collection(path string) CollectionRef {
+	return interceptCollection(w.c.Collection(path), w.fn)
+}
+
+func (w *interceptedClient) CollectionGroup(collectionID string) Query {
+	return &interceptedQuery{q: w.c.CollectionGroup(collectionID), fn: w.fn, path: func() string { return collectionID }}
+}
+
+func (w *interceptedClient) Doc(path string) DocumentRef {
+	return interceptDoc(w.c.Doc(path), w.fn)
+}
+
+func (w *interceptedClient) DocFromFullPath(fullPath string) DocumentRef {
+	return interceptDoc(w.c.DocFromFullPath(fullPath), w.fn)
+}
+
+func (w *interceptedClient) Close() error {
+	_, err := run(context.Background(), w.fn, Op{Method: "FirestoreClient.Close"}, func() (any, error) {
+		return nil, w.c.Close()
+	})
+	return err
+}
+
+func (w *interceptedClient) BulkWriter(ctx context.Context) BulkWriter {
+	return &interceptedBulkWriter{bw: w.c.BulkWriter(ctx), fn: w.fn, ctx: ctx}
+}
+
+func (w *interceptedClient) Batch() WriteBatch {
+	return &interceptedWriteBatch{wb: w.c.Batch(), fn: w.fn}
+}
+
+func (w *interceptedClient) RunTransaction(ctx context.Context, f func(context.Context, Transaction) error, opts ...firestore.TransactionOption) error {
+	_, err := run(ctx, w.fn, Op{Method: "FirestoreClient.RunTransaction", Args: []any{opts}}, func() (any, error) {
+		attempt := 0
+		return nil, w.c.RunTransaction(ctx, func(ctx context.Context, tx Transaction) error {
+			attempt++
+			if err := f(ctx, &interceptedTransaction{tx: tx, fn: w.fn, ctx: ctx}); err != nil {
+				return err
+			}
+			_, err := run(ctx, w.fn, Op{Method: "Transaction.Commit", Call: attempt}, func() (any, error) { return nil, nil })
+			return err
+		}, opts...)
+	})
+	return err
+}
+
+func (w *interceptedClient) Collections(ctx context.Context) CollectionIterator {
+	return &interceptedCollectionIterator{it: w.c.Collections(ctx), seq: newOpSeq(ctx, w.fn, "CollectionIterator.Next", noPath, nil)}
+}
+
+func (w *interceptedClient) GetAll(ctx context.Context, docRefs []*firestore.DocumentRef) ([]DocumentSnapshot, error) {
+	return run(ctx, w.fn, Op{Method: "FirestoreClient.GetAll", Args: []any{docRefs}}, func() ([]DocumentSnapshot, error) {
+		return w.c.GetAll(ctx, docRefs)
+	})
+}
+
+// interceptedQuery decorates a Query; path returns the collection path
+// reported in the Ops of its iterators. Paths are computed lazily so that mocks
+// only need to expect Path for operations that are actually made.
+type interceptedQuery struct {
+	q    Query
+	fn   interceptor
+	path func() string
+}
+
+func (w *interceptedQuery) wrap(q Query) Query {
+	return &interceptedQuery{q: q, fn: w.fn, path: w.path}
+}
+
+// unwrapQuery returns the decorated Query, e.g. to pass it to an undecorated
+// Transaction.
+func (w *interceptedQuery) unwrapQuery() Query { return w.q }
+
+func (w *interceptedQuery) querySpec() QuerySpec { return Describe(w.q) }
+
+func (w *interceptedQuery) Where(path string, op string, value any) Query {
+	return w.wrap(w.q.Where(path, op, value))
+}
+
+func (w *interceptedQuery) WherePath(fp firestore.FieldPath, op string, value any) Query {
+	return w.wrap(w.q.WherePath(fp, op, value))
+}
+
+func (w *interceptedQuery) WhereEntity(ef firestore.EntityFilter) Query {
+	return w.wrap(w.q.WhereEntity(ef))
+}
+
+func (w *interceptedQuery) OrderBy(path string, dir firestore.Direction) Query {
+	return w.wrap(w.q.OrderBy(path, dir))
+}
+
+func (w *interceptedQuery) OrderByPath(fp firestore.FieldPath, dir firestore.Direction) Query {
+	return w.wrap(w.q.OrderByPath(fp, dir))
+}
+
+func (w *interceptedQuery) Limit(n int) Query { return w.wrap(w.q.Limit(n)) }
+
+func (w *interceptedQuery) LimitToLast(n int) Query { return w.wrap(w.q.LimitToLast(n)) }
+
+func (w *interceptedQuery) Offset(n int) Query { return w.wrap(w.q.Offset(n)) }
+
+func (w *interceptedQuery) StartAt(docSnapshotOrFieldValues ...any) Query {
+	return w.wrap(w.q.StartAt(docSnapshotOrFieldValues...))
+}
+
+func (w *interceptedQuery) StartAfter(docSnapshotOrFieldValues ...any) Query {
+	return w.wrap(w.q.StartAfter(docSnapshotOrFieldValues...))
+}
+
+func (w *interceptedQuery) EndAt(docSnapshotOrFieldValues ...any) Query {
+	return w.wrap(w.q.EndAt(docSnapshotOrFieldValues...))
+}
+
+func (w *interceptedQuery) EndBefore(docSnapshotOrFieldValues ...any) Query {
+	return w.wrap(w.q.EndBefore(docSnapshotOrFieldValues...))
+}
+
+func (w *interceptedQuery) Select(paths ...string) Query { return w.wrap(w.q.Select(paths...)) }
+
+func (w *interceptedQuery) SelectPaths(fieldPaths ...firestore.FieldPath) Query {
+	return w.wrap(w.q.SelectPaths(fieldPaths...))
+}
+
+func (w *interceptedQuery) Documents(ctx context.Context) DocumentIterator {
+	return &interceptedDocumentIterator{it: w.q.Documents(ctx), seq: newOpSeq(ctx, w.fn, "DocumentIterator.Next", w.path, w.q)}
+}
+
+func (w *interceptedQuery) Snapshots(ctx context.Context) QuerySnapshotIterator {
+	return &interceptedQuerySnapshotIterator{it: w.q.Snapshots(ctx), seq: newOpSeq(ctx, w.fn, "QuerySnapshotIterator.Next", w.path, w.q)}
+}
+
+func (w *interceptedQuery) NewAggregationQuery() AggregationQuery {
+	return &interceptedAggregationQuery{aq: w.q.NewAggregationQuery(), fn: w.fn, path: w.path, q: w.q}
+}
+
+type interceptedCollection struct {
+	interceptedQuery
+	c CollectionRef
+}
+
+func interceptCollection(c CollectionRef, fn interceptor) CollectionRef {
+	if c == nil {
+		return nil
+	}
+	return &interceptedCollection{interceptedQuery: interceptedQuery{q: c, fn: fn, path: func() string { return fsvalue.RelativePath(c.Path()) }}, c: c}
+}
+
+func (w *interceptedCollection) Doc(id string) DocumentRef {
+	return interceptDoc(w.c.Doc(id), w.fn)
+}
+
+// addResult carries the results of CollectionRef.Add through an interceptor.
+type addResult struct {
+	Ref         *firestore.DocumentRef
+	WriteResult *firestore.WriteResult
+}
+
+func (w *interceptedCollection) Add(ctx context.Context, data any) (*firestore.DocumentRef, *firestore.WriteResult, error) {
+	res, err := run(ctx, w.fn, Op{Method: "CollectionRef.Add", Path: w.path(), Args: []any{data}}, func() (addResult, error) {
+		ref, wr, err := w.c.Add(ctx, data)
+		return addResult{ref, wr}, err
+	})
+	return res.Ref, res.WriteResult, err
+}
+
+func (w *interceptedCollection) NewDoc() DocumentRef {
+	return interceptDoc(w.c.NewDoc(), w.fn)
+}
+
+func (w *interceptedCollection) DocumentRefs(ctx context.Context) DocumentRefIterator {
+	return &interceptedDocumentRefIterator{it: w.c.DocumentRefs(ctx), seq: newOpSeq(ctx, w.fn, "DocumentRefIterator.Next", w.path, nil)}
+}
+
+func (w *interceptedCollection) Parent() DocumentRef {
+	return interceptDoc(w.c.Parent(), w.fn)
+}
+
+func (w *interceptedCollection) Reference() *firestore.CollectionRef { return w.c.Reference() }
+
+func (w *interceptedCollection) ID() string { return w.c.ID() }
+
+func (w *interceptedCollection) Path() string { return w.c.Path() }
+
+type interceptedDoc struct {
+	d  DocumentRef
+	fn interceptor
+}
+
+func interceptDoc(d DocumentRef, fn interceptor) DocumentRef {
+	if d == nil {
+		return nil
+	}
+	return &interceptedDoc{d: d, fn: fn}
+}
+
+func (w *interceptedDoc) path() string { return fsvalue.RelativePath(w.d.Path()) }
+
+func (w *interceptedDoc) op(method string, args ...any) Op {
+	return Op{Method: method, Path: w.path(), Args: args}
+}
+
+func (w *interceptedDoc) Set(ctx context.Context, data any, opts ...firestore.SetOption) (*firestore.WriteResult, error) {
+	return run(ctx, w.fn, w.op("DocumentRef.Set", data, opts), func() (*firestore.WriteResult, error) {
+		return w.d.Set(ctx, data, opts...)
+	})
+}
+
+func (w *interceptedDoc) Get(ctx context.Context) (DocumentSnapshot, error) {
+	return run(ctx, w.fn, w.op("DocumentRef.Get"), func() (DocumentSnapshot, error) {
+		return w.d.Get(ctx)
+	})
+}
+
+func (w *interceptedDoc) Delete(ctx context.Context, opts ...firestore.Precondition) (*firestore.WriteResult, error) {
+	return run(ctx, w.fn, w.op("DocumentRef.Delete", opts), func() (*firestore.WriteResult, error) {
+		return w.d.Delete(ctx, opts...)
+	})
+}
+
+func (w *interceptedDoc) Update(ctx context.Context, updates []firestore.Update, preconds ...firestore.Precondition) (*firestore.WriteResult, error) {
+	return run(ctx, w.fn, w.op("DocumentRef.Update", updates, preconds), func() (*firestore.WriteResult, error) {
+		return w.d.Update(ctx, updates, preconds...)
+	})
+}
+
+func (w *interceptedDoc) Create(ctx context.Context, data any) (*firestore.WriteResult, error) {
+	return run(ctx, w.fn, w.op("DocumentRef.Create", data), func() (*firestore.WriteResult, error) {
+		return w.d.Create(ctx, data)
+	})
+}
+
+func (w *interceptedDoc) Collection(path string) CollectionRef {
+	return interceptCollection(w.d.Collection(path), w.fn)
+}
+
+func (w *interceptedDoc) Collections(ctx context.Context) CollectionIterator {
+	return &interceptedCollectionIterator{it: w.d.Collections(ctx), seq: newOpSeq(ctx, w.fn, "CollectionIterator.Next", w.path, nil)}
+}
+
+func (w *interceptedDoc) Snapshots(ctx context.Context) DocumentSnapshotIterator {
+	return &interceptedDocumentSnapshotIterator{it: w.d.Snapshots(ctx), seq: newOpSeq(ctx, w.fn, "DocumentSnapshotIterator.Next", w.path, nil)}
+}
+
+func (w *interceptedDoc) Reference() *firestore.DocumentRef { return w.d.Reference() }
+
+func (w *interceptedDoc) ID() string { return w.d.ID() }
+
+func (w *interceptedDoc) Path() string { return w.d.Path() }
+
+func (w *interceptedDoc) Parent() *firestore.CollectionRef { return w.d.Parent() }
+
+// interceptedAggregationQuery keeps the query it aggregates and the aliases
+// added so far, which are reported as the Op's Query and Args.
+type interceptedAggregationQuery struct {
+	aq      AggregationQuery
+	fn      interceptor
+	path    func() string
+	q       Query
+	aliases []string
+}
+
+func (w *interceptedAggregationQuery) WithCount(alias string) AggregationQuery {
+	return &interceptedAggregationQuery{
+		aq:      w.aq.WithCount(alias),
+		fn:      w.fn,
+		path:    w.path,
+		q:       w.q,
+		aliases: append(append([]string(nil), w.aliases...), alias),
+	}
+}
+
+func (w *interceptedAggregationQuery) Get(ctx context.Context) (AggregationResult, error) {
+	spec := Describe(w.q)
+	op := Op{Method: "AggregationQuery.Get", Path: w.path(), Query: &spec, Args: []any{w.aliases}}
+	return run(ctx, w.fn, op, func() (AggregationResult, error) {
+		return w.aq.Get(ctx)
+	})
+}
+
+type interceptedWriteBatch struct {
+	wb     WriteBatch
+	fn     interceptor
+	writes []BatchWrite
+}
+
+func (w *interceptedWriteBatch) add(op string, docRef *firestore.DocumentRef, args ...any) {
+	w.writes = append(w.writes, BatchWrite{Op: op, Path: refPath(docRef), Args: args})
+}
+
+func (w *interceptedWriteBatch) Create(docRef *firestore.DocumentRef, data interface{}) WriteBatch {
+	w.add("Create", docRef, data)
+	w.wb.Create(docRef, data)
+	return w
+}
+
+func (w *interceptedWriteBatch) Set(docRef *firestore.DocumentRef, data interface{}, opts ...firestore.SetOption) WriteBatch {
+	w.add("Set", docRef, data, opts)
+	w.wb.Set(docRef, data, opts...)
+	return w
+}
+
+func (w *interceptedWriteBatch) Update(docRef *firestore.DocumentRef, updates []firestore.Update, preconds ...firestore.Precondition) WriteBatch {
+	w.add("Update", docRef, updates, preconds)
+	w.wb.Update(docRef, updates, preconds...)
+	return w
+}
+
+func (w *interceptedWriteBatch) Delete(docRef *firestore.DocumentRef, preconds ...firestore.Precondition) WriteBatch {
+	w.add("Delete", docRef, preconds)
+	w.wb.Delete(docRef, preconds...)
+	return w
+}
+
+func (w *interceptedWriteBatch) Commit(ctx context.Context) ([]*firestore.WriteResult, error) {
+	return run(ctx, w.fn, Op{Method: "WriteBatch.Commit", Args: []any{w.writes}}, func() ([]*firestore.WriteResult, error) {
+		return w.wb.Commit(ctx)
+	})
+}
+
+// refPath returns the path of docRef relative to the database root.
+func refPath(docRef *firestore.DocumentRef) string {
+	if docRef == nil {
+		return ""
+	}
+	return fsvalue.RelativePath(docRef.Path)
+}
+
+// interceptedBulkWriter runs each write as an enqueue Op ("BulkWriter.Set",
+// ...) and wraps the returned job, whose Results runs as a
+// "BulkWriterJob.Results" Op. An interceptor failing the enqueue Op makes the
+// enqueue call return (nil, err), as the SDK does for a rejected write; one
+// failing the Results Op reports the write as failed through its job, as the
+// SDK does for writes the backend rejects.
+type interceptedBulkWriter struct {
+	bw  BulkWriter
+	fn  interceptor
+	ctx context.Context
+}
+
+func (w *interceptedBulkWriter) enqueue(method string, docRef *firestore.DocumentRef, args []any, call func() (BulkWriterJob, error)) (BulkWriterJob, error) {
+	path := refPath(docRef)
+	job, err := run(w.ctx, w.fn, Op{Method: method, Path: path, Args: args}, call)
+	if err != nil {
+		return nil, err
+	}
+	return &interceptedBulkWriterJob{job: job, fn: w.fn, ctx: w.ctx, path: path}, nil
+}
+
+func (w *interceptedBulkWriter) Create(docRef *firestore.DocumentRef, data interface{}) (BulkWriterJob, error) {
+	return w.enqueue("BulkWriter.Create", docRef, []any{data}, func() (BulkWriterJob, error) {
+		return w.bw.Create(docRef, data)
+	})
+}
+
+func (w *interceptedBulkWriter) Set(docRef *firestore.DocumentRef, data interface{}, opts ...firestore.SetOption) (BulkWriterJob, error) {
+	return w.enqueue("BulkWriter.Set", docRef, []any{data, opts}, func() (BulkWriterJob, error) {
+		return w.bw.Set(docRef, data, opts...)
+	})
+}
+
+func (w *interceptedBulkWriter) Update(docRef *firestore.DocumentRef, updates []firestore.Update, preconds ...firestore.Precondition) (BulkWriterJob, error) {
+	return w.enqueue("BulkWriter.Update", docRef, []any{updates, preconds}, func() (BulkWriterJob, error) {
+		return w.bw.Update(docRef, updates, preconds...)
+	})
+}
+
+func (w *interceptedBulkWriter) Delete(docRef *firestore.DocumentRef, preconds ...firestore.Precondition) (BulkWriterJob, error) {
+	return w.enqueue("BulkWriter.Delete", docRef, []any{preconds}, func() (BulkWriterJob, error) {
+		return w.bw.Delete(docRef, preconds...)
+	})
+}
+
+func (w *interceptedBulkWriter) Flush() { w.bw.Flush() }
+
+func (w *interceptedBulkWriter) End() { w.bw.End() }
+
+// interceptedBulkWriterJob is the job of an intercepted BulkWriter write. job
+// is nil when an interceptor served the enqueue Op without performing it.
+type interceptedBulkWriterJob struct {
+	job  BulkWriterJob
+	fn   interceptor
+	ctx  context.Context
+	path string
+}
+
+func (j *interceptedBulkWriterJob) Results() (*firestore.WriteResult, error) {
+	return run(j.ctx, j.fn, Op{Method: "BulkWriterJob.Results", Path: j.path}, func() (*firestore.WriteResult, error) {
+		if j.job == nil {
+			return nil, nil
+		}
+		return j.job.Results()
+	})
+}
+
+type interceptedTransaction struct {
+	tx  Transaction
+	fn  interceptor
+	ctx context.Context
+}
+
+func (w *interceptedTransaction) op(method string, docRef *firestore.DocumentRef, args ...any) Op {
+	return Op{Method: method, Path: refPath(docRef), Args: args}
+}
+
+func (w *interceptedTransaction) Get(docRef *firestore.DocumentRef) (DocumentSnapshot, error) {
+	return run(w.ctx, w.fn, w.op("Transaction.Get", docRef), func() (DocumentSnapshot, error) {
+		return w.tx.Get(docRef)
+	})
+}
+
+func (w *interceptedTransaction) GetAll(docRefs []*firestore.DocumentRef) ([]DocumentSnapshot, error) {
+	return run(w.ctx, w.fn, w.op("Transaction.GetAll", nil, docRefs), func() ([]DocumentSnapshot, error) {
+		return w.tx.GetAll(docRefs)
+	})
+}
+
+func (w *interceptedTransaction) Documents(q Query) DocumentIterator {
+	if iq, ok := q.(interface{ unwrapQuery() Query }); ok {
+		q = iq.unwrapQuery()
+	}
+	path := func() string { return Describe(q).Collection }
+	return &interceptedDocumentIterator{it: w.tx.Documents(q), seq: newOpSeq(w.ctx, w.fn, "DocumentIterator.Next", path, q)}
+}
+
+func (w *interceptedTransaction) DocumentRefs(coll CollectionRef) DocumentRefIterator {
+	path := noPath
+	if ic, ok := coll.(*interceptedCollection); ok {
+		coll, path = ic.c, ic.path
+	}
+	return &interceptedDocumentRefIterator{it: w.tx.DocumentRefs(coll), seq: newOpSeq(w.ctx, w.fn, "DocumentRefIterator.Next", path, nil)}
+}
+
+func (w *interceptedTransaction) Create(docRef *firestore.DocumentRef, data interface{}) error {
+	_, err := run(w.ctx, w.fn, w.op("Transaction.Create", docRef, data), func() (any, error) {
+		return nil, w.tx.Create(docRef, data)
+	})
+	return err
+}
+
+func (w *interceptedTransaction) Set(docRef *firestore.DocumentRef, data interface{}, opts ...firestore.SetOption) error {
+	_, err := run(w.ctx, w.fn, w.op("Transaction.Set", docRef, data, opts), func() (any, error) {
+		return nil, w.tx.Set(docRef, data, opts...)
+	})
+	return err
+}
+
+func (w *interceptedTransaction) Update(docRef *firestore.DocumentRef, updates []firestore.Update, preconds ...firestore.Precondition) error {
+	_, err := run(w.ctx, w.fn, w.op("Transaction.Update", docRef, updates, preconds), func() (any, error) {
+		return nil, w.tx.Update(docRef, updates, preconds...)
+	})
+	return err
+}
+
+func (w *interceptedTransaction) Delete(docRef *firestore.DocumentRef, preconds ...firestore.Precondition) error {
+	_, err := run(w.ctx, w.fn, w.op("Transaction.Delete", docRef, preconds), func() (any, error) {
+		return nil, w.tx.Delete(docRef, preconds...)
+	})
+	return err
+}
+
+func noPath() string { return "" }
+
+// opSeq runs the Next calls of an intercepted iterator as numbered Ops and
+// keeps the first error other than iterator.Done, which (like SDK iterator
+// errors) is sticky.
+type opSeq struct {
+	ctx    context.Context
+	fn     interceptor
+	method string
+	path   func() string
+	q      Query // the query read by the iterator, if any
+	calls  int
+	err    error
+}
+
+func newOpSeq(ctx context.Context, fn interceptor, method string, path func() string, q Query) *opSeq {
+	return &opSeq{ctx: ctx, fn: fn, method: method, path: path, q: q}
+}
+
+func nextOp[T any](s *opSeq, next func() (T, error)) (T, error) {
+	if s.err != nil {
+		var zero T
+		return zero, s.err
+	}
+	s.calls++
+	op := Op{Method: s.method, Path: s.path(), Call: s.calls}
+	if s.q != nil {
+		spec := Describe(s.q)
+		op.Query = &spec
+	}
+	v, err := run(s.ctx, s.fn, op, next)
+	if err != nil && err != iterator.Done {
+		s.err = err
+	}
+	return v, err
+}
+
+// allOp runs the GetAll of an intercepted iterator as one Op of the given
+// method, unless an earlier Next failed.
+func allOp[T any](s *opSeq, method string, all func() (T, error)) (T, error) {
+	if s.err != nil {
+		var zero T
+		return zero, s.err
+	}
+	op := Op{Method: method, Path: s.path(), Call: 1}
+	if s.q != nil {
+		spec := Describe(s.q)
+		op.Query = &spec
+	}
+	v, err := run(s.ctx, s.fn, op, all)
+	if err != nil {
+		s.err = err
+	}
+	return v, err
+}
+
+type interceptedDocumentIterator struct {
+	it   DocumentIterator
+	seq  *opSeq
+	done bool
+}
+
+func (w *interceptedDocumentIterator) Next() (*firestore.DocumentSnapshot, error) {
+	snap, err := nextOp(w.seq, w.it.Next)
+	if err == iterator.Done {
+		w.done = true
+	}
+	return snap, err
+}
+
+func (w *interceptedDocumentIterator) Stop() {
+	w.done = true
+	w.it.Stop()
+}
+
+// GetAll runs the GetAll of the decorated iterator, which the SDK needs for
+// LimitToLast queries, as one "DocumentIterator.GetAll" Op. Like the SDK, it
+// stops the iterator and, once the iterator is done, returns iterator.Done.
+func (w *interceptedDocumentIterator) GetAll() ([]*firestore.DocumentSnapshot, error) {
+	if w.done {
+		return nil, iterator.Done
+	}
+	w.done = true
+	defer w.it.Stop()
+	return allOp(w.seq, "DocumentIterator.GetAll", w.it.GetAll)
+}
+
+type interceptedDocumentRefIterator struct {
+	it  DocumentRefIterator
+	seq *opSeq
+}
+
+func (w *interceptedDocumentRefIterator) Next() (*firestore.DocumentRef, error) {
+	return nextOp(w.seq, w.it.Next)
+}
+
+func (w *interceptedDocumentRefIterator) GetAll() ([]*firestore.DocumentRef, error) {
+	var refs []*firestore.DocumentRef
+	for {
+		ref, err := w.Next()
+		if err == iterator.Done {
+			return refs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		refs = append(refs, ref)
+	}
+}
+
+type interceptedCollectionIterator struct {
+	it  CollectionIterator
+	seq *opSeq
+}
+
+func (w *interceptedCollectionIterator) Next() (*firestore.CollectionRef, error) {
+	return nextOp(w.seq, w.it.Next)
+}
+
+func (w *interceptedCollectionIterator) Stop() { w.it.Stop() }
+
+type interceptedQuerySnapshotIterator struct {
+	it  QuerySnapshotIterator
+	seq *opSeq
+}
+
+func (w *interceptedQuerySnapshotIterator) Next() (*firestore.QuerySnapshot, error) {
+	return nextOp(w.seq, w.it.Next)
+}
+
+func (w *interceptedQuerySnapshotIterator) Stop() { w.it.Stop() }
+
+type interceptedDocumentSnapshotIterator struct {
+	it  DocumentSnapshotIterator
+	seq *opSeq
+}
+
+func (w *interceptedDocumentSnapshotIterator) Next() (DocumentSnapshot, error) {
+	return nextOp(w.seq, w.it.Next)
+}
+
+func (w *interceptedDocumentSnapshotIterator) Stop() { w.it.Stop() }
@@ -0,0 +1,63 @@
+package firestore
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"cloud.google.com/go/firestore"
+)
+
+// TestDecorators_LimitToLast runs a LimitToLast query, which the SDK only
+// serves through GetAll, through each decorator of a real client.
+func TestDecorators_LimitToLast(t *testing.T) {
+	ctx := context.Background()
+	sc := newSnapshotClient(t)
+	for i := 1; i <= 4; i++ {
+		newSnapshot(t, sc, fmt.Sprintf("users/u%d", i), map[string]any{"age": i})
+	}
+	ix, err := ReadIndexes([]byte(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var log bytes.Buffer
+
+	tests := []struct {
+		name   string
+		client FirestoreClient
+	}{
+		{name: "WithFaults", client: WithFaults(NewFirestoreClient(sc), FaultRule{Method: "DocumentRef.Set"})},
+		{name: "WithLatency", client: WithLatency(NewFirestoreClient(sc), LatencyRule{Latency: FixedLatency(0)})},
+		{name: "WithIndexes", client: WithIndexes(NewFirestoreClient(sc), ix)},
+		{name: "Record", client: Record(NewFirestoreClient(sc), &log)},
+	}
+	want := []string{"u3", "u4"}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			docs, err := tt.client.Collection("users").OrderBy("age", firestore.Asc).LimitToLast(2).Documents(ctx).GetAll()
+			if err != nil {
+				t.Fatalf("GetAll() error = %v", err)
+			}
+			var ids []string
+			for _, d := range docs {
+				ids = append(ids, d.Ref.ID)
+			}
+			if !reflect.DeepEqual(ids, want) {
+				t.Errorf("GetAll() = %v, want %v", ids, want)
+			}
+		})
+	}
+
+	t.Run("Replay", func(t *testing.T) {
+		replay, err := Replay(&log)
+		if err != nil {
+			t.Fatalf("Replay() error = %v", err)
+		}
+		docs, err := replay.Collection("users").OrderBy("age", firestore.Asc).LimitToLast(2).Documents(ctx).GetAll()
+		if err != nil || len(docs) != 2 || docs[0].Ref.ID != "u3" || docs[1].Data()["age"] != int64(4) {
+			t.Errorf("replayed GetAll() = %v, %v", docs, err)
+		}
+	})
+}
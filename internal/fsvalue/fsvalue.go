@@ -0,0 +1,231 @@
+// Package fsvalue holds the path and document value helpers shared by the
+// root package and its subpackages (fsmatch, fstest, conformance): rendering
+// and parsing field paths, relative document paths, and comparing and
+// formatting document values the way Diff does.
+package fsvalue
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/genproto/googleapis/type/latlng"
+)
+
+// RelativePath strips the "projects/P/databases/D/documents/" prefix from a
+// full Firestore resource path, giving the path relative to the database root
+// ("users/u1"). Other paths are returned unchanged.
+func RelativePath(path string) string {
+	const sep = "/documents/"
+	if strings.HasPrefix(path, "projects/") {
+		if i := strings.Index(path, sep); i >= 0 {
+			return path[i+len(sep):]
+		}
+	}
+	return path
+}
+
+var simpleFieldName = regexp.MustCompile(`^[A-Za-z_][A-Za-z_0-9]*$`)
+
+// FieldPathString renders fp in the dotted form accepted by Where / OrderBy,
+// quoting components that are not simple identifiers with backticks, e.g.
+// "address.`zip-code`".
+func FieldPathString(fp firestore.FieldPath) string {
+	parts := make([]string, len(fp))
+	for i, p := range fp {
+		if simpleFieldName.MatchString(p) {
+			parts[i] = p
+			continue
+		}
+		p = strings.ReplaceAll(p, `\`, `\\`)
+		parts[i] = "`" + strings.ReplaceAll(p, "`", "\\`") + "`"
+	}
+	return strings.Join(parts, ".")
+}
+
+// ParseFieldPath is the inverse of FieldPathString: it splits a dotted path
+// into its components, honoring backtick-quoted components.
+func ParseFieldPath(s string) firestore.FieldPath {
+	var (
+		fp      firestore.FieldPath
+		cur     strings.Builder
+		quoted  bool
+		escaped bool
+	)
+	for _, r := range s {
+		switch {
+		case escaped:
+			cur.WriteRune(r)
+			escaped = false
+		case quoted && r == '\\':
+			escaped = true
+		case r == '`':
+			quoted = !quoted
+		case r == '.' && !quoted:
+			fp = append(fp, cur.String())
+			cur.Reset()
+		default:
+			cur.WriteRune(r)
+		}
+	}
+	return append(fp, cur.String())
+}
+
+// RefPath is a document reference compared by relative path.
+type RefPath string
+
+// Normalize converts v to the types DocumentSnapshot.Data returns, with
+// times at microsecond precision and references as RefPath.
+func Normalize(v any) any {
+	switch v := v.(type) {
+	case int:
+		return int64(v)
+	case int8:
+		return int64(v)
+	case int16:
+		return int64(v)
+	case int32:
+		return int64(v)
+	case uint8:
+		return int64(v)
+	case uint16:
+		return int64(v)
+	case uint32:
+		return int64(v)
+	case float32:
+		return float64(v)
+	case time.Time:
+		return v.UTC().Truncate(time.Microsecond)
+	case *firestore.DocumentRef:
+		if v == nil {
+			return nil
+		}
+		return RefPath(RelativePath(v.Path))
+	case firestore.Vector32:
+		vec := make(firestore.Vector64, len(v))
+		for i, f := range v {
+			vec[i] = float64(f)
+		}
+		return vec
+	}
+	return v
+}
+
+// Equal reports whether the document values a and b are equal as Firestore
+// types: int64(1) differs from float64(1) and times are compared at
+// microsecond precision.
+func Equal(a, b any) bool {
+	a, b = Normalize(a), Normalize(b)
+	switch a := a.(type) {
+	case float64:
+		b, ok := b.(float64)
+		return ok && (a == b || math.IsNaN(a) && math.IsNaN(b))
+	case time.Time:
+		b, ok := b.(time.Time)
+		return ok && a.Equal(b)
+	case []byte:
+		b, ok := b.([]byte)
+		return ok && bytes.Equal(a, b)
+	case *latlng.LatLng:
+		b, ok := b.(*latlng.LatLng)
+		return ok && (a == nil) == (b == nil) && (a == nil || a.Latitude == b.Latitude && a.Longitude == b.Longitude)
+	case firestore.Vector64:
+		b, ok := b.(firestore.Vector64)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !Equal(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		b, ok := b.([]any)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !Equal(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]any:
+		b, ok := b.(map[string]any)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for k, av := range a {
+			bv, ok := b[k]
+			if !ok || !Equal(av, bv) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.TypeOf(a) == reflect.TypeOf(b) && reflect.DeepEqual(a, b)
+}
+
+// Format renders a document value compactly, keeping the distinctions Equal
+// makes: "1" for an integer, "1.0" for a float, quoted strings,
+// ref(users/u1) for a reference and {k: v, ...} for a map.
+func Format(v any) string {
+	switch v := Normalize(v).(type) {
+	case nil:
+		return "null"
+	case string:
+		return strconv.Quote(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		s := strconv.FormatFloat(v, 'g', -1, 64)
+		if !strings.ContainsAny(s, ".eEN") { // N: NaN, Inf
+			s += ".0"
+		}
+		return s
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case RefPath:
+		return "ref(" + string(v) + ")"
+	case []byte:
+		return "bytes(" + base64.StdEncoding.EncodeToString(v) + ")"
+	case *latlng.LatLng:
+		if v == nil {
+			return "null"
+		}
+		return "geo(" + Format(v.Latitude) + ", " + Format(v.Longitude) + ")"
+	case firestore.Vector64:
+		parts := make([]string, len(v))
+		for i, f := range v {
+			parts[i] = Format(f)
+		}
+		return "vector[" + strings.Join(parts, ", ") + "]"
+	case []any:
+		parts := make([]string, len(v))
+		for i, x := range v {
+			parts[i] = Format(x)
+		}
+		return "[" + strings.Join(parts, ", ") + "]"
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		parts := make([]string, len(keys))
+		for i, k := range keys {
+			parts[i] = FieldPathString(firestore.FieldPath{k}) + ": " + Format(v[k])
+		}
+		return "{" + strings.Join(parts, ", ") + "}"
+	}
+	return fmt.Sprintf("%v", v)
+}
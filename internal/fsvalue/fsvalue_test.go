@@ -0,0 +1,82 @@
+package fsvalue
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/genproto/googleapis/type/latlng"
+)
+
+func TestEqual(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		a, b any
+		want bool
+	}{
+		{name: "int widths", a: 1, b: int64(1), want: true},
+		{name: "int is not float", a: 1, b: 1.0, want: false},
+		{name: "NaN", a: math.NaN(), b: math.NaN(), want: true},
+		{name: "time zones", a: at, b: at.In(time.FixedZone("x", 3600)), want: true},
+		{name: "time below microseconds", a: at, b: at.Add(time.Nanosecond), want: true},
+		{name: "refs by relative path", a: &firestore.DocumentRef{Path: "projects/p/databases/(default)/documents/users/u1"}, b: &firestore.DocumentRef{Path: "users/u1"}, want: true},
+		{name: "geo points", a: &latlng.LatLng{Latitude: 1, Longitude: 2}, b: &latlng.LatLng{Latitude: 1, Longitude: 2}, want: true},
+		{name: "vectors", a: firestore.Vector32{1, 2}, b: firestore.Vector64{1, 2}, want: true},
+		{name: "nested maps", a: map[string]any{"a": []any{1, "x"}}, b: map[string]any{"a": []any{int64(1), "x"}}, want: true},
+		{name: "missing key", a: map[string]any{"a": 1}, b: map[string]any{"b": 1}, want: false},
+		{name: "bytes", a: []byte("x"), b: []byte("y"), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Equal(tt.a, tt.b); got != tt.want {
+				t.Errorf("Equal(%v, %v) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		v    any
+		want string
+	}{
+		{v: nil, want: "null"},
+		{v: 1, want: "1"},
+		{v: 1.0, want: "1.0"},
+		{v: "a", want: `"a"`},
+		{v: &firestore.DocumentRef{Path: "projects/p/databases/(default)/documents/users/u1"}, want: "ref(users/u1)"},
+		{v: []byte("hello"), want: "bytes(aGVsbG8=)"},
+		{v: &latlng.LatLng{Latitude: 1.5, Longitude: 2}, want: "geo(1.5, 2.0)"},
+		{v: firestore.Vector64{0.5, 1}, want: "vector[0.5, 1.0]"},
+		{v: map[string]any{"b": []any{true}, "zip-code": 1}, want: "{b: [true], `zip-code`: 1}"},
+	}
+	for _, tt := range tests {
+		if got := Format(tt.v); got != tt.want {
+			t.Errorf("Format(%#v) = %s, want %s", tt.v, got, tt.want)
+		}
+	}
+}
+
+// FuzzFieldPathString checks that ParseFieldPath reads back the paths
+// rendered by FieldPathString; the fuzzed string holds the components
+// separated by NUL bytes.
+func FuzzFieldPathString(f *testing.F) {
+	for _, seed := range []string{"a", "a\x00b", "zip-code", "a.b", "`", "\\", "", "a\x00\x00b", "é\x00_x1"} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		if !utf8.ValidString(s) {
+			t.Skip("field names are UTF-8")
+		}
+		fp := firestore.FieldPath(strings.Split(s, "\x00"))
+		rendered := FieldPathString(fp)
+		if got := ParseFieldPath(rendered); !reflect.DeepEqual(got, fp) {
+			t.Errorf("ParseFieldPath(%q) = %q, want %q", rendered, got, fp)
+		}
+	})
+}
@@ -0,0 +1,68 @@
+package firestore
+
+import (
+	"iter"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+)
+
+// All returns a range-over-func sequence over the documents of it:
+//
+//	for snap, err := range All(q.Documents(ctx)) {
+//		if err != nil {
+//			return err
+//		}
+//		...
+//	}
+//
+// The sequence ends after iterator.Done, or after yielding the first other
+// error as (nil, err). it is stopped when the sequence ends, including when
+// the loop body breaks early.
+func All(it DocumentIterator) iter.Seq2[*firestore.DocumentSnapshot, error] {
+	return seq(it.Next, it.Stop)
+}
+
+// AllDocumentRefs is All for a DocumentRefIterator. DocumentRefIterator has no
+// Stop method, so nothing is released on early break.
+func AllDocumentRefs(it DocumentRefIterator) iter.Seq2[*firestore.DocumentRef, error] {
+	return seq(it.Next, func() {})
+}
+
+// AllCollections is All for a CollectionIterator.
+func AllCollections(it CollectionIterator) iter.Seq2[*firestore.CollectionRef, error] {
+	return seq(it.Next, it.Stop)
+}
+
+// AllQuerySnapshots is All for a QuerySnapshotIterator. Snapshot listeners do
+// not end on their own: break out of the loop (or cancel the context passed to
+// Snapshots) to stop listening.
+func AllQuerySnapshots(it QuerySnapshotIterator) iter.Seq2[*firestore.QuerySnapshot, error] {
+	return seq(it.Next, it.Stop)
+}
+
+// AllDocumentSnapshots is All for a DocumentSnapshotIterator. See
+// AllQuerySnapshots for how to stop listening.
+func AllDocumentSnapshots(it DocumentSnapshotIterator) iter.Seq2[DocumentSnapshot, error] {
+	return seq(it.Next, it.Stop)
+}
+
+func seq[T any](next func() (T, error), stop func()) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		defer stop()
+		for {
+			item, err := next()
+			if err == iterator.Done {
+				return
+			}
+			if err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+			if !yield(item, nil) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package firestore
+
+import (
+	"errors"
+	"testing"
+
+	"cloud.google.com/go/firestore"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/api/iterator"
+)
+
+func TestAll(t *testing.T) {
+	a := &firestore.DocumentSnapshot{}
+	b := &firestore.DocumentSnapshot{}
+
+	var got []*firestore.DocumentSnapshot
+	for snap, err := range All(NewDocumentIteratorFromSlice([]*firestore.DocumentSnapshot{a, b}, nil)) {
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		got = append(got, snap)
+	}
+	if len(got) != 2 || got[0] != a || got[1] != b {
+		t.Errorf("All() yielded %v, want [a b]", got)
+	}
+}
+
+func TestAll_Error(t *testing.T) {
+	boom := errors.New("boom")
+
+	var errs []error
+	n := 0
+	for snap, err := range All(NewDocumentIteratorFromSlice([]*firestore.DocumentSnapshot{{}}, boom)) {
+		if err != nil {
+			if snap != nil {
+				t.Errorf("snapshot = %v with error, want nil", snap)
+			}
+			errs = append(errs, err)
+			continue
+		}
+		n++
+	}
+	if n != 1 || len(errs) != 1 || errs[0] != boom {
+		t.Errorf("got %d items and errors %v, want 1 item and [boom]", n, errs)
+	}
+}
+
+func TestAll_StopsOnBreak(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	it := NewMockDocumentIterator(ctrl)
+	it.EXPECT().Next().Return(&firestore.DocumentSnapshot{}, nil)
+	it.EXPECT().Stop()
+
+	for range All(it) {
+		break
+	}
+}
+
+func TestAll_StopsAtEnd(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	it := NewMockQuerySnapshotIterator(ctrl)
+	gomock.InOrder(
+		it.EXPECT().Next().Return(&firestore.QuerySnapshot{Size: 1}, nil),
+		it.EXPECT().Next().Return(nil, iterator.Done),
+		it.EXPECT().Stop(),
+	)
+
+	n := 0
+	for snap, err := range AllQuerySnapshots(it) {
+		if err != nil || snap.Size != 1 {
+			t.Fatalf("got %v, %v", snap, err)
+		}
+		n++
+	}
+	if n != 1 {
+		t.Errorf("yielded %d snapshots, want 1", n)
+	}
+}
+
+func TestAllDocumentRefs(t *testing.T) {
+	refs := []*firestore.DocumentRef{{ID: "a"}, {ID: "b"}, {ID: "c"}}
+
+	var ids []string
+	for ref, err := range AllDocumentRefs(NewDocumentRefIteratorFromSlice(refs, nil)) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, ref.ID)
+		if ref.ID == "b" {
+			break
+		}
+	}
+	if len(ids) != 2 {
+		t.Errorf("ids = %v, want [a b]", ids)
+	}
+}
+
+func TestAllCollections(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	it := NewMockCollectionIterator(ctrl)
+	gomock.InOrder(
+		it.EXPECT().Next().Return(&firestore.CollectionRef{ID: "posts"}, nil),
+		it.EXPECT().Next().Return(nil, iterator.Done),
+		it.EXPECT().Stop(),
+	)
+
+	var ids []string
+	for coll, err := range AllCollections(it) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, coll.ID)
+	}
+	if len(ids) != 1 || ids[0] != "posts" {
+		t.Errorf("ids = %v, want [posts]", ids)
+	}
+}
+
+func TestAllDocumentSnapshots(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	it := NewMockDocumentSnapshotIterator(ctrl)
+	snap := NewMockDocumentSnapshot(ctrl)
+	snap.EXPECT().Exists().Return(true).AnyTimes()
+	it.EXPECT().Next().Return(snap, nil).Times(3)
+	it.EXPECT().Stop()
+
+	n := 0
+	for s, err := range AllDocumentSnapshots(it) {
+		if err != nil || !s.Exists() {
+			t.Fatalf("got %v, %v", s, err)
+		}
+		n++
+		if n == 3 {
+			break
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package firestore
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+
+	"google.golang.org/grpc/status"
+)
+
+// LatencyRule selects operations of a WithLatency client and how long they
+// are delayed. Empty patterns match everything:
+//
+//	// every read of users/* takes 20-80ms
+//	{Method: "DocumentRef.Get", Path: "users/*", Latency: UniformLatency(20*time.Millisecond, 80*time.Millisecond)}
+//	// each page of query results takes ~5ms
+//	{Method: "*.Next", Latency: FixedLatency(5 * time.Millisecond)}
+type LatencyRule struct {
+	// Method is a path.Match pattern for Op.Method (see Op).
+	Method string
+	// Path is a path.Match pattern for Op.Path, e.g. "users/*".
+	Path string
+	// Latency returns the delay of one operation; nil means no delay, so a
+	// rule without it exempts matching operations from later rules.
+	Latency func() time.Duration
+}
+
+// FixedLatency delays every operation by d.
+func FixedLatency(d time.Duration) func() time.Duration {
+	return func() time.Duration { return d }
+}
+
+// UniformLatency delays operations by a duration drawn uniformly from
+// [min, max).
+func UniformLatency(min, max time.Duration) func() time.Duration {
+	return func() time.Duration {
+		if max <= min {
+			return min
+		}
+		return min + rand.N(max-min)
+	}
+}
+
+// NormalLatency delays operations by a normally distributed duration with the
+// given mean and standard deviation, never less than zero.
+func NormalLatency(mean, stddev time.Duration) func() time.Duration {
+	return func() time.Duration {
+		return max(0, mean+time.Duration(rand.NormFloat64()*float64(stddev)))
+	}
+}
+
+// WithLatency decorates client so that operations matching one of rules are
+// delayed before being performed; the first matching rule wins. Like
+// WithFaults, the rules apply to everything the returned client hands out,
+// including each Next of its iterators.
+//
+// The delay honors the operation's context (for iterators, the context passed
+// to Documents, Snapshots, ...; for transactions, the RunTransaction context).
+// If the context is done first, the operation is not performed and fails the
+// way the SDK surfaces it: with a gRPC status error of code DeadlineExceeded
+// or Canceled.
+func WithLatency(client FirestoreClient, rules ...LatencyRule) FirestoreClient {
+	return intercept(client, func(ctx context.Context, op Op, call func() (any, error)) (any, error) {
+		for _, r := range rules {
+			if matchOp(r.Method, r.Path, op) {
+				if r.Latency != nil {
+					if err := sleepContext(ctx, r.Latency()); err != nil {
+						return nil, err
+					}
+				}
+				break
+			}
+		}
+		return call()
+	})
+}
+
+// sleepContext waits for d or until ctx is done, whichever comes first.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return status.FromContextError(err).Err()
+	}
+	if d <= 0 {
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return status.FromContextError(ctx.Err()).Err()
+	}
+}
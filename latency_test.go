@@ -0,0 +1,134 @@
+package firestore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestWithLatency_DelaysMatchingOps(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	inner := NewMockFirestoreClient(ctrl)
+	user := NewMockDocumentRef(ctrl)
+	order := NewMockDocumentRef(ctrl)
+	inner.EXPECT().Doc("users/u1").Return(user)
+	inner.EXPECT().Doc("orders/o1").Return(order)
+	user.EXPECT().Path().Return(testDocsRoot + "users/u1").AnyTimes()
+	order.EXPECT().Path().Return(testDocsRoot + "orders/o1").AnyTimes()
+	user.EXPECT().Get(ctx).Return(nil, nil)
+	order.EXPECT().Get(ctx).Return(nil, nil)
+
+	const delay = 30 * time.Millisecond
+	client := WithLatency(inner, LatencyRule{Method: "DocumentRef.Get", Path: "users/*", Latency: FixedLatency(delay)})
+
+	start := time.Now()
+	if _, err := client.Doc("users/u1").Get(ctx); err != nil {
+		t.Fatalf("Get(users/u1) error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < delay {
+		t.Errorf("Get(users/u1) took %v, want at least %v", elapsed, delay)
+	}
+
+	start = time.Now()
+	if _, err := client.Doc("orders/o1").Get(ctx); err != nil {
+		t.Fatalf("Get(orders/o1) error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed >= delay {
+		t.Errorf("Get(orders/o1) took %v, want no delay", elapsed)
+	}
+}
+
+func TestWithLatency_NilLatency(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	inner := NewMockFirestoreClient(ctrl)
+	order := NewMockDocumentRef(ctrl)
+	inner.EXPECT().Doc("orders/o1").Return(order)
+	order.EXPECT().Path().Return(testDocsRoot + "orders/o1").AnyTimes()
+	order.EXPECT().Get(ctx).Return(nil, nil)
+
+	// the first rule exempts orders from the catch-all delay
+	client := WithLatency(inner, LatencyRule{Path: "orders/*"}, LatencyRule{Latency: FixedLatency(time.Minute)})
+	done := make(chan error, 1)
+	go func() {
+		_, err := client.Doc("orders/o1").Get(ctx)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Get(orders/o1) error = %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Get(orders/o1) was delayed by a later rule")
+	}
+}
+
+func TestWithLatency_ContextDone(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	inner := NewMockFirestoreClient(ctrl)
+	doc := NewMockDocumentRef(ctrl) // Set must not be called
+	inner.EXPECT().Doc("users/u1").Return(doc).AnyTimes()
+	doc.EXPECT().Path().Return(testDocsRoot + "users/u1").AnyTimes()
+
+	client := WithLatency(inner, LatencyRule{Latency: FixedLatency(time.Minute)})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := client.Doc("users/u1").Set(ctx, map[string]any{}); status.Code(err) != codes.DeadlineExceeded {
+		t.Errorf("Set() error = %v, want DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Set() took %v, want to return at the deadline", elapsed)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	if _, err := client.Doc("users/u1").Set(ctx, map[string]any{}); status.Code(err) != codes.Canceled {
+		t.Errorf("Set() error = %v, want Canceled", err)
+	}
+}
+
+func TestWithLatency_IteratorNext(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	inner := NewMockFirestoreClient(ctrl)
+	r := NewQueryResponder()
+	r.OnQuery(QuerySpec{Collection: "users"}).Return(&firestore.DocumentSnapshot{}, &firestore.DocumentSnapshot{})
+	inner.EXPECT().Collection("users").Return(r.Collection("users"))
+
+	client := WithLatency(inner, LatencyRule{Method: "DocumentIterator.Next", Latency: FixedLatency(40 * time.Millisecond)})
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Millisecond)
+	defer cancel()
+
+	it := client.Collection("users").Documents(ctx)
+	if _, err := it.Next(); err != nil {
+		t.Fatalf("first Next() error = %v", err)
+	}
+	if _, err := it.Next(); status.Code(err) != codes.DeadlineExceeded {
+		t.Errorf("second Next() error = %v, want DeadlineExceeded", err)
+	}
+}
+
+func TestLatencyDistributions(t *testing.T) {
+	if d := FixedLatency(time.Second)(); d != time.Second {
+		t.Errorf("FixedLatency() = %v", d)
+	}
+	for i := 0; i < 100; i++ {
+		if d := UniformLatency(10*time.Millisecond, 20*time.Millisecond)(); d < 10*time.Millisecond || d >= 20*time.Millisecond {
+			t.Fatalf("UniformLatency() = %v, want in [10ms, 20ms)", d)
+		}
+		if d := NormalLatency(time.Millisecond, time.Second)(); d < 0 {
+			t.Fatalf("NormalLatency() = %v, want >= 0", d)
+		}
+	}
+	if d := UniformLatency(time.Second, time.Second)(); d != time.Second {
+		t.Errorf("UniformLatency(empty range) = %v", d)
+	}
+}
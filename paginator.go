@@ -0,0 +1,285 @@
+package firestore
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/akmalsyrf/go-firestore-mock/internal/fsvalue"
+)
+
+// ErrInvalidPageToken is returned (wrapped) by Paginator.Page for tokens that
+// are malformed, were signed with another key, or belong to another query.
+var ErrInvalidPageToken = errors.New("go-firestore-mock: invalid page token")
+
+// Paginator pages through the results of a query with opaque page tokens.
+//
+// A token records the order-by values and the document ID of the document a
+// page ends (or starts) at, and is signed with HMAC-SHA256 so that clients
+// cannot forge or alter it. Resuming from a token rebuilds the query with
+// StartAfter (next page) or EndBefore + LimitToLast (previous page) field
+// values, so no snapshot has to be kept between requests.
+//
+// The query's order is taken from Describe(q); a final OrderBy on the
+// document ID is added as tie-breaker unless q already ends with one. Order-by
+// values must be nil, booleans, numbers, strings, timestamps or bytes.
+type Paginator struct {
+	q        Query
+	spec     QuerySpec
+	orders   []OrderSpec
+	pageSize int
+	key      []byte
+}
+
+// Page is one page of query results.
+type Page struct {
+	Docs []*firestore.DocumentSnapshot
+	// NextToken resumes after the last document; empty on the last page.
+	NextToken string
+	// PrevToken resumes before the first document; empty on the first page.
+	PrevToken string
+}
+
+// NewPaginator returns a Paginator over q with pages of pageSize documents.
+// key is the HMAC key used to sign page tokens.
+//
+// q must be a Query whose spec is known to Describe (the wrappers from
+// NewFirestoreClient and the fakes of this package). Collection group queries
+// are not supported, because document IDs cannot be turned back into cursor
+// values for them, and neither are cursors, limits and offsets, which the
+// Paginator sets itself.
+func NewPaginator(q Query, pageSize int, key []byte) (*Paginator, error) {
+	spec := Describe(q)
+	switch {
+	case spec.Collection == "":
+		return nil, fmt.Errorf("go-firestore-mock: paginator: cannot describe query of type %T", q)
+	case spec.CollectionGroup:
+		return nil, errors.New("go-firestore-mock: paginator: collection group queries are not supported")
+	case spec.StartAt != nil || spec.EndAt != nil || spec.Limit != 0:
+		return nil, errors.New("go-firestore-mock: paginator: query must not set cursors or limits")
+	case spec.Offset != 0:
+		// an offset would skip documents after every cursor, not once
+		return nil, errors.New("go-firestore-mock: paginator: query must not set an offset")
+	case pageSize <= 0:
+		return nil, fmt.Errorf("go-firestore-mock: paginator: invalid page size %d", pageSize)
+	case len(key) == 0:
+		return nil, errors.New("go-firestore-mock: paginator: empty HMAC key")
+	}
+
+	orders := spec.Orders
+	if n := len(orders); n == 0 || orders[n-1].Path != firestore.DocumentID {
+		dir := firestore.Asc
+		if n > 0 {
+			dir = orders[n-1].Dir
+		}
+		q = q.OrderBy(firestore.DocumentID, dir)
+		orders = append(append([]OrderSpec(nil), orders...), OrderSpec{Path: firestore.DocumentID, Dir: dir})
+	}
+	return &Paginator{q: q, spec: spec, orders: orders, pageSize: pageSize, key: append([]byte(nil), key...)}, nil
+}
+
+// Page returns the page identified by token: the first page for an empty
+// token, otherwise the page after (NextToken) or before (PrevToken) the
+// document the token was issued for.
+func (p *Paginator) Page(ctx context.Context, token string) (*Page, error) {
+	if token == "" {
+		docs, err := p.q.Limit(p.pageSize + 1).Documents(ctx).GetAll()
+		if err != nil {
+			return nil, err
+		}
+		page := &Page{Docs: docs}
+		if len(docs) > p.pageSize {
+			page.Docs = docs[:p.pageSize]
+			if page.NextToken, err = p.token(page.Docs[p.pageSize-1], false); err != nil {
+				return nil, err
+			}
+		}
+		return page, nil
+	}
+
+	c, err := p.decode(token)
+	if err != nil {
+		return nil, err
+	}
+	vals, err := c.cursorValues()
+	if err != nil {
+		return nil, err
+	}
+	if !c.Prev {
+		docs, err := p.q.StartAfter(vals...).Limit(p.pageSize + 1).Documents(ctx).GetAll()
+		if err != nil {
+			return nil, err
+		}
+		page := &Page{Docs: docs}
+		if len(docs) > p.pageSize {
+			page.Docs = docs[:p.pageSize]
+			if page.NextToken, err = p.token(page.Docs[p.pageSize-1], false); err != nil {
+				return nil, err
+			}
+		}
+		if len(page.Docs) > 0 {
+			if page.PrevToken, err = p.token(page.Docs[0], true); err != nil {
+				return nil, err
+			}
+		}
+		return page, nil
+	}
+
+	docs, err := p.q.EndBefore(vals...).LimitToLast(p.pageSize + 1).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+	page := &Page{Docs: docs}
+	if len(docs) > p.pageSize {
+		page.Docs = docs[1:]
+		if page.PrevToken, err = p.token(page.Docs[0], true); err != nil {
+			return nil, err
+		}
+	}
+	if n := len(page.Docs); n > 0 {
+		if page.NextToken, err = p.token(page.Docs[n-1], false); err != nil {
+			return nil, err
+		}
+	}
+	return page, nil
+}
+
+// pageCursor is the signed payload of a page token.
+type pageCursor struct {
+	// Query is a digest of the query spec, binding the token to the query.
+	Query string `json:"q"`
+	// Values are the order-by values of the document, document ID last.
+	Values []cursorValue `json:"v"`
+	// Prev is set for tokens that page backwards.
+	Prev bool `json:"p,omitempty"`
+}
+
+// cursorValue is a type-tagged order-by value.
+type cursorValue struct {
+	Type  string `json:"t"`
+	Value string `json:"v,omitempty"`
+}
+
+func (p *Paginator) token(snap *firestore.DocumentSnapshot, prev bool) (string, error) {
+	c := pageCursor{Query: p.queryDigest(), Prev: prev}
+	for _, o := range p.orders {
+		if o.Path == firestore.DocumentID {
+			c.Values = append(c.Values, cursorValue{Type: "id", Value: snap.Ref.ID})
+			continue
+		}
+		v, err := snap.DataAtPath(fsvalue.ParseFieldPath(o.Path))
+		if err != nil {
+			return "", fmt.Errorf("go-firestore-mock: paginator: order-by field %s of %s: %w", o.Path, snap.Ref.ID, err)
+		}
+		cv, err := encodeCursorValue(v)
+		if err != nil {
+			return "", fmt.Errorf("go-firestore-mock: paginator: order-by field %s of %s: %w", o.Path, snap.Ref.ID, err)
+		}
+		c.Values = append(c.Values, cv)
+	}
+	payload, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	enc := base64.RawURLEncoding
+	return enc.EncodeToString(payload) + "." + enc.EncodeToString(p.sign(payload)), nil
+}
+
+func (p *Paginator) decode(token string) (*pageCursor, error) {
+	enc := base64.RawURLEncoding
+	payloadPart, sigPart, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, fmt.Errorf("%w: malformed", ErrInvalidPageToken)
+	}
+	payload, err := enc.DecodeString(payloadPart)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed", ErrInvalidPageToken)
+	}
+	sig, err := enc.DecodeString(sigPart)
+	if err != nil || !hmac.Equal(sig, p.sign(payload)) {
+		return nil, fmt.Errorf("%w: bad signature", ErrInvalidPageToken)
+	}
+	var c pageCursor
+	if err := json.Unmarshal(payload, &c); err != nil {
+		return nil, fmt.Errorf("%w: malformed", ErrInvalidPageToken)
+	}
+	if c.Query != p.queryDigest() || len(c.Values) != len(p.orders) {
+		return nil, fmt.Errorf("%w: issued for another query", ErrInvalidPageToken)
+	}
+	return &c, nil
+}
+
+func (p *Paginator) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, p.key)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// queryDigest identifies the query (collection, filters and orders) a token
+// was issued for.
+func (p *Paginator) queryDigest() string {
+	sum := sha256.Sum256([]byte(p.spec.String()))
+	return base64.RawURLEncoding.EncodeToString(sum[:12])
+}
+
+func (c *pageCursor) cursorValues() ([]any, error) {
+	vals := make([]any, len(c.Values))
+	for i, cv := range c.Values {
+		v, err := cv.decode()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPageToken, err)
+		}
+		vals[i] = v
+	}
+	return vals, nil
+}
+
+func encodeCursorValue(v any) (cursorValue, error) {
+	switch x := v.(type) {
+	case nil:
+		return cursorValue{Type: "null"}, nil
+	case bool:
+		return cursorValue{Type: "bool", Value: strconv.FormatBool(x)}, nil
+	case int64:
+		return cursorValue{Type: "int", Value: strconv.FormatInt(x, 10)}, nil
+	case float64:
+		return cursorValue{Type: "float", Value: strconv.FormatFloat(x, 'g', -1, 64)}, nil
+	case string:
+		return cursorValue{Type: "string", Value: x}, nil
+	case time.Time:
+		return cursorValue{Type: "time", Value: x.UTC().Format(time.RFC3339Nano)}, nil
+	case []byte:
+		return cursorValue{Type: "bytes", Value: base64.StdEncoding.EncodeToString(x)}, nil
+	default:
+		return cursorValue{}, fmt.Errorf("unsupported order-by value type %T", v)
+	}
+}
+
+func (cv cursorValue) decode() (any, error) {
+	switch cv.Type {
+	case "null":
+		return nil, nil
+	case "bool":
+		return strconv.ParseBool(cv.Value)
+	case "int":
+		return strconv.ParseInt(cv.Value, 10, 64)
+	case "float":
+		return strconv.ParseFloat(cv.Value, 64)
+	case "string", "id":
+		return cv.Value, nil
+	case "time":
+		return time.Parse(time.RFC3339Nano, cv.Value)
+	case "bytes":
+		return base64.StdEncoding.DecodeString(cv.Value)
+	default:
+		return nil, fmt.Errorf("unknown cursor value type %q", cv.Type)
+	}
+}
@@ -0,0 +1,159 @@
+package firestore
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/firestore"
+)
+
+func pageIDs(p *Page) []string {
+	ids := make([]string, len(p.Docs))
+	for i, d := range p.Docs {
+		ids[i] = d.Ref.ID
+	}
+	return ids
+}
+
+func TestPaginator_ForwardAndBackward(t *testing.T) {
+	ctx := context.Background()
+	client := newSnapshotClient(t)
+	u1 := newSnapshot(t, client, "users/u1", map[string]any{"age": 20})
+	u2 := newSnapshot(t, client, "users/u2", map[string]any{"age": 30})
+	u3 := newSnapshot(t, client, "users/u3", map[string]any{"age": 40})
+	u4 := newSnapshot(t, client, "users/u4", map[string]any{"age": 50})
+
+	orders := []OrderSpec{{Path: "age", Dir: firestore.Asc}, {Path: firestore.DocumentID, Dir: firestore.Asc}}
+	r := NewQueryResponder()
+	r.OnQuery(QuerySpec{Collection: "users", Orders: orders, Limit: 3}).Return(u1, u2, u3)
+	r.OnQuery(QuerySpec{
+		Collection: "users", Orders: orders, Limit: 3,
+		StartAt: &CursorSpec{Values: []any{int64(30), "u2"}},
+	}).Return(u3, u4)
+	r.OnQuery(QuerySpec{
+		Collection: "users", Orders: orders, Limit: 3, LimitToLast: true,
+		EndAt: &CursorSpec{Values: []any{int64(40), "u3"}},
+	}).Return(u1, u2)
+
+	p, err := NewPaginator(r.Collection("users").OrderBy("age", firestore.Asc), 2, []byte("secret"))
+	if err != nil {
+		t.Fatalf("NewPaginator() error = %v", err)
+	}
+
+	first, err := p.Page(ctx, "")
+	if err != nil {
+		t.Fatalf("Page(first) error = %v", err)
+	}
+	if got := strings.Join(pageIDs(first), ","); got != "u1,u2" || first.NextToken == "" || first.PrevToken != "" {
+		t.Fatalf("Page(first) = %s next=%q prev=%q", got, first.NextToken, first.PrevToken)
+	}
+
+	second, err := p.Page(ctx, first.NextToken)
+	if err != nil {
+		t.Fatalf("Page(next) error = %v", err)
+	}
+	if got := strings.Join(pageIDs(second), ","); got != "u3,u4" || second.NextToken != "" || second.PrevToken == "" {
+		t.Fatalf("Page(next) = %s next=%q prev=%q", got, second.NextToken, second.PrevToken)
+	}
+
+	back, err := p.Page(ctx, second.PrevToken)
+	if err != nil {
+		t.Fatalf("Page(prev) error = %v", err)
+	}
+	if got := strings.Join(pageIDs(back), ","); got != "u1,u2" || back.PrevToken != "" || back.NextToken == "" {
+		t.Fatalf("Page(prev) = %s next=%q prev=%q", got, back.NextToken, back.PrevToken)
+	}
+	if unmatched := r.Unmatched(); len(unmatched) != 0 {
+		t.Errorf("Unmatched() = %v", unmatched)
+	}
+}
+
+func TestPaginator_InvalidTokens(t *testing.T) {
+	ctx := context.Background()
+	client := newSnapshotClient(t)
+	u1 := newSnapshot(t, client, "users/u1", map[string]any{"age": 20})
+	u2 := newSnapshot(t, client, "users/u2", map[string]any{"age": 30})
+
+	r := NewQueryResponder()
+	r.OnQuery(QuerySpec{
+		Collection: "users",
+		Orders:     []OrderSpec{{Path: "age", Dir: firestore.Asc}, {Path: firestore.DocumentID, Dir: firestore.Asc}},
+		Limit:      2,
+	}).Return(u1, u2)
+
+	q := r.Collection("users").OrderBy("age", firestore.Asc)
+	p, err := NewPaginator(q, 1, []byte("secret"))
+	if err != nil {
+		t.Fatalf("NewPaginator() error = %v", err)
+	}
+	first, err := p.Page(ctx, "")
+	if err != nil {
+		t.Fatalf("Page() error = %v", err)
+	}
+	payload, sig, _ := strings.Cut(first.NextToken, ".")
+
+	otherKey, _ := NewPaginator(q, 1, []byte("other"))
+	otherQuery, _ := NewPaginator(r.Collection("users").OrderBy("age", firestore.Desc), 1, []byte("secret"))
+
+	tests := []struct {
+		name  string
+		p     *Paginator
+		token string
+	}{
+		{"malformed", p, "not-a-token"},
+		{"tampered payload", p, payload + "x." + sig},
+		{"tampered signature", p, payload + "." + sig[:len(sig)-2] + "AA"},
+		{"other key", otherKey, first.NextToken},
+		{"other query", otherQuery, first.NextToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.p.Page(ctx, tt.token); !errors.Is(err, ErrInvalidPageToken) {
+				t.Errorf("Page() error = %v, want ErrInvalidPageToken", err)
+			}
+		})
+	}
+}
+
+func TestNewPaginator_Errors(t *testing.T) {
+	r := NewQueryResponder()
+	users := r.Collection("users")
+	tests := []struct {
+		name     string
+		q        Query
+		pageSize int
+		key      []byte
+	}{
+		{"unknown query", NewMockQuery(nil), 10, []byte("k")},
+		{"collection group", r.CollectionGroup("users"), 10, []byte("k")},
+		{"limit set", users.Limit(5), 10, []byte("k")},
+		{"offset set", users.Offset(5), 10, []byte("k")},
+		{"zero page size", users, 0, []byte("k")},
+		{"empty key", users, 10, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewPaginator(tt.q, tt.pageSize, tt.key); err == nil {
+				t.Error("NewPaginator() error = nil, want error")
+			}
+		})
+	}
+}
+
+func TestCursorValue_RoundTrip(t *testing.T) {
+	for _, v := range []any{nil, true, int64(-7), 1.5, "x", []byte("b")} {
+		cv, err := encodeCursorValue(v)
+		if err != nil {
+			t.Fatalf("encodeCursorValue(%v) error = %v", v, err)
+		}
+		got, err := cv.decode()
+		if err != nil || formatSpecValue(got) != formatSpecValue(v) {
+			t.Errorf("round trip of %v = %v, %v", v, got, err)
+		}
+	}
+	if _, err := encodeCursorValue(map[string]any{}); err == nil {
+		t.Error("encodeCursorValue(map) error = nil, want error")
+	}
+}
@@ -0,0 +1,399 @@
+package firestore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"reflect"
+	"strings"
+	"sync"
+
+	"cloud.google.com/go/firestore"
+	"github.com/akmalsyrf/go-firestore-mock/internal/fsvalue"
+)
+
+// QueryResponder serves canned results for queries built through the Query
+// interface. Its collections record the chain of Where / OrderBy / Limit /
+// cursor calls and, when Documents is called, look up the response registered
+// for the resulting QuerySpec:
+//
+//	r := NewQueryResponder()
+//	r.OnQuery(QuerySpec{
+//		Collection: "users",
+//		Filters:    []FilterSpec{{Path: "status", Op: "==", Value: "active"}},
+//		Limit:      10,
+//	}).Return(snap1, snap2)
+//
+//	repo := NewUserRepo(r.Collection("users"))
+//
+// Specs are compared with reflect.DeepEqual, so filter values must have the
+// same Go type as the ones passed to Where. Queries that match no registered
+// spec fail with an *UnmatchedQueryError describing the closest registered
+// spec; they are also listed by Unmatched.
+//
+// Only the query side of CollectionRef is scripted: Doc and NewDoc return
+// references whose reads and writes fail with an error (their Collection is
+// scripted by the same QueryResponder), Add returns an error, and Parent and
+// Reference return nil.
+type QueryResponder struct {
+	mu        sync.Mutex
+	responses []*QueryResponse
+	unmatched []QuerySpec
+}
+
+// QueryResponse is the canned result registered for a QuerySpec.
+type QueryResponse struct {
+	r     *QueryResponder
+	spec  QuerySpec
+	docs  []*firestore.DocumentSnapshot
+	err   error
+	calls int
+}
+
+// NewQueryResponder returns a QueryResponder with no registered responses.
+func NewQueryResponder() *QueryResponder {
+	return &QueryResponder{}
+}
+
+// OnQuery registers a response for queries described by spec. Responses are
+// matched in registration order; the first one whose spec equals the query
+// spec wins. The response returns no documents until Return or ReturnError is
+// called.
+func (r *QueryResponder) OnQuery(spec QuerySpec) *QueryResponse {
+	resp := &QueryResponse{r: r, spec: spec.clone()}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.responses = append(r.responses, resp)
+	return resp
+}
+
+// Return sets the documents returned, in order, by matching queries.
+func (resp *QueryResponse) Return(docs ...*firestore.DocumentSnapshot) *QueryResponse {
+	resp.r.mu.Lock()
+	defer resp.r.mu.Unlock()
+	resp.docs = docs
+	resp.err = nil
+	return resp
+}
+
+// ReturnError makes matching queries fail with err after yielding no documents.
+func (resp *QueryResponse) ReturnError(err error) *QueryResponse {
+	resp.r.mu.Lock()
+	defer resp.r.mu.Unlock()
+	resp.docs = nil
+	resp.err = err
+	return resp
+}
+
+// Calls returns the number of times the response was served.
+func (resp *QueryResponse) Calls() int {
+	resp.r.mu.Lock()
+	defer resp.r.mu.Unlock()
+	return resp.calls
+}
+
+// Collection returns a scripted CollectionRef for the collection at path
+// (relative to the database root, e.g. "users" or "users/u1/posts").
+func (r *QueryResponder) Collection(path string) CollectionRef {
+	return &scriptedCollection{scriptedQuery: scriptedQuery{r: r, spec: QuerySpec{Collection: path}}}
+}
+
+// CollectionGroup returns a scripted Query over all collections with the
+// given ID.
+func (r *QueryResponder) CollectionGroup(collectionID string) Query {
+	return &scriptedQuery{r: r, spec: QuerySpec{Collection: collectionID, CollectionGroup: true}}
+}
+
+// Unmatched returns the specs of the queries that matched no registered
+// response, in the order they were run.
+func (r *QueryResponder) Unmatched() []QuerySpec {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]QuerySpec, len(r.unmatched))
+	for i, s := range r.unmatched {
+		out[i] = s.clone()
+	}
+	return out
+}
+
+// respond returns the documents registered for spec.
+func (r *QueryResponder) respond(spec QuerySpec) ([]*firestore.DocumentSnapshot, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, resp := range r.responses {
+		if reflect.DeepEqual(resp.spec, spec) {
+			resp.calls++
+			return resp.docs, resp.err
+		}
+	}
+	r.unmatched = append(r.unmatched, spec)
+
+	uerr := &UnmatchedQueryError{Spec: spec}
+	best := -1
+	for _, resp := range r.responses {
+		diff := diffQuerySpecs(spec, resp.spec)
+		if best < 0 || len(diff) < best {
+			best = len(diff)
+			closest := resp.spec.clone()
+			uerr.Closest = &closest
+			uerr.Diff = diff
+		}
+	}
+	return nil, uerr
+}
+
+// UnmatchedQueryError is returned by a QueryResponder for a query whose spec
+// matches no registered response.
+type UnmatchedQueryError struct {
+	// Spec is the spec of the query that was run.
+	Spec QuerySpec
+	// Closest is the registered spec with the fewest differences, or nil if
+	// no response is registered.
+	Closest *QuerySpec
+	// Diff lists the differences between Spec (got) and Closest (want).
+	Diff []string
+}
+
+func (e *UnmatchedQueryError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "go-firestore-mock: no response registered for query %s", e.Spec)
+	if e.Closest == nil {
+		return b.String()
+	}
+	fmt.Fprintf(&b, "\nclosest registered query: %s", e.Closest)
+	for _, line := range e.Diff {
+		b.WriteString("\n  ")
+		b.WriteString(line)
+	}
+	return b.String()
+}
+
+// scriptedQuery is the Query implementation handed out by QueryResponder.
+type scriptedQuery struct {
+	r    *QueryResponder
+	spec QuerySpec
+}
+
+func (q *scriptedQuery) with(spec QuerySpec) Query {
+	return &scriptedQuery{r: q.r, spec: spec}
+}
+
+func (q *scriptedQuery) Where(path string, op string, value any) Query {
+	return q.with(q.spec.where(path, op, value))
+}
+
+func (q *scriptedQuery) WherePath(fp firestore.FieldPath, op string, value any) Query {
+	return q.with(q.spec.where(fsvalue.FieldPathString(fp), op, value))
+}
+
+func (q *scriptedQuery) WhereEntity(ef firestore.EntityFilter) Query {
+	return q.with(q.spec.whereEntity(ef))
+}
+
+func (q *scriptedQuery) OrderBy(path string, dir firestore.Direction) Query {
+	return q.with(q.spec.orderBy(path, dir))
+}
+
+func (q *scriptedQuery) OrderByPath(fp firestore.FieldPath, dir firestore.Direction) Query {
+	return q.with(q.spec.orderBy(fsvalue.FieldPathString(fp), dir))
+}
+
+func (q *scriptedQuery) Limit(n int) Query {
+	return q.with(q.spec.limit(n, false))
+}
+
+func (q *scriptedQuery) LimitToLast(n int) Query {
+	return q.with(q.spec.limit(n, true))
+}
+
+func (q *scriptedQuery) Offset(n int) Query {
+	return q.with(q.spec.offset(n))
+}
+
+func (q *scriptedQuery) StartAt(docSnapshotOrFieldValues ...any) Query {
+	return q.with(q.spec.startAt(docSnapshotOrFieldValues, true))
+}
+
+func (q *scriptedQuery) StartAfter(docSnapshotOrFieldValues ...any) Query {
+	return q.with(q.spec.startAt(docSnapshotOrFieldValues, false))
+}
+
+func (q *scriptedQuery) EndAt(docSnapshotOrFieldValues ...any) Query {
+	return q.with(q.spec.endAt(docSnapshotOrFieldValues, true))
+}
+
+func (q *scriptedQuery) EndBefore(docSnapshotOrFieldValues ...any) Query {
+	return q.with(q.spec.endAt(docSnapshotOrFieldValues, false))
+}
+
+func (q *scriptedQuery) Select(paths ...string) Query {
+	return q.with(q.spec.selectPaths(paths))
+}
+
+func (q *scriptedQuery) SelectPaths(fieldPaths ...firestore.FieldPath) Query {
+	return q.with(q.spec.selectPaths(fieldPathStrings(fieldPaths)))
+}
+
+func (q *scriptedQuery) Documents(ctx context.Context) DocumentIterator {
+	docs, err := q.r.respond(q.spec)
+	return NewDocumentIteratorFromSlice(docs, err)
+}
+
+// Snapshots is not supported: *firestore.QuerySnapshot cannot be built
+// outside the SDK. The returned iterator fails on the first Next.
+func (q *scriptedQuery) Snapshots(ctx context.Context) QuerySnapshotIterator {
+	return &errQuerySnapshotIterator{err: errors.New("go-firestore-mock: QueryResponder does not support Snapshots")}
+}
+
+// NewAggregationQuery returns an aggregation whose counts are the number of
+// documents registered for the query.
+func (q *scriptedQuery) NewAggregationQuery() AggregationQuery {
+	return &scriptedAggregationQuery{q: q}
+}
+
+func (q *scriptedQuery) querySpec() QuerySpec {
+	return q.spec.clone()
+}
+
+// scriptedCollection is the CollectionRef implementation handed out by
+// QueryResponder.Collection.
+type scriptedCollection struct {
+	scriptedQuery
+}
+
+func (c *scriptedCollection) Doc(id string) DocumentRef {
+	return &unsupportedDoc{r: c.r, path: path.Join(c.spec.Collection, id)}
+}
+
+func (c *scriptedCollection) Add(ctx context.Context, data any) (*firestore.DocumentRef, *firestore.WriteResult, error) {
+	return nil, nil, errors.New("go-firestore-mock: QueryResponder collections do not support Add")
+}
+
+func (c *scriptedCollection) NewDoc() DocumentRef {
+	return c.Doc(randomDocumentID())
+}
+
+// DocumentRefs returns the references of the documents registered for the
+// bare collection query.
+func (c *scriptedCollection) DocumentRefs(ctx context.Context) DocumentRefIterator {
+	docs, err := c.r.respond(c.spec)
+	refs := make([]*firestore.DocumentRef, 0, len(docs))
+	for _, d := range docs {
+		refs = append(refs, d.Ref)
+	}
+	return NewDocumentRefIteratorFromSlice(refs, err)
+}
+
+func (c *scriptedCollection) Parent() DocumentRef {
+	return nil
+}
+
+func (c *scriptedCollection) Reference() *firestore.CollectionRef {
+	return nil
+}
+
+func (c *scriptedCollection) ID() string {
+	return path.Base(c.spec.Collection)
+}
+
+func (c *scriptedCollection) Path() string {
+	return c.spec.Collection
+}
+
+// unsupportedDoc is the DocumentRef handed out by the Doc and NewDoc of a
+// scriptedCollection.
+type unsupportedDoc struct {
+	r    *QueryResponder
+	path string
+}
+
+func (d *unsupportedDoc) err(method string) error {
+	return fmt.Errorf("go-firestore-mock: QueryResponder documents do not support %s (%s)", method, d.path)
+}
+
+func (d *unsupportedDoc) Set(ctx context.Context, data any, opts ...firestore.SetOption) (*firestore.WriteResult, error) {
+	return nil, d.err("Set")
+}
+
+func (d *unsupportedDoc) Get(ctx context.Context) (DocumentSnapshot, error) {
+	return nil, d.err("Get")
+}
+
+func (d *unsupportedDoc) Delete(ctx context.Context, opts ...firestore.Precondition) (*firestore.WriteResult, error) {
+	return nil, d.err("Delete")
+}
+
+func (d *unsupportedDoc) Update(ctx context.Context, updates []firestore.Update, preconds ...firestore.Precondition) (*firestore.WriteResult, error) {
+	return nil, d.err("Update")
+}
+
+func (d *unsupportedDoc) Create(ctx context.Context, data any) (*firestore.WriteResult, error) {
+	return nil, d.err("Create")
+}
+
+func (d *unsupportedDoc) Collection(p string) CollectionRef {
+	return d.r.Collection(path.Join(d.path, p))
+}
+
+func (d *unsupportedDoc) Collections(ctx context.Context) CollectionIterator {
+	return NewCollectionIteratorFromSlice(nil, d.err("Collections"))
+}
+
+func (d *unsupportedDoc) Snapshots(ctx context.Context) DocumentSnapshotIterator {
+	return errDocumentSnapshotIterator{d.err("Snapshots")}
+}
+
+func (d *unsupportedDoc) Reference() *firestore.DocumentRef {
+	return nil
+}
+
+func (d *unsupportedDoc) ID() string {
+	return path.Base(d.path)
+}
+
+func (d *unsupportedDoc) Path() string {
+	return d.path
+}
+
+func (d *unsupportedDoc) Parent() *firestore.CollectionRef {
+	return nil
+}
+
+// errDocumentSnapshotIterator fails every Next with err.
+type errDocumentSnapshotIterator struct{ err error }
+
+func (it errDocumentSnapshotIterator) Next() (DocumentSnapshot, error) { return nil, it.err }
+
+func (it errDocumentSnapshotIterator) Stop() {}
+
+type scriptedAggregationQuery struct {
+	q       *scriptedQuery
+	aliases []string
+}
+
+func (a *scriptedAggregationQuery) WithCount(alias string) AggregationQuery {
+	return &scriptedAggregationQuery{q: a.q, aliases: append(append([]string(nil), a.aliases...), alias)}
+}
+
+func (a *scriptedAggregationQuery) Get(ctx context.Context) (AggregationResult, error) {
+	docs, err := a.q.r.respond(a.q.spec)
+	if err != nil {
+		return nil, err
+	}
+	result := firestore.AggregationResult{}
+	for _, alias := range a.aliases {
+		result[alias] = int64(len(docs))
+	}
+	return &aggregationResultWrapper{ar: &result}, nil
+}
+
+type errQuerySnapshotIterator struct {
+	err error
+}
+
+func (it *errQuerySnapshotIterator) Next() (*firestore.QuerySnapshot, error) {
+	return nil, it.err
+}
+
+func (it *errQuerySnapshotIterator) Stop() {}
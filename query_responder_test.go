@@ -0,0 +1,186 @@
+package firestore
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+)
+
+func TestQueryResponder_Documents(t *testing.T) {
+	ctx := context.Background()
+	client := newOfflineClient(t)
+	u1 := &firestore.DocumentSnapshot{Ref: client.Doc("users/u1")}
+	u2 := &firestore.DocumentSnapshot{Ref: client.Doc("users/u2")}
+
+	r := NewQueryResponder()
+	active := r.OnQuery(QuerySpec{
+		Collection: "users",
+		Filters:    []FilterSpec{{Path: "status", Op: "==", Value: "active"}},
+		Orders:     []OrderSpec{{Path: "createdAt", Dir: firestore.Desc}},
+		Limit:      10,
+	}).Return(u1, u2)
+
+	users := r.Collection("users")
+	docs, err := users.
+		Where("status", "==", "active").
+		OrderBy("createdAt", firestore.Desc).
+		Limit(10).
+		Documents(ctx).
+		GetAll()
+	if err != nil {
+		t.Fatalf("GetAll() error = %v", err)
+	}
+	if len(docs) != 2 || docs[0] != u1 || docs[1] != u2 {
+		t.Errorf("GetAll() = %v, want [u1 u2]", docs)
+	}
+	if got := active.Calls(); got != 1 {
+		t.Errorf("Calls() = %d, want 1", got)
+	}
+	if got := Describe(users.Where("a", "==", 1)).Filters[0].Path; got != "a" {
+		t.Errorf("Describe() filter path = %q, want %q", got, "a")
+	}
+}
+
+func TestQueryResponder_ReturnError(t *testing.T) {
+	wantErr := errors.New("boom")
+	r := NewQueryResponder()
+	r.OnQuery(QuerySpec{Collection: "users"}).ReturnError(wantErr)
+
+	it := r.Collection("users").Documents(context.Background())
+	if _, err := it.Next(); !errors.Is(err, wantErr) {
+		t.Errorf("Next() error = %v, want %v", err, wantErr)
+	}
+}
+
+func TestQueryResponder_Unmatched(t *testing.T) {
+	r := NewQueryResponder()
+	r.OnQuery(QuerySpec{Collection: "orders"})
+	r.OnQuery(QuerySpec{
+		Collection: "users",
+		Filters:    []FilterSpec{{Path: "status", Op: "==", Value: "active"}},
+		Limit:      20,
+	})
+
+	_, err := r.Collection("users").
+		Where("status", "==", "active").
+		Limit(10).
+		Documents(context.Background()).
+		Next()
+
+	var uerr *UnmatchedQueryError
+	if !errors.As(err, &uerr) {
+		t.Fatalf("Next() error = %v, want *UnmatchedQueryError", err)
+	}
+	if uerr.Closest == nil || uerr.Closest.Limit != 20 {
+		t.Fatalf("Closest = %v, want the users spec", uerr.Closest)
+	}
+	if want := []string{"limit: got 10, want 20"}; strings.Join(uerr.Diff, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Diff = %q, want %q", uerr.Diff, want)
+	}
+	if msg := err.Error(); !strings.Contains(msg, `closest registered query: users where status == "active" limit 20`) {
+		t.Errorf("Error() = %q, want the closest spec", msg)
+	}
+	if got := r.Unmatched(); len(got) != 1 || got[0].Limit != 10 {
+		t.Errorf("Unmatched() = %v, want the limit 10 query", got)
+	}
+}
+
+func TestQueryResponder_UnmatchedValueType(t *testing.T) {
+	r := NewQueryResponder()
+	r.OnQuery(QuerySpec{
+		Collection: "users",
+		Filters:    []FilterSpec{{Path: "age", Op: ">", Value: int64(18)}},
+	})
+
+	_, err := r.Collection("users").Where("age", ">", 18).Documents(context.Background()).Next()
+
+	var uerr *UnmatchedQueryError
+	if !errors.As(err, &uerr) {
+		t.Fatalf("Next() error = %v, want *UnmatchedQueryError", err)
+	}
+	if want := "filters[0]: got age > 18 (int), want age > 18 (int64)"; len(uerr.Diff) != 1 || uerr.Diff[0] != want {
+		t.Errorf("Diff = %q, want [%q]", uerr.Diff, want)
+	}
+}
+
+func TestQueryResponder_CollectionGroupAndCount(t *testing.T) {
+	ctx := context.Background()
+	client := newOfflineClient(t)
+
+	r := NewQueryResponder()
+	r.OnQuery(QuerySpec{Collection: "posts", CollectionGroup: true}).Return(
+		&firestore.DocumentSnapshot{Ref: client.Doc("users/u1/posts/p1")},
+		&firestore.DocumentSnapshot{Ref: client.Doc("users/u2/posts/p2")},
+	)
+
+	res, err := r.CollectionGroup("posts").NewAggregationQuery().WithCount("total").Get(ctx)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	n, err := res.Count("total")
+	if err != nil || n == nil || *n != 2 {
+		t.Errorf("Count() = %v, %v, want 2", n, err)
+	}
+}
+
+func TestQueryResponder_CollectionMetadata(t *testing.T) {
+	client := newOfflineClient(t)
+	r := NewQueryResponder()
+	r.OnQuery(QuerySpec{Collection: "users/u1/posts"}).Return(
+		&firestore.DocumentSnapshot{Ref: client.Doc("users/u1/posts/p1")},
+	)
+
+	posts := r.Collection("users/u1/posts")
+	if posts.ID() != "posts" || posts.Path() != "users/u1/posts" {
+		t.Errorf("ID(), Path() = %q, %q", posts.ID(), posts.Path())
+	}
+
+	it := posts.DocumentRefs(context.Background())
+	ref, err := it.Next()
+	if err != nil || ref.ID != "p1" {
+		t.Fatalf("Next() = %v, %v, want p1", ref, err)
+	}
+	if _, err := it.Next(); err != iterator.Done {
+		t.Errorf("Next() error = %v, want iterator.Done", err)
+	}
+}
+
+func TestQueryResponder_DocsUnsupported(t *testing.T) {
+	ctx := context.Background()
+	client := newOfflineClient(t)
+	r := NewQueryResponder()
+	r.OnQuery(QuerySpec{Collection: "users/u1/posts"}).Return(
+		&firestore.DocumentSnapshot{Ref: client.Doc("users/u1/posts/p1")},
+	)
+	users := r.Collection("users")
+
+	u1 := users.Doc("u1")
+	if u1.ID() != "u1" || u1.Path() != "users/u1" {
+		t.Errorf("Doc: ID(), Path() = %q, %q", u1.ID(), u1.Path())
+	}
+	if _, err := u1.Get(ctx); err == nil || !strings.Contains(err.Error(), "do not support Get (users/u1)") {
+		t.Errorf("Get() error = %v, want unsupported", err)
+	}
+	if _, err := u1.Set(ctx, map[string]any{}); err == nil {
+		t.Error("Set() error = nil, want unsupported")
+	}
+	if _, err := u1.Snapshots(ctx).Next(); err == nil {
+		t.Error("Snapshots().Next() error = nil, want unsupported")
+	}
+	// subcollections are scripted by the same responder
+	if docs, err := u1.Collection("posts").Documents(ctx).GetAll(); err != nil || len(docs) != 1 {
+		t.Errorf("subcollection GetAll() = %d docs, %v, want 1", len(docs), err)
+	}
+
+	fresh := users.NewDoc()
+	if len(fresh.ID()) != 20 || fresh.Path() != "users/"+fresh.ID() {
+		t.Errorf("NewDoc: ID(), Path() = %q, %q", fresh.ID(), fresh.Path())
+	}
+	if _, err := fresh.Create(ctx, map[string]any{}); err == nil {
+		t.Error("Create() error = nil, want unsupported")
+	}
+}
@@ -0,0 +1,357 @@
+package firestore
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"cloud.google.com/go/firestore"
+	"github.com/akmalsyrf/go-firestore-mock/internal/fsvalue"
+)
+
+// QuerySpec is a plain description of a query built through the Query
+// interface (Where, OrderBy, Limit, cursors, Select, ...).
+//
+// Use Describe to obtain the QuerySpec of a Query and compare it in tests
+// instead of mocking every chained call.
+type QuerySpec struct {
+	// Collection is the queried collection path relative to the database root
+	// (e.g. "users" or "users/u1/posts"), or the collection ID for collection
+	// group queries.
+	Collection string
+	// CollectionGroup reports whether the query spans all collections with
+	// the ID in Collection (FirestoreClient.CollectionGroup).
+	CollectionGroup bool
+	// Filters are the Where / WherePath / WhereEntity clauses in call order.
+	Filters []FilterSpec
+	// Orders are the OrderBy / OrderByPath clauses in call order.
+	Orders []OrderSpec
+	// StartAt and EndAt are the query cursors; nil if not set.
+	StartAt *CursorSpec
+	EndAt   *CursorSpec
+	// Limit is the value passed to Limit or LimitToLast; zero means no limit.
+	Limit int
+	// LimitToLast reports whether Limit was set through LimitToLast.
+	LimitToLast bool
+	// Offset is the number of results to skip; zero means no offset.
+	Offset int
+	// Select is the projection; nil means all fields. Select() with no paths
+	// is recorded as []string{firestore.DocumentID}, as the SDK does.
+	Select []string
+}
+
+// FilterSpec describes a single filter of a QuerySpec.
+//
+// Property filters (Where, WherePath, and WhereEntity with a PropertyFilter or
+// PropertyPathFilter) set Path, Op and Value. Composite filters passed to
+// WhereEntity (AndFilter, OrFilter) are kept as is in Entity.
+type FilterSpec struct {
+	Path   string
+	Op     string
+	Value  any
+	Entity firestore.EntityFilter
+}
+
+// OrderSpec describes a single OrderBy clause of a QuerySpec.
+type OrderSpec struct {
+	Path string
+	Dir  firestore.Direction
+}
+
+// CursorSpec describes a query cursor. Values holds the arguments passed to
+// StartAt / StartAfter / EndAt / EndBefore (field values or a single
+// *firestore.DocumentSnapshot). Inclusive is true for StartAt and EndAt.
+type CursorSpec struct {
+	Values    []any
+	Inclusive bool
+}
+
+// Describe returns the QuerySpec of q.
+//
+// It works for the wrappers produced by NewFirestoreClient (queries and
+// collection references) and for the fakes in this package. For other Query
+// implementations (e.g. gomock mocks) it returns the zero QuerySpec.
+func Describe(q Query) QuerySpec {
+	if d, ok := q.(querySpecer); ok {
+		return d.querySpec()
+	}
+	return QuerySpec{}
+}
+
+// querySpecer is implemented by Query values that know their QuerySpec.
+type querySpecer interface {
+	querySpec() QuerySpec
+}
+
+// String renders the spec in a compact, human-readable form, e.g.
+//
+//	users where status == "active" order by createdAt desc limit 10
+func (s QuerySpec) String() string {
+	var b strings.Builder
+	if s.CollectionGroup {
+		b.WriteString("collection group ")
+	}
+	if s.Collection == "" {
+		b.WriteString("<unknown>")
+	} else {
+		b.WriteString(s.Collection)
+	}
+	for i, f := range s.Filters {
+		if i == 0 {
+			b.WriteString(" where ")
+		} else {
+			b.WriteString(" and ")
+		}
+		b.WriteString(f.String())
+	}
+	for i, o := range s.Orders {
+		if i == 0 {
+			b.WriteString(" order by ")
+		} else {
+			b.WriteString(", ")
+		}
+		b.WriteString(o.String())
+	}
+	if s.StartAt != nil {
+		if s.StartAt.Inclusive {
+			b.WriteString(" start at ")
+		} else {
+			b.WriteString(" start after ")
+		}
+		b.WriteString(formatSpecValues(s.StartAt.Values))
+	}
+	if s.EndAt != nil {
+		if s.EndAt.Inclusive {
+			b.WriteString(" end at ")
+		} else {
+			b.WriteString(" end before ")
+		}
+		b.WriteString(formatSpecValues(s.EndAt.Values))
+	}
+	if s.Limit != 0 {
+		if s.LimitToLast {
+			fmt.Fprintf(&b, " limit to last %d", s.Limit)
+		} else {
+			fmt.Fprintf(&b, " limit %d", s.Limit)
+		}
+	}
+	if s.Offset != 0 {
+		fmt.Fprintf(&b, " offset %d", s.Offset)
+	}
+	if s.Select != nil {
+		fmt.Fprintf(&b, " select %s", strings.Join(s.Select, ", "))
+	}
+	return b.String()
+}
+
+// String renders the filter as "path op value".
+func (f FilterSpec) String() string {
+	if f.Entity != nil {
+		return fmt.Sprintf("%+v", f.Entity)
+	}
+	return fmt.Sprintf("%s %s %s", f.Path, f.Op, formatSpecValue(f.Value))
+}
+
+// String renders the order as "path asc" or "path desc".
+func (o OrderSpec) String() string {
+	if o.Dir == firestore.Desc {
+		return o.Path + " desc"
+	}
+	return o.Path + " asc"
+}
+
+func formatSpecValues(vals []any) string {
+	parts := make([]string, len(vals))
+	for i, v := range vals {
+		parts[i] = formatSpecValue(v)
+	}
+	return "(" + strings.Join(parts, ", ") + ")"
+}
+
+func formatSpecValue(v any) string {
+	switch x := v.(type) {
+	case string:
+		return fmt.Sprintf("%q", x)
+	case *firestore.DocumentSnapshot:
+		if x != nil && x.Ref != nil {
+			return "snapshot(" + fsvalue.RelativePath(x.Ref.Path) + ")"
+		}
+		return "snapshot(<nil>)"
+	case *firestore.DocumentRef:
+		if x != nil {
+			return "ref(" + fsvalue.RelativePath(x.Path) + ")"
+		}
+		return "ref(<nil>)"
+	default:
+		return fmt.Sprintf("%v", x)
+	}
+}
+
+// collectionSpec returns the base QuerySpec for a collection reference.
+func collectionSpec(ref *firestore.CollectionRef) QuerySpec {
+	if ref == nil {
+		return QuerySpec{}
+	}
+	return QuerySpec{Collection: fsvalue.RelativePath(ref.Path)}
+}
+
+// clone returns a copy of s that shares no slices with it, so that chained
+// calls on a Query never modify the spec of the Query they were called on.
+func (s QuerySpec) clone() QuerySpec {
+	s.Filters = append([]FilterSpec(nil), s.Filters...)
+	s.Orders = append([]OrderSpec(nil), s.Orders...)
+	if s.Select != nil {
+		s.Select = append([]string{}, s.Select...)
+	}
+	return s
+}
+
+func (s QuerySpec) where(path, op string, value any) QuerySpec {
+	s = s.clone()
+	s.Filters = append(s.Filters, FilterSpec{Path: path, Op: op, Value: value})
+	return s
+}
+
+func (s QuerySpec) whereEntity(ef firestore.EntityFilter) QuerySpec {
+	s = s.clone()
+	switch f := ef.(type) {
+	case firestore.PropertyFilter:
+		s.Filters = append(s.Filters, FilterSpec{Path: f.Path, Op: f.Operator, Value: f.Value})
+	case firestore.PropertyPathFilter:
+		s.Filters = append(s.Filters, FilterSpec{Path: fsvalue.FieldPathString(f.Path), Op: f.Operator, Value: f.Value})
+	default:
+		s.Filters = append(s.Filters, FilterSpec{Entity: ef})
+	}
+	return s
+}
+
+func (s QuerySpec) orderBy(path string, dir firestore.Direction) QuerySpec {
+	s = s.clone()
+	s.Orders = append(s.Orders, OrderSpec{Path: path, Dir: dir})
+	return s
+}
+
+func (s QuerySpec) limit(n int, toLast bool) QuerySpec {
+	s = s.clone()
+	s.Limit = n
+	s.LimitToLast = toLast
+	return s
+}
+
+func (s QuerySpec) offset(n int) QuerySpec {
+	s = s.clone()
+	s.Offset = n
+	return s
+}
+
+func (s QuerySpec) startAt(vals []any, inclusive bool) QuerySpec {
+	s = s.clone()
+	s.StartAt = &CursorSpec{Values: append([]any(nil), vals...), Inclusive: inclusive}
+	return s
+}
+
+func (s QuerySpec) endAt(vals []any, inclusive bool) QuerySpec {
+	s = s.clone()
+	s.EndAt = &CursorSpec{Values: append([]any(nil), vals...), Inclusive: inclusive}
+	return s
+}
+
+func (s QuerySpec) selectPaths(paths []string) QuerySpec {
+	s = s.clone()
+	if len(paths) == 0 {
+		s.Select = []string{firestore.DocumentID}
+	} else {
+		s.Select = append([]string{}, paths...)
+	}
+	return s
+}
+
+func fieldPathStrings(fps []firestore.FieldPath) []string {
+	paths := make([]string, len(fps))
+	for i, fp := range fps {
+		paths[i] = fsvalue.FieldPathString(fp)
+	}
+	return paths
+}
+
+// diffQuerySpecs returns one line per component in which got differs from
+// want, e.g. `limit: got 10, want 20`. It returns nil when the specs are equal.
+func diffQuerySpecs(got, want QuerySpec) []string {
+	var lines []string
+	add := func(name string, g, w string, gv, wv any) {
+		if g == w {
+			g = fmt.Sprintf("%s (%T)", g, gv)
+			w = fmt.Sprintf("%s (%T)", w, wv)
+		}
+		lines = append(lines, fmt.Sprintf("%s: got %s, want %s", name, g, w))
+	}
+	if got.Collection != want.Collection || got.CollectionGroup != want.CollectionGroup {
+		g := QuerySpec{Collection: got.Collection, CollectionGroup: got.CollectionGroup}.String()
+		w := QuerySpec{Collection: want.Collection, CollectionGroup: want.CollectionGroup}.String()
+		add("collection", g, w, nil, nil)
+	}
+	for i := 0; i < len(got.Filters) || i < len(want.Filters); i++ {
+		name := fmt.Sprintf("filters[%d]", i)
+		switch {
+		case i >= len(want.Filters):
+			lines = append(lines, fmt.Sprintf("%s: unexpected %s", name, got.Filters[i]))
+		case i >= len(got.Filters):
+			lines = append(lines, fmt.Sprintf("%s: missing %s", name, want.Filters[i]))
+		case !reflect.DeepEqual(got.Filters[i], want.Filters[i]):
+			add(name, got.Filters[i].String(), want.Filters[i].String(), got.Filters[i].Value, want.Filters[i].Value)
+		}
+	}
+	for i := 0; i < len(got.Orders) || i < len(want.Orders); i++ {
+		name := fmt.Sprintf("orders[%d]", i)
+		switch {
+		case i >= len(want.Orders):
+			lines = append(lines, fmt.Sprintf("%s: unexpected %s", name, got.Orders[i]))
+		case i >= len(got.Orders):
+			lines = append(lines, fmt.Sprintf("%s: missing %s", name, want.Orders[i]))
+		case got.Orders[i] != want.Orders[i]:
+			add(name, got.Orders[i].String(), want.Orders[i].String(), nil, nil)
+		}
+	}
+	if !reflect.DeepEqual(got.StartAt, want.StartAt) {
+		add("start", formatCursorSpec(got.StartAt, true), formatCursorSpec(want.StartAt, true), got.StartAt, want.StartAt)
+	}
+	if !reflect.DeepEqual(got.EndAt, want.EndAt) {
+		add("end", formatCursorSpec(got.EndAt, false), formatCursorSpec(want.EndAt, false), got.EndAt, want.EndAt)
+	}
+	if got.Limit != want.Limit || got.LimitToLast != want.LimitToLast {
+		add("limit", formatLimit(got), formatLimit(want), nil, nil)
+	}
+	if got.Offset != want.Offset {
+		add("offset", fmt.Sprint(got.Offset), fmt.Sprint(want.Offset), nil, nil)
+	}
+	if !reflect.DeepEqual(got.Select, want.Select) {
+		add("select", fmt.Sprintf("%q", got.Select), fmt.Sprintf("%q", want.Select), got.Select, want.Select)
+	}
+	return lines
+}
+
+func formatCursorSpec(c *CursorSpec, start bool) string {
+	switch {
+	case c == nil:
+		return "none"
+	case start && c.Inclusive:
+		return "start at " + formatSpecValues(c.Values)
+	case start:
+		return "start after " + formatSpecValues(c.Values)
+	case c.Inclusive:
+		return "end at " + formatSpecValues(c.Values)
+	default:
+		return "end before " + formatSpecValues(c.Values)
+	}
+}
+
+func formatLimit(s QuerySpec) string {
+	switch {
+	case s.Limit == 0:
+		return "none"
+	case s.LimitToLast:
+		return fmt.Sprintf("last %d", s.Limit)
+	default:
+		return fmt.Sprint(s.Limit)
+	}
+}
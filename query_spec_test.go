@@ -0,0 +1,158 @@
+package firestore
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"cloud.google.com/go/firestore"
+)
+
+// newOfflineClient returns a real *firestore.Client pointed at an unused
+// emulator address. It never dials unless an RPC is issued, which makes it
+// suitable for building references and queries in unit tests.
+func newOfflineClient(t *testing.T) *firestore.Client {
+	t.Helper()
+	t.Setenv("FIRESTORE_EMULATOR_HOST", "127.0.0.1:1")
+	client, err := firestore.NewClient(context.Background(), "test-project")
+	if err != nil {
+		t.Fatalf("firestore.NewClient: %v", err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+	return client
+}
+
+func TestDescribe(t *testing.T) {
+	client := NewFirestoreClient(newOfflineClient(t))
+
+	tests := []struct {
+		name  string
+		query Query
+		want  QuerySpec
+	}{
+		{
+			name:  "collection",
+			query: client.Collection("users"),
+			want:  QuerySpec{Collection: "users"},
+		},
+		{
+			name:  "subcollection",
+			query: client.Doc("users/u1").Collection("posts"),
+			want:  QuerySpec{Collection: "users/u1/posts"},
+		},
+		{
+			name: "where order by limit",
+			query: client.Collection("users").
+				Where("status", "==", "active").
+				OrderBy("createdAt", firestore.Desc).
+				Limit(10),
+			want: QuerySpec{
+				Collection: "users",
+				Filters:    []FilterSpec{{Path: "status", Op: "==", Value: "active"}},
+				Orders:     []OrderSpec{{Path: "createdAt", Dir: firestore.Desc}},
+				Limit:      10,
+			},
+		},
+		{
+			name: "paths, entity filters and projection",
+			query: client.Collection("users").
+				WherePath(firestore.FieldPath{"address", "zip-code"}, "in", []string{"1", "2"}).
+				WhereEntity(firestore.PropertyFilter{Path: "age", Operator: ">=", Value: 18}).
+				OrderByPath(firestore.FieldPath{"age"}, firestore.Asc).
+				SelectPaths(firestore.FieldPath{"name"}, firestore.FieldPath{"age"}),
+			want: QuerySpec{
+				Collection: "users",
+				Filters: []FilterSpec{
+					{Path: "address.`zip-code`", Op: "in", Value: []string{"1", "2"}},
+					{Path: "age", Op: ">=", Value: 18},
+				},
+				Orders: []OrderSpec{{Path: "age", Dir: firestore.Asc}},
+				Select: []string{"name", "age"},
+			},
+		},
+		{
+			name: "cursors, limit to last and offset",
+			query: client.Collection("users").
+				OrderBy("age", firestore.Asc).
+				StartAt(10).
+				StartAfter(20).
+				EndBefore(60).
+				LimitToLast(5).
+				Offset(2),
+			want: QuerySpec{
+				Collection:  "users",
+				Orders:      []OrderSpec{{Path: "age", Dir: firestore.Asc}},
+				StartAt:     &CursorSpec{Values: []any{20}},
+				EndAt:       &CursorSpec{Values: []any{60}},
+				Limit:       5,
+				LimitToLast: true,
+				Offset:      2,
+			},
+		},
+		{
+			name:  "collection group",
+			query: client.CollectionGroup("posts").Where("published", "==", true),
+			want: QuerySpec{
+				Collection:      "posts",
+				CollectionGroup: true,
+				Filters:         []FilterSpec{{Path: "published", Op: "==", Value: true}},
+			},
+		},
+		{
+			name:  "select no fields",
+			query: client.Collection("users").Select(),
+			want:  QuerySpec{Collection: "users", Select: []string{firestore.DocumentID}},
+		},
+		{
+			name:  "unsupported implementation",
+			query: NewMockQuery(nil),
+			want:  QuerySpec{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Describe(tt.query)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Describe() =\n  %#v\nwant\n  %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDescribe_ChainDoesNotShareState(t *testing.T) {
+	client := NewFirestoreClient(newOfflineClient(t))
+
+	base := client.Collection("users").Where("status", "==", "active")
+	a := base.Where("age", ">", 18)
+	b := base.Where("age", "<", 65)
+
+	if got := len(Describe(base).Filters); got != 1 {
+		t.Errorf("base filters = %d, want 1", got)
+	}
+	if got := Describe(a).Filters[1].Op; got != ">" {
+		t.Errorf("a second filter op = %q, want %q", got, ">")
+	}
+	if got := Describe(b).Filters[1].Op; got != "<" {
+		t.Errorf("b second filter op = %q, want %q", got, "<")
+	}
+}
+
+func TestQuerySpec_String(t *testing.T) {
+	spec := QuerySpec{
+		Collection: "users",
+		Filters: []FilterSpec{
+			{Path: "status", Op: "==", Value: "active"},
+			{Path: "age", Op: ">", Value: 18},
+		},
+		Orders:  []OrderSpec{{Path: "createdAt", Dir: firestore.Desc}},
+		StartAt: &CursorSpec{Values: []any{"x"}},
+		Limit:   10,
+		Select:  []string{"name"},
+	}
+
+	want := `users where status == "active" and age > 18 order by createdAt desc start after ("x") limit 10 select name`
+	if got := spec.String(); got != want {
+		t.Errorf("String() =\n  %s\nwant\n  %s", got, want)
+	}
+}
@@ -0,0 +1,441 @@
+package firestore
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/akmalsyrf/go-firestore-mock/internal/fsvalue"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// RecordEntry is one line of a Record log: an operation made through the
+// recording client (see Op) and its outcome.
+type RecordEntry struct {
+	// Seq numbers the entries from 1, in the order the operations completed.
+	Seq int `json:"seq"`
+	// Op, Path and Call are the Method, Path and Call of the Op.
+	Op   string `json:"op"`
+	Path string `json:"path,omitempty"`
+	Call int    `json:"call"`
+	// Query is the QuerySpec of the Op in its String form.
+	Query string `json:"query,omitempty"`
+	// Args are the call arguments, encoded on a best-effort basis: document
+	// and collection references become their paths relative to the database
+	// root, structs their exported
+	// fields (named by their firestore tags), and opaque values such as
+	// SetOption or Precondition their %v form without whitespace.
+	Args json.RawMessage `json:"args,omitempty"`
+	// Result is the value returned by the call, if any.
+	Result *RecordedResult `json:"result,omitempty"`
+	// Error is the error returned by the call, if any.
+	Error *RecordedError `json:"error,omitempty"`
+}
+
+// RecordedResult is the result of a recorded operation. Only the field
+// matching the operation is set.
+type RecordedResult struct {
+	// WriteTime is the update time of a *firestore.WriteResult.
+	WriteTime *time.Time `json:"writeTime,omitempty"`
+	// WriteTimes are the update times of WriteBatch.Commit.
+	WriteTimes []time.Time `json:"writeTimes,omitempty"`
+	// Doc is a document snapshot.
+	Doc *RecordedDoc `json:"doc,omitempty"`
+	// Docs are the snapshots of FirestoreClient.GetAll, Transaction.GetAll
+	// and DocumentIterator.GetAll.
+	Docs []RecordedDoc `json:"docs,omitempty"`
+	// Ref is the full path of a document reference (DocumentRefIterator.Next
+	// and CollectionRef.Add, which also sets WriteTime).
+	Ref string `json:"ref,omitempty"`
+	// Collection is the full path of a collection reference.
+	Collection string `json:"collection,omitempty"`
+	// Counts are the counts of AggregationQuery.Get by alias.
+	Counts map[string]int64 `json:"counts,omitempty"`
+	// Size is the size of a *firestore.QuerySnapshot.
+	Size *int `json:"size,omitempty"`
+}
+
+// RecordedDoc is a recorded document snapshot.
+type RecordedDoc struct {
+	// Path is the full resource name of the document.
+	Path   string `json:"path"`
+	Exists bool   `json:"exists"`
+	// Fields are the document fields in the Firestore REST encoding, e.g.
+	// {"name": {"stringValue": "Ada"}}.
+	Fields     map[string]json.RawMessage `json:"fields,omitempty"`
+	CreateTime time.Time                  `json:"createTime,omitzero"`
+	UpdateTime time.Time                  `json:"updateTime,omitzero"`
+	ReadTime   time.Time                  `json:"readTime,omitzero"`
+}
+
+// RecordedError is a recorded error.
+type RecordedError struct {
+	// Code is the name of the gRPC status code ("NotFound"), empty for
+	// errors that are not status errors.
+	Code    string `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+	// Done is set for iterator.Done.
+	Done bool `json:"done,omitempty"`
+}
+
+// Record decorates client so that every operation made through it (see Op)
+// is logged to w as a JSON-encoded RecordEntry per line, e.g. to capture a
+// session against the emulator or a staging project and serve it back with
+// Replay. Operations are performed as usual; writes to w are serialized.
+//
+// Logging failures do not fail the operations; the first one ends the log and
+// is returned by Close of the recording client. Results are read through the
+// DocumentSnapshot and AggregationResult interfaces, so mocks returned by the
+// decorated client must expect those calls.
+func Record(client FirestoreClient, w io.Writer) FirestoreClient {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false) // keep operators such as >= in queries readable
+	r := &recorder{enc: enc}
+	return intercept(client, r.intercept)
+}
+
+// ReadRecording reads the entries of a Record log.
+func ReadRecording(r io.Reader) ([]RecordEntry, error) {
+	var entries []RecordEntry
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 64<<20)
+	for line := 1; sc.Scan(); line++ {
+		if strings.TrimSpace(sc.Text()) == "" {
+			continue
+		}
+		var e RecordEntry
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("go-firestore-mock: read recording line %d: %w", line, err)
+		}
+		entries = append(entries, e)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("go-firestore-mock: read recording: %w", err)
+	}
+	return entries, nil
+}
+
+type recorder struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+	seq int
+	err error // first logging failure
+}
+
+func (r *recorder) intercept(_ context.Context, op Op, call func() (any, error)) (any, error) {
+	res, err := call()
+
+	e := RecordEntry{Op: op.Method, Path: op.Path, Call: op.Call, Error: recordError(err)}
+	if op.Query != nil && op.Query.Collection != "" {
+		e.Query = op.Query.String()
+	}
+	args, argsErr := encodeArgs(op.Args)
+	e.Args = args
+	result, resErr := recordResult(op, res)
+	e.Result = result
+
+	r.mu.Lock()
+	r.seq++
+	e.Seq = r.seq
+	if r.err == nil {
+		if encErr := errors.Join(argsErr, resErr); encErr != nil {
+			r.err = fmt.Errorf("go-firestore-mock: record %s %s: %w", op.Method, op.Path, encErr)
+		} else if encErr := r.enc.Encode(e); encErr != nil {
+			r.err = fmt.Errorf("go-firestore-mock: record %s %s: %w", op.Method, op.Path, encErr)
+		}
+	}
+	if op.Method == "FirestoreClient.Close" && err == nil {
+		err = r.err
+	}
+	r.mu.Unlock()
+	return res, err
+}
+
+func recordError(err error) *RecordedError {
+	if err == nil {
+		return nil
+	}
+	if err == iterator.Done {
+		return &RecordedError{Done: true}
+	}
+	if s, ok := status.FromError(err); ok {
+		return &RecordedError{Code: s.Code().String(), Message: s.Message()}
+	}
+	return &RecordedError{Message: err.Error()}
+}
+
+func recordResult(op Op, res any) (*RecordedResult, error) {
+	switch v := res.(type) {
+	case *firestore.WriteResult:
+		if v != nil {
+			return &RecordedResult{WriteTime: &v.UpdateTime}, nil
+		}
+	case []*firestore.WriteResult:
+		if v != nil {
+			times := make([]time.Time, len(v))
+			for i, wr := range v {
+				if wr != nil {
+					times[i] = wr.UpdateTime
+				}
+			}
+			return &RecordedResult{WriteTimes: times}, nil
+		}
+	case *firestore.DocumentSnapshot:
+		if v != nil {
+			doc, err := recordDoc(&documentSnapshotWrapper{snap: v})
+			return &RecordedResult{Doc: doc}, err
+		}
+	case []*firestore.DocumentSnapshot:
+		if v != nil {
+			docs := make([]RecordedDoc, len(v))
+			for i, snap := range v {
+				doc, err := recordDoc(&documentSnapshotWrapper{snap: snap})
+				if err != nil {
+					return nil, err
+				}
+				docs[i] = *doc
+			}
+			return &RecordedResult{Docs: docs}, nil
+		}
+	case DocumentSnapshot:
+		if v != nil {
+			doc, err := recordDoc(v)
+			return &RecordedResult{Doc: doc}, err
+		}
+	case []DocumentSnapshot:
+		if v != nil {
+			docs := make([]RecordedDoc, len(v))
+			for i, snap := range v {
+				doc, err := recordDoc(snap)
+				if err != nil {
+					return nil, err
+				}
+				docs[i] = *doc
+			}
+			return &RecordedResult{Docs: docs}, nil
+		}
+	case *firestore.DocumentRef:
+		if v != nil {
+			return &RecordedResult{Ref: v.Path}, nil
+		}
+	case *firestore.CollectionRef:
+		if v != nil {
+			return &RecordedResult{Collection: v.Path}, nil
+		}
+	case addResult:
+		if v.Ref != nil {
+			res := &RecordedResult{Ref: v.Ref.Path}
+			if v.WriteResult != nil {
+				res.WriteTime = &v.WriteResult.UpdateTime
+			}
+			return res, nil
+		}
+	case AggregationResult:
+		if v != nil {
+			counts := map[string]int64{}
+			if len(op.Args) > 0 {
+				aliases, _ := op.Args[0].([]string)
+				for _, alias := range aliases {
+					n, err := v.Count(alias)
+					if err != nil {
+						return nil, err
+					}
+					if n != nil {
+						counts[alias] = *n
+					}
+				}
+			}
+			return &RecordedResult{Counts: counts}, nil
+		}
+	case *firestore.QuerySnapshot:
+		if v != nil {
+			return &RecordedResult{Size: &v.Size}, nil
+		}
+	}
+	return nil, nil
+}
+
+func recordDoc(snap DocumentSnapshot) (*RecordedDoc, error) {
+	doc := &RecordedDoc{
+		Exists:     snap.Exists(),
+		CreateTime: snap.CreateTime(),
+		UpdateTime: snap.UpdateTime(),
+		ReadTime:   snap.ReadTime(),
+	}
+	if ref := snap.Ref(); ref != nil {
+		doc.Path = ref.Path
+	}
+	if !doc.Exists {
+		return doc, nil
+	}
+	fields, err := toProtoFields(snap.Data())
+	if err != nil {
+		return nil, err
+	}
+	doc.Fields = make(map[string]json.RawMessage, len(fields))
+	for k, v := range fields {
+		b, err := protojson.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		doc.Fields[k] = compactJSON(b)
+	}
+	return doc, nil
+}
+
+// compactJSON removes the whitespace protojson randomly adds to its output.
+func compactJSON(b []byte) json.RawMessage {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, b); err != nil {
+		return b
+	}
+	return buf.Bytes()
+}
+
+// RecordedArg returns a gomock matcher for an argument that is encoded as
+// encoded in RecordEntry.Args (one element of the array), e.g. `"users/u1"`
+// for a reference to users/u1 or `{"name":"Ada"}` for the data of a Set. It
+// matches arguments whose Go values the log does not preserve, such as
+// structs, and is used by the code generated by cmd/fsmockgen. For variadic
+// arguments, encoded is the array of all of them.
+func RecordedArg(encoded string) gomock.Matcher {
+	return recordedArgMatcher(encoded)
+}
+
+type recordedArgMatcher string
+
+func (m recordedArgMatcher) Matches(x any) bool {
+	got, err := encodeArgs([]any{x})
+	return err == nil && jsonEqual(got, json.RawMessage("["+string(m)+"]"))
+}
+
+func (m recordedArgMatcher) String() string {
+	return "is recorded as " + string(m)
+}
+
+func (m recordedArgMatcher) Got(x any) string {
+	got, err := encodeArgs([]any{x})
+	if err != nil {
+		return fmt.Sprintf("%v (%T)", x, x)
+	}
+	return "is recorded as " + strings.TrimSuffix(strings.TrimPrefix(string(got), "["), "]")
+}
+
+// encodeArgs encodes call arguments for a RecordEntry (see RecordEntry.Args).
+func encodeArgs(args []any) (json.RawMessage, error) {
+	if len(args) == 0 {
+		return nil, nil
+	}
+	values := make([]any, len(args))
+	for i, a := range args {
+		values[i] = argValue(reflect.ValueOf(a), 0)
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(values); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSpace(buf.Bytes()), nil
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	docRefType   = reflect.TypeOf(&firestore.DocumentRef{})
+	collRefType  = reflect.TypeOf(&firestore.CollectionRef{})
+	maxArgsDepth = 32
+)
+
+// argValue converts v to a value encoding/json renders deterministically.
+func argValue(v reflect.Value, depth int) any {
+	if !v.IsValid() {
+		return nil
+	}
+	if depth > maxArgsDepth {
+		return "..."
+	}
+	switch v.Type() {
+	case timeType:
+		if v.CanInterface() {
+			return v.Interface().(time.Time).UTC().Format(time.RFC3339Nano)
+		}
+	case docRefType:
+		if v.IsNil() {
+			return nil
+		}
+		return fsvalue.RelativePath(v.Interface().(*firestore.DocumentRef).Path)
+	case collRefType:
+		if v.IsNil() {
+			return nil
+		}
+		return fsvalue.RelativePath(v.Interface().(*firestore.CollectionRef).Path)
+	}
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return argValue(v.Elem(), depth+1)
+	case reflect.Bool:
+		return v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint()
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	case reflect.String:
+		return v.String()
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8 {
+			return v.Bytes()
+		}
+		values := make([]any, v.Len())
+		for i := range values {
+			values[i] = argValue(v.Index(i), depth+1)
+		}
+		return values
+	case reflect.Map:
+		m := make(map[string]any, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			m[fmt.Sprint(argValue(iter.Key(), depth+1))] = argValue(iter.Value(), depth+1)
+		}
+		return m
+	case reflect.Struct:
+		m := map[string]any{}
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			name := f.Name
+			if tag, _, _ := strings.Cut(f.Tag.Get("firestore"), ","); tag == "-" {
+				continue
+			} else if tag != "" {
+				name = tag
+			}
+			m[name] = argValue(v.Field(i), depth+1)
+		}
+		if len(m) == 0 && v.CanInterface() {
+			// Opaque, e.g. a Precondition. Whitespace is dropped because the
+			// text form of protos it may contain varies between builds.
+			return strings.Join(strings.Fields(fmt.Sprintf("%v", v.Interface())), "")
+		}
+		return m
+	}
+	return v.Type().String()
+}
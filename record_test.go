@@ -0,0 +1,297 @@
+package firestore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// recordSession makes a fixed series of calls through client and returns
+// what the caller observed, so that recorded and replayed runs can be
+// compared.
+func recordSession(t *testing.T, client FirestoreClient) []string {
+	t.Helper()
+	ctx := context.Background()
+	var seen []string
+	see := func(format string, args ...any) { seen = append(seen, fmt.Sprintf(format, args...)) }
+
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	wr, err := client.Doc("users/u1").Set(ctx, map[string]any{"name": "Ada", "age": 36, "tags": []any{"a", "b"}, "at": at})
+	see("set: %v %v", wr != nil, err)
+
+	snap, err := client.Doc("users/u1").Get(ctx)
+	if err != nil {
+		t.Fatalf("Get(users/u1) error = %v", err)
+	}
+	see("get: %v %v %v", snap.Exists(), snap.Data(), snap.Ref().Path)
+	_, err = client.Doc("users/missing").Get(ctx)
+	see("get missing: %v", status.Code(err))
+
+	wrs, err := client.Batch().
+		Set(client.Doc("users/u2").Reference(), map[string]any{"n": 1}).
+		Delete(client.Doc("users/u1").Reference()).
+		Commit(ctx)
+	see("batch: %d %v", len(wrs), err)
+
+	snaps, err := client.GetAll(ctx, []*firestore.DocumentRef{client.Doc("users/u1").Reference(), client.Doc("users/u2").Reference()})
+	see("get all: %v", err)
+	for _, s := range snaps {
+		see("  %s exists=%v data=%v", s.Ref().ID, s.Exists(), s.Data())
+	}
+
+	err = client.RunTransaction(ctx, func(ctx context.Context, tx Transaction) error {
+		ref := client.Doc("users/u2").Reference()
+		s, err := tx.Get(ref)
+		if err != nil {
+			return err
+		}
+		n, _ := s.DataAt("n")
+		see("tx get: %v", n)
+		return tx.Update(ref, []firestore.Update{{Path: "n", Value: firestore.Increment(1)}})
+	})
+	see("tx: %v", err)
+	return seen
+}
+
+func TestRecordReplay_RoundTrip(t *testing.T) {
+	var log bytes.Buffer
+	client := Record(NewFirestoreClient(newSnapshotClient(t)), &log)
+	recorded := recordSession(t, client)
+	if err := client.Close(); err != nil {
+		t.Fatalf("Close() of recording client error = %v", err)
+	}
+	if !strings.Contains(log.String(), `"op":"DocumentRef.Set","path":"users/u1"`) {
+		t.Errorf("log does not contain the Set of users/u1:\n%s", log.String())
+	}
+
+	replay, err := Replay(bytes.NewReader(log.Bytes()))
+	if err != nil {
+		t.Fatalf("Replay() error = %v", err)
+	}
+	replayed := recordSession(t, replay)
+	if !reflect.DeepEqual(replayed, recorded) {
+		t.Errorf("replayed session observed\n%s\nrecorded session observed\n%s", strings.Join(replayed, "\n"), strings.Join(recorded, "\n"))
+	}
+	if err := replay.Close(); err != nil {
+		t.Errorf("Close() of replay client error = %v", err)
+	}
+}
+
+func TestRecordReplay_QueriesAndAggregations(t *testing.T) {
+	ctx := context.Background()
+	sc := newSnapshotClient(t)
+	r := NewQueryResponder()
+	adults := QuerySpec{Collection: "users", Filters: []FilterSpec{{Path: "age", Op: ">=", Value: 18}}}
+	r.OnQuery(adults).Return(
+		newSnapshot(t, sc, "users/u1", map[string]any{"age": 36}),
+		newSnapshot(t, sc, "users/u2", map[string]any{"age": 20}),
+	)
+	ctrl := gomock.NewController(t)
+	inner := NewMockFirestoreClient(ctrl)
+	inner.EXPECT().Collection("users").Return(r.Collection("users")).AnyTimes()
+
+	session := func(client FirestoreClient) (ids []string, count int64) {
+		q := client.Collection("users").Where("age", ">=", 18)
+		docs, err := q.Documents(ctx).GetAll()
+		if err != nil {
+			t.Fatalf("GetAll() error = %v", err)
+		}
+		for _, d := range docs {
+			ids = append(ids, d.Ref.ID)
+		}
+		res, err := q.NewAggregationQuery().WithCount("n").Get(ctx)
+		if err != nil {
+			t.Fatalf("aggregation Get() error = %v", err)
+		}
+		n, err := res.Count("n")
+		if err != nil {
+			t.Fatalf("Count() error = %v", err)
+		}
+		return ids, *n
+	}
+
+	var log bytes.Buffer
+	wantIDs, wantCount := session(Record(inner, &log))
+	if !strings.Contains(log.String(), `"query":"users where age >= 18"`) {
+		t.Errorf("log does not contain the query spec:\n%s", log.String())
+	}
+
+	replay, err := Replay(&log)
+	if err != nil {
+		t.Fatalf("Replay() error = %v", err)
+	}
+	ids, count := session(replay)
+	if !reflect.DeepEqual(ids, wantIDs) || count != wantCount {
+		t.Errorf("replay = %v, %d, want %v, %d", ids, count, wantIDs, wantCount)
+	}
+
+	// a different query diverges
+	_, err = replay.Collection("users").Where("age", ">=", 21).Documents(ctx).Next()
+	if !errors.Is(err, ErrReplayDivergence) {
+		t.Errorf("Next() of another query error = %v, want ErrReplayDivergence", err)
+	}
+}
+
+func TestReplay_Divergence(t *testing.T) {
+	ctx := context.Background()
+	var log bytes.Buffer
+	client := Record(NewFirestoreClient(newSnapshotClient(t)), &log)
+	for _, id := range []string{"u1", "u2"} {
+		if _, err := client.Doc("users/"+id).Set(ctx, map[string]any{"id": id}); err != nil {
+			t.Fatalf("Set(%s) error = %v", id, err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		run  func(c FirestoreClient) error
+	}{
+		{
+			name: "path",
+			run: func(c FirestoreClient) error {
+				_, err := c.Doc("users/u2").Set(ctx, map[string]any{"id": "u2"})
+				return err
+			},
+		},
+		{
+			name: "method",
+			run: func(c FirestoreClient) error {
+				_, err := c.Doc("users/u1").Get(ctx)
+				return err
+			},
+		},
+		{
+			name: "args",
+			run: func(c FirestoreClient) error {
+				_, err := c.Doc("users/u1").Set(ctx, map[string]any{"id": "other"})
+				return err
+			},
+		},
+		{
+			name: "after the last entry",
+			run: func(c FirestoreClient) error {
+				for _, id := range []string{"u1", "u2", "u3"} {
+					if _, err := c.Doc("users/"+id).Set(ctx, map[string]any{"id": id}); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			replay, err := Replay(bytes.NewReader(log.Bytes()))
+			if err != nil {
+				t.Fatalf("Replay() error = %v", err)
+			}
+			defer replay.Close()
+			if err := tt.run(replay); !errors.Is(err, ErrReplayDivergence) {
+				t.Fatalf("error = %v, want ErrReplayDivergence", err)
+			}
+			// divergence is sticky
+			if _, err := replay.Doc("users/u1").Set(ctx, map[string]any{"id": "u1"}); !errors.Is(err, ErrReplayDivergence) {
+				t.Errorf("next call error = %v, want ErrReplayDivergence", err)
+			}
+		})
+	}
+
+	t.Run("operations left at Close", func(t *testing.T) {
+		replay, err := Replay(bytes.NewReader(log.Bytes()))
+		if err != nil {
+			t.Fatalf("Replay() error = %v", err)
+		}
+		if _, err := replay.Doc("users/u1").Set(ctx, map[string]any{"id": "u1"}); err != nil {
+			t.Fatalf("Set() error = %v", err)
+		}
+		if err := replay.Close(); !errors.Is(err, ErrReplayDivergence) {
+			t.Errorf("Close() error = %v, want ErrReplayDivergence", err)
+		}
+	})
+}
+
+func TestReplay_RecordedErrors(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	inner := NewMockFirestoreClient(ctrl)
+	doc := NewMockDocumentRef(ctrl)
+	inner.EXPECT().Doc("users/u1").Return(doc).AnyTimes()
+	doc.EXPECT().Path().Return(testDocsRoot + "users/u1").AnyTimes()
+	doc.EXPECT().Delete(ctx).Return(nil, status.Error(codes.PermissionDenied, "nope"))
+	doc.EXPECT().Create(ctx, gomock.Any()).Return(nil, errors.New("plain"))
+
+	var log bytes.Buffer
+	client := Record(inner, &log)
+	_, _ = client.Doc("users/u1").Delete(ctx)
+	_, _ = client.Doc("users/u1").Create(ctx, map[string]any{})
+
+	replay, err := Replay(&log)
+	if err != nil {
+		t.Fatalf("Replay() error = %v", err)
+	}
+	defer replay.Close()
+	if _, err := replay.Doc("users/u1").Delete(ctx); status.Code(err) != codes.PermissionDenied || status.Convert(err).Message() != "nope" {
+		t.Errorf("Delete() error = %v, want PermissionDenied nope", err)
+	}
+	if _, err := replay.Doc("users/u1").Create(ctx, map[string]any{}); err == nil || err.Error() != "plain" {
+		t.Errorf("Create() error = %v, want plain", err)
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errors.New("disk full") }
+
+func TestRecord_WriteErrorReportedByClose(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	inner := NewMockFirestoreClient(ctrl)
+	doc := NewMockDocumentRef(ctrl)
+	inner.EXPECT().Doc("users/u1").Return(doc)
+	doc.EXPECT().Path().Return(testDocsRoot + "users/u1").AnyTimes()
+	doc.EXPECT().Delete(ctx).Return(&firestore.WriteResult{}, nil)
+	inner.EXPECT().Close().Return(nil)
+
+	client := Record(inner, failingWriter{})
+	if _, err := client.Doc("users/u1").Delete(ctx); err != nil {
+		t.Errorf("Delete() error = %v, want nil", err)
+	}
+	if err := client.Close(); err == nil || !strings.Contains(err.Error(), "disk full") {
+		t.Errorf("Close() error = %v, want disk full", err)
+	}
+}
+
+func TestRecordedArg(t *testing.T) {
+	client := newOfflineClient(t)
+	tests := []struct {
+		name    string
+		encoded string
+		x       any
+		want    bool
+	}{
+		{"reference", `"users/u1"`, client.Doc("users/u1"), true},
+		{"other reference", `"users/u1"`, client.Doc("users/u2"), false},
+		{"data", `{"age":36,"name":"Ada"}`, map[string]any{"name": "Ada", "age": 36}, true},
+		{"options", `["MergeAll"]`, []firestore.SetOption{firestore.MergeAll}, true},
+		{"no options", `[]`, []firestore.SetOption{}, true},
+		{"updates", `[{"FieldPath":[],"Path":"n","Value":1}]`, []firestore.Update{{Path: "n", Value: 1}}, true},
+		{"other data", `{"name":"Ada"}`, map[string]any{"name": "Bob"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RecordedArg(tt.encoded).Matches(tt.x); got != tt.want {
+				t.Errorf("RecordedArg(%s).Matches(%v) = %v, want %v", tt.encoded, tt.x, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package firestore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"cloud.google.com/go/firestore"
+	"github.com/akmalsyrf/go-firestore-mock/internal/fsvalue"
+	"google.golang.org/api/iterator"
+)
+
+// RecursiveDeleteOptions configures RecursiveDelete. The zero value (or nil)
+// deletes everything without callbacks.
+type RecursiveDeleteOptions struct {
+	// DryRun only walks the tree: documents are reported to OnFound and
+	// listed in the result, but nothing is deleted.
+	DryRun bool
+	// OnFound, if set, is called for every document when the walk reaches
+	// it, including missing documents that only hold subcollections.
+	OnFound func(path string)
+	// OnDeleted, if set, is called once per document after its delete has
+	// completed, with the error of that delete (nil on success).
+	OnDeleted func(path string, err error)
+}
+
+// RecursiveDeleteResult reports what RecursiveDelete found and deleted.
+type RecursiveDeleteResult struct {
+	// Paths lists every document found, descendants before their parent,
+	// relative to the database root (e.g. "users/u1/orders/o1").
+	Paths []string
+	// Deleted is the number of documents deleted successfully.
+	Deleted int
+	// Errors holds the delete error of each document that failed, by path.
+	Errors map[string]error
+}
+
+// RecursiveDelete deletes ref together with all documents in its
+// subcollections, at any depth.
+//
+// It walks DocumentRef.Collections and CollectionRef.DocumentRefs (which also
+// lists missing documents that only hold subcollections) and deletes every
+// document found through a BulkWriter of client, descendants first. An error
+// while listing aborts the walk before anything is deleted; failed deletes
+// are collected per document in the result and reported together in the
+// returned error.
+//
+// Only the interfaces of this package are used, so the walk can be scripted
+// with mocks. DocumentRef.Reference must return the SDK reference passed to
+// BulkWriter.Delete.
+func RecursiveDelete(ctx context.Context, client FirestoreClient, ref DocumentRef, opts *RecursiveDeleteOptions) (*RecursiveDeleteResult, error) {
+	if opts == nil {
+		opts = &RecursiveDeleteOptions{}
+	}
+	var refs []*firestore.DocumentRef
+	if err := walkDocument(ctx, ref, opts, &refs); err != nil {
+		return nil, err
+	}
+
+	res := &RecursiveDeleteResult{Paths: make([]string, len(refs)), Errors: map[string]error{}}
+	for i, r := range refs {
+		res.Paths[i] = fsvalue.RelativePath(r.Path)
+	}
+	if opts.DryRun {
+		return res, nil
+	}
+
+	bw := client.BulkWriter(ctx)
+	writes := make([]BulkWrite, len(refs))
+	for i, r := range refs {
+		job, err := bw.Delete(r)
+		if err != nil {
+			res.Errors[res.Paths[i]] = err
+		}
+		writes[i] = BulkWrite{Path: res.Paths[i], Job: job}
+	}
+	var bwErr *BulkWriteError
+	if errors.As(EndBulkWriter(bw, writes), &bwErr) {
+		for _, f := range bwErr.Errors {
+			res.Errors[f.Path] = f.Err
+		}
+	}
+
+	for _, path := range res.Paths {
+		err := res.Errors[path]
+		if err == nil {
+			res.Deleted++
+		}
+		if opts.OnDeleted != nil {
+			opts.OnDeleted(path, err)
+		}
+	}
+	if len(res.Errors) > 0 {
+		return res, fmt.Errorf("go-firestore-mock: recursive delete: %d of %d documents failed", len(res.Errors), len(refs))
+	}
+	return res, nil
+}
+
+// walkDocument appends the descendants of ref and then ref itself to refs.
+func walkDocument(ctx context.Context, ref DocumentRef, opts *RecursiveDeleteOptions, refs *[]*firestore.DocumentRef) error {
+	if opts.OnFound != nil {
+		opts.OnFound(fsvalue.RelativePath(ref.Path()))
+	}
+	it := ref.Collections(ctx)
+	defer it.Stop()
+	for {
+		coll, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("go-firestore-mock: recursive delete: listing collections of %s: %w", ref.Path(), err)
+		}
+		if err := walkCollection(ctx, ref.Collection(coll.ID), opts, refs); err != nil {
+			return err
+		}
+	}
+	*refs = append(*refs, ref.Reference())
+	return nil
+}
+
+func walkCollection(ctx context.Context, coll CollectionRef, opts *RecursiveDeleteOptions, refs *[]*firestore.DocumentRef) error {
+	it := coll.DocumentRefs(ctx)
+	for {
+		doc, err := it.Next()
+		if err == iterator.Done {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("go-firestore-mock: recursive delete: listing documents of %s: %w", coll.Path(), err)
+		}
+		if err := walkDocument(ctx, coll.Doc(doc.ID), opts, refs); err != nil {
+			return err
+		}
+	}
+}
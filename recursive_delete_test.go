@@ -0,0 +1,124 @@
+package firestore
+
+import (
+	"context"
+	"errors"
+	"path"
+	"reflect"
+	"testing"
+
+	"cloud.google.com/go/firestore"
+	"github.com/akmalsyrf/go-firestore-mock/internal/fsvalue"
+	"go.uber.org/mock/gomock"
+)
+
+const testDocsRoot = "projects/p/databases/(default)/documents/"
+
+// mockDocTree returns a mock DocumentRef for docPath whose subcollections and
+// their documents are taken from tree, which maps document paths to
+// collection IDs and collection paths to document IDs.
+func mockDocTree(ctrl *gomock.Controller, tree map[string][]string, docPath string) *MockDocumentRef {
+	doc := NewMockDocumentRef(ctrl)
+	doc.EXPECT().Path().Return(testDocsRoot + docPath).AnyTimes()
+	doc.EXPECT().Reference().Return(&firestore.DocumentRef{ID: path.Base(docPath), Path: testDocsRoot + docPath}).AnyTimes()
+
+	var colls []*firestore.CollectionRef
+	for _, id := range tree[docPath] {
+		collPath := docPath + "/" + id
+		coll := NewMockCollectionRef(ctrl)
+		coll.EXPECT().Path().Return(testDocsRoot + collPath).AnyTimes()
+		var refs []*firestore.DocumentRef
+		for _, docID := range tree[collPath] {
+			refs = append(refs, &firestore.DocumentRef{ID: docID})
+			coll.EXPECT().Doc(docID).Return(mockDocTree(ctrl, tree, collPath+"/"+docID)).AnyTimes()
+		}
+		coll.EXPECT().DocumentRefs(gomock.Any()).Return(NewDocumentRefIteratorFromSlice(refs, nil)).AnyTimes()
+		colls = append(colls, &firestore.CollectionRef{ID: id})
+		doc.EXPECT().Collection(id).Return(coll).AnyTimes()
+	}
+	doc.EXPECT().Collections(gomock.Any()).Return(NewCollectionIteratorFromSlice(colls, nil)).AnyTimes()
+	return doc
+}
+
+var testUserTree = map[string][]string{
+	"users/u1":                 {"orders"},
+	"users/u1/orders":          {"o1", "o2"},
+	"users/u1/orders/o1":       {"items"},
+	"users/u1/orders/o1/items": {"i1"},
+}
+
+func TestRecursiveDelete(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	client := NewMockFirestoreClient(ctrl)
+	bw := NewMockBulkWriter(ctrl)
+	boom := errors.New("boom")
+
+	client.EXPECT().BulkWriter(ctx).Return(bw)
+	var deletes []string
+	bw.EXPECT().Delete(gomock.Any()).DoAndReturn(func(ref *firestore.DocumentRef, _ ...firestore.Precondition) (BulkWriterJob, error) {
+		deletes = append(deletes, fsvalue.RelativePath(ref.Path))
+		if ref.ID == "o2" {
+			return nil, boom
+		}
+		return nil, nil
+	}).Times(4)
+	bw.EXPECT().End()
+
+	var deleted []string
+	res, err := RecursiveDelete(ctx, client, mockDocTree(ctrl, testUserTree, "users/u1"), &RecursiveDeleteOptions{
+		OnDeleted: func(path string, err error) {
+			if err == nil {
+				deleted = append(deleted, path)
+			}
+		},
+	})
+	if err == nil {
+		t.Fatal("RecursiveDelete() error = nil, want error for failed delete")
+	}
+	wantPaths := []string{"users/u1/orders/o1/items/i1", "users/u1/orders/o1", "users/u1/orders/o2", "users/u1"}
+	if !reflect.DeepEqual(res.Paths, wantPaths) || !reflect.DeepEqual(deletes, wantPaths) {
+		t.Errorf("Paths = %v, deletes = %v, want %v", res.Paths, deletes, wantPaths)
+	}
+	if res.Deleted != 3 || len(res.Errors) != 1 || !errors.Is(res.Errors["users/u1/orders/o2"], boom) {
+		t.Errorf("Deleted = %d, Errors = %v, want 3 and o2: boom", res.Deleted, res.Errors)
+	}
+	if len(deleted) != 3 {
+		t.Errorf("OnDeleted successes = %v, want 3", deleted)
+	}
+}
+
+func TestRecursiveDelete_DryRun(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	client := NewMockFirestoreClient(ctrl) // BulkWriter must not be called
+
+	var found []string
+	res, err := RecursiveDelete(context.Background(), client, mockDocTree(ctrl, testUserTree, "users/u1"), &RecursiveDeleteOptions{
+		DryRun:  true,
+		OnFound: func(path string) { found = append(found, path) },
+	})
+	if err != nil {
+		t.Fatalf("RecursiveDelete() error = %v", err)
+	}
+	wantFound := []string{"users/u1", "users/u1/orders/o1", "users/u1/orders/o1/items/i1", "users/u1/orders/o2"}
+	if !reflect.DeepEqual(found, wantFound) {
+		t.Errorf("OnFound order = %v, want %v", found, wantFound)
+	}
+	if len(res.Paths) != 4 || res.Deleted != 0 {
+		t.Errorf("result = %+v, want 4 paths and nothing deleted", res)
+	}
+}
+
+func TestRecursiveDelete_ListError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	client := NewMockFirestoreClient(ctrl)
+	doc := NewMockDocumentRef(ctrl)
+	boom := errors.New("boom")
+
+	doc.EXPECT().Path().Return(testDocsRoot + "users/u1").AnyTimes()
+	doc.EXPECT().Collections(gomock.Any()).Return(NewCollectionIteratorFromSlice(nil, boom))
+
+	if _, err := RecursiveDelete(context.Background(), client, doc, nil); !errors.Is(err, boom) {
+		t.Errorf("RecursiveDelete() error = %v, want boom", err)
+	}
+}
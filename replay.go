@@ -0,0 +1,306 @@
+package firestore
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"cloud.google.com/go/firestore"
+	pb "cloud.google.com/go/firestore/apiv1/firestorepb"
+	"github.com/akmalsyrf/go-firestore-mock/internal/fsvalue"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// ErrReplayDivergence is returned (wrapped) by the operations of a Replay
+// client once they diverge from the recording.
+var ErrReplayDivergence = errors.New("go-firestore-mock: replay diverged from the recording")
+
+// Replay returns a FirestoreClient that serves the operations of a Record log
+// read from r: each operation must match the next recorded entry (method,
+// path, call, query and arguments) and gets its recorded result and error,
+// with real *firestore.DocumentSnapshot values for recorded snapshots. An
+// operation that does not match, or comes after the last entry, fails with
+// ErrReplayDivergence, as do all later ones. Close reports recorded operations
+// that were not replayed.
+//
+// Since entries are served in order, operations must be made in the recorded
+// order; sessions with concurrent operations, e.g. parallel ChunkedWriter
+// commits, cannot be replayed reliably. Errors that were not gRPC status errors
+// are replayed as plain errors with the recorded message. Transaction retries
+// caused by aborted commits of the decorated client are not recorded, and
+// QuerySnapshotIterator results cannot be replayed.
+func Replay(r io.Reader) (FirestoreClient, error) {
+	entries, err := ReadRecording(r)
+	if err != nil {
+		return nil, err
+	}
+	project, database := recordingDatabase(entries)
+	f, err := newSnapshotFactory(project, database)
+	if err != nil {
+		return nil, err
+	}
+	rp := &replayer{entries: entries, f: f}
+	return intercept(NewFirestoreClient(f.client), rp.intercept), nil
+}
+
+// recordingDatabase returns the project and database of the first full path
+// in entries, or placeholders if there is none.
+func recordingDatabase(entries []RecordEntry) (project, database string) {
+	for _, e := range entries {
+		if e.Result == nil {
+			continue
+		}
+		paths := []string{e.Result.Ref, e.Result.Collection}
+		if e.Result.Doc != nil {
+			paths = append(paths, e.Result.Doc.Path)
+		}
+		for _, d := range e.Result.Docs {
+			paths = append(paths, d.Path)
+		}
+		for _, p := range paths {
+			// projects/{project}/databases/{database}/documents/...
+			parts := strings.SplitN(p, "/", 6)
+			if len(parts) >= 5 && parts[0] == "projects" && parts[2] == "databases" && parts[4] == "documents" {
+				return parts[1], parts[3]
+			}
+		}
+	}
+	return "replay-project", firestore.DefaultDatabaseID
+}
+
+type replayer struct {
+	f *snapshotFactory
+
+	mu        sync.Mutex
+	entries   []RecordEntry
+	pos       int
+	err       error // sticky divergence
+	closeOnce sync.Once
+}
+
+func (r *replayer) intercept(ctx context.Context, op Op, call func() (any, error)) (any, error) {
+	switch op.Method {
+	case "FirestoreClient.Close":
+		return nil, r.close()
+	case "FirestoreClient.RunTransaction":
+		// Run the function, whose operations come before the transaction in
+		// the recording; the outcome is the recorded one.
+		if _, err := call(); errors.Is(err, ErrReplayDivergence) {
+			return nil, err
+		}
+	}
+	e, err := r.next(op)
+	if err != nil {
+		return nil, err
+	}
+	res, err := r.result(ctx, op, e)
+	if err != nil {
+		return nil, err
+	}
+	return res, replayError(e.Error)
+}
+
+// next consumes the entry for op.
+func (r *replayer) next(op Op) (RecordEntry, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return RecordEntry{}, r.err
+	}
+	if r.pos >= len(r.entries) {
+		r.err = fmt.Errorf("%w: %s %s after the last recorded operation", ErrReplayDivergence, op.Method, op.Path)
+		return RecordEntry{}, r.err
+	}
+	e := r.entries[r.pos]
+	if e.Op != op.Method || e.Path != op.Path || e.Call != op.Call {
+		r.err = fmt.Errorf("%w: got %s %s (call %d), recorded #%d is %s %s (call %d)",
+			ErrReplayDivergence, op.Method, op.Path, op.Call, e.Seq, e.Op, e.Path, e.Call)
+		return RecordEntry{}, r.err
+	}
+	if e.Query != "" && op.Query != nil {
+		if q := op.Query.String(); q != e.Query {
+			r.err = fmt.Errorf("%w: %s %s queries %q, recorded #%d queries %q", ErrReplayDivergence, op.Method, op.Path, q, e.Seq, e.Query)
+			return RecordEntry{}, r.err
+		}
+	}
+	args, err := encodeArgs(op.Args)
+	if err != nil {
+		return RecordEntry{}, err
+	}
+	if !jsonEqual(args, e.Args) {
+		r.err = fmt.Errorf("%w: %s %s called with %s, recorded #%d with %s", ErrReplayDivergence, op.Method, op.Path, args, e.Seq, e.Args)
+		return RecordEntry{}, r.err
+	}
+	r.pos++
+	return e, nil
+}
+
+func jsonEqual(a, b json.RawMessage) bool {
+	var ca, cb bytes.Buffer
+	if len(a) > 0 && json.Compact(&ca, a) != nil {
+		return false
+	}
+	if len(b) > 0 && json.Compact(&cb, b) != nil {
+		return false
+	}
+	return bytes.Equal(ca.Bytes(), cb.Bytes())
+}
+
+// close consumes the recorded Close, if it is next, and reports the entries
+// left over.
+func (r *replayer) close() error {
+	r.closeOnce.Do(func() { _ = r.f.Close() })
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return r.err
+	}
+	var err error
+	if r.pos < len(r.entries) && r.entries[r.pos].Op == "FirestoreClient.Close" {
+		err = replayError(r.entries[r.pos].Error)
+		r.pos++
+	}
+	if left := len(r.entries) - r.pos; left > 0 {
+		e := r.entries[r.pos]
+		return fmt.Errorf("%w: %d recorded operations not replayed, starting with #%d %s %s", ErrReplayDivergence, left, e.Seq, e.Op, e.Path)
+	}
+	return err
+}
+
+// result rebuilds the recorded result of op, typed as op's call returns it.
+func (r *replayer) result(ctx context.Context, op Op, e RecordEntry) (any, error) {
+	res := e.Result
+	if res == nil {
+		res = &RecordedResult{}
+	}
+	switch op.Method {
+	case "DocumentRef.Set", "DocumentRef.Create", "DocumentRef.Update", "DocumentRef.Delete", "BulkWriterJob.Results":
+		if res.WriteTime != nil {
+			return &firestore.WriteResult{UpdateTime: *res.WriteTime}, nil
+		}
+	case "WriteBatch.Commit":
+		if res.WriteTimes != nil {
+			wrs := make([]*firestore.WriteResult, len(res.WriteTimes))
+			for i, t := range res.WriteTimes {
+				wrs[i] = &firestore.WriteResult{UpdateTime: t}
+			}
+			return wrs, nil
+		}
+	case "DocumentRef.Get", "Transaction.Get", "DocumentSnapshotIterator.Next":
+		if res.Doc != nil {
+			snap, err := r.snapshot(ctx, res.Doc)
+			if err != nil {
+				return nil, err
+			}
+			return DocumentSnapshot(&documentSnapshotWrapper{snap: snap}), nil
+		}
+	case "DocumentIterator.Next":
+		if res.Doc != nil {
+			return r.snapshot(ctx, res.Doc)
+		}
+	case "DocumentIterator.GetAll":
+		if res.Docs != nil {
+			snaps := make([]*firestore.DocumentSnapshot, len(res.Docs))
+			for i := range res.Docs {
+				snap, err := r.snapshot(ctx, &res.Docs[i])
+				if err != nil {
+					return nil, err
+				}
+				snaps[i] = snap
+			}
+			return snaps, nil
+		}
+	case "FirestoreClient.GetAll", "Transaction.GetAll":
+		if res.Docs != nil {
+			snaps := make([]DocumentSnapshot, len(res.Docs))
+			for i := range res.Docs {
+				snap, err := r.snapshot(ctx, &res.Docs[i])
+				if err != nil {
+					return nil, err
+				}
+				snaps[i] = &documentSnapshotWrapper{snap: snap}
+			}
+			return snaps, nil
+		}
+	case "DocumentRefIterator.Next":
+		if res.Ref != "" {
+			return r.f.client.Doc(fsvalue.RelativePath(res.Ref)), nil
+		}
+	case "CollectionIterator.Next":
+		if res.Collection != "" {
+			return r.f.client.Collection(fsvalue.RelativePath(res.Collection)), nil
+		}
+	case "CollectionRef.Add":
+		if res.Ref != "" {
+			add := addResult{Ref: r.f.client.Doc(fsvalue.RelativePath(res.Ref))}
+			if res.WriteTime != nil {
+				add.WriteResult = &firestore.WriteResult{UpdateTime: *res.WriteTime}
+			}
+			return add, nil
+		}
+	case "AggregationQuery.Get":
+		if e.Error == nil {
+			ar := firestore.AggregationResult{}
+			for alias, n := range res.Counts {
+				ar[alias] = n
+			}
+			return AggregationResult(&aggregationResultWrapper{ar: &ar}), nil
+		}
+	case "QuerySnapshotIterator.Next":
+		if e.Error == nil {
+			return nil, status.Errorf(codes.Unimplemented, "go-firestore-mock: Replay cannot serve QuerySnapshotIterator results (#%d)", e.Seq)
+		}
+	}
+	return nil, nil
+}
+
+// snapshot rebuilds a recorded document snapshot.
+func (r *replayer) snapshot(ctx context.Context, doc *RecordedDoc) (*firestore.DocumentSnapshot, error) {
+	if doc.Path == "" {
+		return nil, errors.New("go-firestore-mock: recorded snapshot has no document path")
+	}
+	var fields map[string]*pb.Value
+	if doc.Exists {
+		fields = make(map[string]*pb.Value, len(doc.Fields))
+		for k, raw := range doc.Fields {
+			v := &pb.Value{}
+			if err := protojson.Unmarshal(raw, v); err != nil {
+				return nil, fmt.Errorf("go-firestore-mock: recorded field %q of %s: %w", k, doc.Path, err)
+			}
+			fields[k] = v
+		}
+	}
+	return r.f.snapshot(ctx, fsvalue.RelativePath(doc.Path), fields, doc.CreateTime, doc.UpdateTime, doc.ReadTime)
+}
+
+var codesByName = func() map[string]codes.Code {
+	m := map[string]codes.Code{}
+	for c := codes.OK; c <= codes.Unauthenticated; c++ {
+		m[c.String()] = c
+	}
+	return m
+}()
+
+func replayError(e *RecordedError) error {
+	switch {
+	case e == nil:
+		return nil
+	case e.Done:
+		return iterator.Done
+	case e.Code == "":
+		return errors.New(e.Message)
+	}
+	code, ok := codesByName[e.Code]
+	if !ok {
+		code = codes.Unknown
+	}
+	return status.Error(code, e.Message)
+}
@@ -0,0 +1,127 @@
+package firestore
+
+import (
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+)
+
+// NewDocumentIteratorFromSlice returns a DocumentIterator that yields snaps in
+// order and then err, or iterator.Done if err is nil.
+//
+// It follows the protocol of *firestore.DocumentIterator: once Next returns an
+// error (including iterator.Done) every later call returns the same error;
+// Stop is idempotent and makes later calls return iterator.Done; GetAll
+// returns the snapshots not yet consumed by Next and stops the iterator, or
+// returns (nil, err) if the iterator has already finished or failed.
+func NewDocumentIteratorFromSlice(snaps []*firestore.DocumentSnapshot, err error) DocumentIterator {
+	return &sliceDocumentIterator{sliceIterator[*firestore.DocumentSnapshot]{items: snaps, final: err}}
+}
+
+// NewDocumentRefIteratorFromSlice returns a DocumentRefIterator that yields
+// refs in order and then err, or iterator.Done if err is nil.
+//
+// It follows the protocol of *firestore.DocumentRefIterator: errors are
+// sticky and GetAll returns the refs not yet consumed by Next (nil once the
+// iterator is exhausted).
+func NewDocumentRefIteratorFromSlice(refs []*firestore.DocumentRef, err error) DocumentRefIterator {
+	return &sliceDocumentRefIterator{sliceIterator[*firestore.DocumentRef]{items: refs, final: err}}
+}
+
+// NewCollectionIteratorFromSlice returns a CollectionIterator that yields
+// colls in order and then err, or iterator.Done if err is nil.
+//
+// Errors are sticky, and Stop is idempotent and makes later calls to Next
+// return iterator.Done.
+func NewCollectionIteratorFromSlice(colls []*firestore.CollectionRef, err error) CollectionIterator {
+	return &sliceCollectionIterator{sliceIterator[*firestore.CollectionRef]{items: colls, final: err}}
+}
+
+// sliceIterator holds the state shared by the slice-backed iterators.
+type sliceIterator[T any] struct {
+	items []T
+	pos   int
+	final error // returned after the last item; iterator.Done if nil
+	err   error // sticky error, set once Next fails
+}
+
+func (it *sliceIterator[T]) next() (T, error) {
+	var zero T
+	if it.err != nil {
+		return zero, it.err
+	}
+	if it.pos < len(it.items) {
+		it.pos++
+		return it.items[it.pos-1], nil
+	}
+	it.err = it.final
+	if it.err == nil {
+		it.err = iterator.Done
+	}
+	return zero, it.err
+}
+
+func (it *sliceIterator[T]) stop() {
+	if it.err == nil {
+		it.err = iterator.Done
+	}
+}
+
+// rest drains the iterator, returning the remaining items or the first
+// error other than iterator.Done.
+func (it *sliceIterator[T]) rest() ([]T, error) {
+	var out []T
+	for {
+		item, err := it.next()
+		if err == iterator.Done {
+			return out, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, item)
+	}
+}
+
+type sliceDocumentIterator struct {
+	sliceIterator[*firestore.DocumentSnapshot]
+}
+
+func (it *sliceDocumentIterator) Next() (*firestore.DocumentSnapshot, error) {
+	return it.next()
+}
+
+func (it *sliceDocumentIterator) Stop() {
+	it.stop()
+}
+
+func (it *sliceDocumentIterator) GetAll() ([]*firestore.DocumentSnapshot, error) {
+	if it.err != nil {
+		return nil, it.err
+	}
+	defer it.stop()
+	return it.rest()
+}
+
+type sliceDocumentRefIterator struct {
+	sliceIterator[*firestore.DocumentRef]
+}
+
+func (it *sliceDocumentRefIterator) Next() (*firestore.DocumentRef, error) {
+	return it.next()
+}
+
+func (it *sliceDocumentRefIterator) GetAll() ([]*firestore.DocumentRef, error) {
+	return it.rest()
+}
+
+type sliceCollectionIterator struct {
+	sliceIterator[*firestore.CollectionRef]
+}
+
+func (it *sliceCollectionIterator) Next() (*firestore.CollectionRef, error) {
+	return it.next()
+}
+
+func (it *sliceCollectionIterator) Stop() {
+	it.stop()
+}
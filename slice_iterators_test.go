@@ -0,0 +1,134 @@
+package firestore
+
+import (
+	"errors"
+	"testing"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+)
+
+func TestNewDocumentIteratorFromSlice(t *testing.T) {
+	a := &firestore.DocumentSnapshot{}
+	b := &firestore.DocumentSnapshot{}
+	boom := errors.New("boom")
+
+	t.Run("done after last item and sticky", func(t *testing.T) {
+		it := NewDocumentIteratorFromSlice([]*firestore.DocumentSnapshot{a, b}, nil)
+		for i, want := range []*firestore.DocumentSnapshot{a, b} {
+			got, err := it.Next()
+			if err != nil || got != want {
+				t.Fatalf("Next() #%d = %p, %v, want %p, nil", i, got, err, want)
+			}
+		}
+		for i := 0; i < 2; i++ {
+			if _, err := it.Next(); err != iterator.Done {
+				t.Errorf("Next() after end = %v, want iterator.Done", err)
+			}
+		}
+	})
+
+	t.Run("sticky error", func(t *testing.T) {
+		it := NewDocumentIteratorFromSlice([]*firestore.DocumentSnapshot{a}, boom)
+		if _, err := it.Next(); err != nil {
+			t.Fatalf("Next() error = %v", err)
+		}
+		for i := 0; i < 2; i++ {
+			if _, err := it.Next(); err != boom {
+				t.Errorf("Next() = %v, want boom", err)
+			}
+		}
+		it.Stop()
+		if _, err := it.Next(); err != boom {
+			t.Errorf("Next() after Stop = %v, want boom", err)
+		}
+		if got, err := it.GetAll(); got != nil || err != boom {
+			t.Errorf("GetAll() = %v, %v, want nil, boom", got, err)
+		}
+	})
+
+	t.Run("stop is idempotent", func(t *testing.T) {
+		it := NewDocumentIteratorFromSlice([]*firestore.DocumentSnapshot{a, b}, nil)
+		it.Stop()
+		it.Stop()
+		if _, err := it.Next(); err != iterator.Done {
+			t.Errorf("Next() after Stop = %v, want iterator.Done", err)
+		}
+	})
+
+	t.Run("get all after partial next", func(t *testing.T) {
+		it := NewDocumentIteratorFromSlice([]*firestore.DocumentSnapshot{a, b}, nil)
+		if _, err := it.Next(); err != nil {
+			t.Fatalf("Next() error = %v", err)
+		}
+		got, err := it.GetAll()
+		if err != nil || len(got) != 1 || got[0] != b {
+			t.Fatalf("GetAll() = %v, %v, want [b]", got, err)
+		}
+		if got, err := it.GetAll(); got != nil || err != iterator.Done {
+			t.Errorf("second GetAll() = %v, %v, want nil, iterator.Done", got, err)
+		}
+	})
+
+	t.Run("get all with error", func(t *testing.T) {
+		it := NewDocumentIteratorFromSlice([]*firestore.DocumentSnapshot{a}, boom)
+		if got, err := it.GetAll(); got != nil || err != boom {
+			t.Errorf("GetAll() = %v, %v, want nil, boom", got, err)
+		}
+	})
+}
+
+func TestNewDocumentRefIteratorFromSlice(t *testing.T) {
+	a := &firestore.DocumentRef{ID: "a"}
+	b := &firestore.DocumentRef{ID: "b"}
+	boom := errors.New("boom")
+
+	it := NewDocumentRefIteratorFromSlice([]*firestore.DocumentRef{a, b}, nil)
+	if got, err := it.Next(); err != nil || got != a {
+		t.Fatalf("Next() = %v, %v, want a", got, err)
+	}
+	got, err := it.GetAll()
+	if err != nil || len(got) != 1 || got[0] != b {
+		t.Fatalf("GetAll() = %v, %v, want [b]", got, err)
+	}
+	if _, err := it.Next(); err != iterator.Done {
+		t.Errorf("Next() after GetAll = %v, want iterator.Done", err)
+	}
+	if got, err := it.GetAll(); got != nil || err != nil {
+		t.Errorf("GetAll() when exhausted = %v, %v, want nil, nil", got, err)
+	}
+
+	it = NewDocumentRefIteratorFromSlice(nil, boom)
+	if _, err := it.GetAll(); err != boom {
+		t.Errorf("GetAll() error = %v, want boom", err)
+	}
+	if _, err := it.Next(); err != boom {
+		t.Errorf("Next() error = %v, want sticky boom", err)
+	}
+}
+
+func TestNewCollectionIteratorFromSlice(t *testing.T) {
+	a := &firestore.CollectionRef{ID: "a"}
+	boom := errors.New("boom")
+
+	it := NewCollectionIteratorFromSlice([]*firestore.CollectionRef{a}, boom)
+	if got, err := it.Next(); err != nil || got != a {
+		t.Fatalf("Next() = %v, %v, want a", got, err)
+	}
+	if _, err := it.Next(); err != boom {
+		t.Errorf("Next() = %v, want boom", err)
+	}
+
+	it = NewCollectionIteratorFromSlice([]*firestore.CollectionRef{a}, nil)
+	it.Stop()
+	it.Stop()
+	if _, err := it.Next(); err != iterator.Done {
+		t.Errorf("Next() after Stop = %v, want iterator.Done", err)
+	}
+}
+
+func TestSliceIterators_InterfaceCompliance(t *testing.T) {
+	var _ DocumentIterator = (*sliceDocumentIterator)(nil)
+	var _ DocumentRefIterator = (*sliceDocumentRefIterator)(nil)
+	var _ CollectionIterator = (*sliceCollectionIterator)(nil)
+}
@@ -0,0 +1,211 @@
+package firestore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	pb "cloud.google.com/go/firestore/apiv1/firestorepb"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// snapshotFactory builds real *firestore.DocumentSnapshot values, which
+// cannot be constructed outside the SDK: it serves documents to a
+// *firestore.Client from an in-process gRPC backend and reads them back.
+// Writes and transactions against the client succeed without effect.
+type snapshotFactory struct {
+	client  *firestore.Client
+	srv     *grpc.Server
+	backend *factoryBackend
+	mu      sync.Mutex // serializes snapshot
+}
+
+func newSnapshotFactory(projectID, databaseID string) (*snapshotFactory, error) {
+	backend := &factoryBackend{docs: map[string]factoryDoc{}}
+	return startSnapshotFactory(projectID, databaseID, backend, backend)
+}
+
+// startSnapshotFactory serves server, which reads documents from backend, to
+// the client of a new snapshotFactory. Tests pass a server that also applies
+// writes.
+func startSnapshotFactory(projectID, databaseID string, backend *factoryBackend, server pb.FirestoreServer) (*snapshotFactory, error) {
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	pb.RegisterFirestoreServer(srv, server)
+	go func() { _ = srv.Serve(lis) }()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		srv.Stop()
+		return nil, fmt.Errorf("go-firestore-mock: dial snapshot backend: %w", err)
+	}
+	client, err := firestore.NewClientWithDatabase(context.Background(), projectID, databaseID, option.WithGRPCConn(conn))
+	if err != nil {
+		srv.Stop()
+		return nil, fmt.Errorf("go-firestore-mock: snapshot backend client: %w", err)
+	}
+	return &snapshotFactory{client: client, srv: srv, backend: backend}, nil
+}
+
+// snapshot returns the snapshot of the document at path (relative to the
+// database root) with the given fields, or of a missing document if fields
+// is nil. The times are reported as is.
+func (f *snapshotFactory) snapshot(ctx context.Context, path string, fields map[string]*pb.Value, createTime, updateTime, readTime time.Time) (*firestore.DocumentSnapshot, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	ref := f.client.Doc(path)
+	if ref == nil {
+		return nil, fmt.Errorf("go-firestore-mock: invalid document path %q", path)
+	}
+	d := factoryDoc{readTime: timestamppb.New(readTime)}
+	if fields != nil {
+		d.doc = &pb.Document{
+			Name:       ref.Path,
+			Fields:     fields,
+			CreateTime: timestamppb.New(createTime),
+			UpdateTime: timestamppb.New(updateTime),
+		}
+	}
+	f.backend.put(ref.Path, d)
+	snaps, err := f.client.GetAll(ctx, []*firestore.DocumentRef{ref})
+	if err != nil {
+		return nil, err
+	}
+	return snaps[0], nil
+}
+
+// Close stops the backend and closes its client.
+func (f *snapshotFactory) Close() error {
+	err := f.client.Close()
+	f.srv.Stop()
+	return err
+}
+
+type factoryDoc struct {
+	doc      *pb.Document // nil for a missing document
+	readTime *timestamppb.Timestamp
+}
+
+type factoryBackend struct {
+	pb.UnimplementedFirestoreServer
+
+	mu   sync.Mutex
+	docs map[string]factoryDoc
+}
+
+func (b *factoryBackend) put(name string, d factoryDoc) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.docs[name] = d
+}
+
+func (b *factoryBackend) BatchGetDocuments(req *pb.BatchGetDocumentsRequest, stream pb.Firestore_BatchGetDocumentsServer) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, name := range req.Documents {
+		d := b.docs[name]
+		resp := &pb.BatchGetDocumentsResponse{ReadTime: d.readTime}
+		if d.doc != nil {
+			resp.Result = &pb.BatchGetDocumentsResponse_Found{Found: d.doc}
+		} else {
+			resp.Result = &pb.BatchGetDocumentsResponse_Missing{Missing: name}
+		}
+		if resp.ReadTime == nil {
+			resp.ReadTime = timestamppb.Now()
+		}
+		if err := stream.Send(resp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *factoryBackend) Commit(_ context.Context, req *pb.CommitRequest) (*pb.CommitResponse, error) {
+	now := timestamppb.Now()
+	resp := &pb.CommitResponse{CommitTime: now}
+	for range req.Writes {
+		resp.WriteResults = append(resp.WriteResults, &pb.WriteResult{UpdateTime: now})
+	}
+	return resp, nil
+}
+
+func (b *factoryBackend) BeginTransaction(context.Context, *pb.BeginTransactionRequest) (*pb.BeginTransactionResponse, error) {
+	return &pb.BeginTransactionResponse{Transaction: []byte("tx")}, nil
+}
+
+func (b *factoryBackend) Rollback(context.Context, *pb.RollbackRequest) (*emptypb.Empty, error) {
+	return &emptypb.Empty{}, nil
+}
+
+var (
+	sharedFactoriesMu sync.Mutex
+	sharedFactories   = map[[2]string]*snapshotFactory{}
+)
+
+// SnapshotFromData returns a real *firestore.DocumentSnapshot of the document
+// at path holding data, or of a missing document if data is nil, e.g. to
+// return from mocked iterators or to wrap with NewDocumentSnapshot. path is a
+// full resource name ("projects/p/databases/(default)/documents/users/u1") or
+// is relative to the root of database "(default)" of project "test-project".
+//
+// data holds the types DocumentSnapshot.Data returns: nil, bool, integers,
+// floats, string, []byte, time.Time, *latlng.LatLng, *firestore.DocumentRef,
+// firestore.Vector32 / Vector64, []any and map[string]any. Snapshot times are
+// zero. Snapshots are served by an in-process backend (a gRPC server and a
+// client) per database, which is started on first use and kept until
+// CloseSnapshotBackends is called or the process exits.
+func SnapshotFromData(path string, data map[string]any) (*firestore.DocumentSnapshot, error) {
+	project, database := "test-project", firestore.DefaultDatabaseID
+	// projects/{project}/databases/{database}/documents/{path}
+	if parts := strings.SplitN(path, "/", 6); len(parts) == 6 && parts[0] == "projects" && parts[2] == "databases" && parts[4] == "documents" {
+		project, database, path = parts[1], parts[3], parts[5]
+	}
+	var fields map[string]*pb.Value
+	if data != nil {
+		var err error
+		if fields, err = toProtoFields(data); err != nil {
+			return nil, err
+		}
+	}
+
+	sharedFactoriesMu.Lock()
+	f, ok := sharedFactories[[2]string{project, database}]
+	if !ok {
+		var err error
+		if f, err = newSnapshotFactory(project, database); err != nil {
+			sharedFactoriesMu.Unlock()
+			return nil, err
+		}
+		sharedFactories[[2]string{project, database}] = f
+	}
+	sharedFactoriesMu.Unlock()
+	return f.snapshot(context.Background(), path, fields, time.Time{}, time.Time{}, time.Time{})
+}
+
+// CloseSnapshotBackends stops the backends started by SnapshotFromData, e.g.
+// from TestMain or in leak-checked tests. Snapshots they served remain valid,
+// but reading through their references fails; later SnapshotFromData calls
+// start new backends.
+func CloseSnapshotBackends() error {
+	sharedFactoriesMu.Lock()
+	defer sharedFactoriesMu.Unlock()
+	var errs []error
+	for key, f := range sharedFactories {
+		errs = append(errs, f.Close())
+		delete(sharedFactories, key)
+	}
+	return errors.Join(errs...)
+}
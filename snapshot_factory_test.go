@@ -0,0 +1,107 @@
+package firestore
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/genproto/googleapis/type/latlng"
+)
+
+func TestSnapshotFromData(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		path     string
+		data     map[string]any
+		wantPath string
+		want     map[string]any
+	}{
+		{
+			name:     "relative path",
+			path:     "users/u1",
+			data:     map[string]any{"name": "Ada", "age": 36, "score": 9.5, "at": at, "tags": []any{"a"}},
+			wantPath: "projects/test-project/databases/(default)/documents/users/u1",
+			want:     map[string]any{"name": "Ada", "age": int64(36), "score": 9.5, "at": at, "tags": []any{"a"}},
+		},
+		{
+			name:     "full path of another database",
+			path:     "projects/p2/databases/db2/documents/users/u1/posts/a",
+			data:     map[string]any{"geo": &latlng.LatLng{Latitude: 1, Longitude: 2}, "nested": map[string]any{"b": []byte("x")}},
+			wantPath: "projects/p2/databases/db2/documents/users/u1/posts/a",
+			want:     map[string]any{"geo": &latlng.LatLng{Latitude: 1, Longitude: 2}, "nested": map[string]any{"b": []byte("x")}},
+		},
+		{
+			name:     "vector",
+			path:     "items/i1",
+			data:     map[string]any{"embedding": firestore.Vector64{0.5, 1}},
+			wantPath: "projects/test-project/databases/(default)/documents/items/i1",
+			want:     map[string]any{"embedding": firestore.Vector64{0.5, 1}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			snap, err := SnapshotFromData(tt.path, tt.data)
+			if err != nil {
+				t.Fatalf("SnapshotFromData() error = %v", err)
+			}
+			if !snap.Exists() || snap.Ref.Path != tt.wantPath {
+				t.Fatalf("snapshot of %s, exists %v, want existing %s", snap.Ref.Path, snap.Exists(), tt.wantPath)
+			}
+			got := NewDocumentSnapshot(snap).Data()
+			if len(got) != len(tt.want) {
+				t.Fatalf("Data() = %v, want %v", got, tt.want)
+			}
+			for k, want := range tt.want {
+				if g, ok := got[k].(*latlng.LatLng); ok {
+					if w := want.(*latlng.LatLng); g.Latitude != w.Latitude || g.Longitude != w.Longitude {
+						t.Errorf("Data()[%q] = %v, want %v", k, g, w)
+					}
+					continue
+				}
+				if !reflect.DeepEqual(got[k], want) {
+					t.Errorf("Data()[%q] = %#v, want %#v", k, got[k], want)
+				}
+			}
+		})
+	}
+}
+
+func TestSnapshotFromData_MissingAndInvalid(t *testing.T) {
+	snap, err := SnapshotFromData("users/missing", nil)
+	if err != nil {
+		t.Fatalf("SnapshotFromData(nil) error = %v", err)
+	}
+	if snap.Exists() {
+		t.Errorf("Exists() = true, want false")
+	}
+	if _, err := SnapshotFromData("users", map[string]any{}); err == nil {
+		t.Errorf("SnapshotFromData(collection path) error = nil, want error")
+	}
+	if _, err := SnapshotFromData("users/u1", map[string]any{"ch": make(chan int)}); err == nil {
+		t.Errorf("SnapshotFromData(unsupported value) error = nil, want error")
+	}
+}
+
+func TestCloseSnapshotBackends(t *testing.T) {
+	if _, err := SnapshotFromData("users/u1", map[string]any{"a": 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := CloseSnapshotBackends(); err != nil {
+		t.Fatalf("CloseSnapshotBackends() = %v", err)
+	}
+	sharedFactoriesMu.Lock()
+	n := len(sharedFactories)
+	sharedFactoriesMu.Unlock()
+	if n != 0 {
+		t.Errorf("%d backends left after CloseSnapshotBackends", n)
+	}
+	snap, err := SnapshotFromData("users/u1", map[string]any{"a": 2})
+	if err != nil {
+		t.Fatalf("SnapshotFromData() after close error = %v", err)
+	}
+	if got := snap.Data()["a"]; got != int64(2) {
+		t.Errorf("Data()[a] = %v, want 2", got)
+	}
+}
@@ -0,0 +1,397 @@
+package firestore
+
+import (
+	"cmp"
+	"context"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	pb "cloud.google.com/go/firestore/apiv1/firestorepb"
+	"github.com/akmalsyrf/go-firestore-mock/internal/fsvalue"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// snapshotServer is the backend of a snapshotFactory that also applies
+// writes, for tests that write documents and read them back: Commit stores
+// documents (update masks, server timestamps and preconditions included),
+// ListCollectionIds and ListDocuments list them and RunQuery runs simple
+// queries over them. Transactions always begin and roll back.
+type snapshotServer struct {
+	*factoryBackend
+}
+
+func (s *snapshotServer) Commit(_ context.Context, req *pb.CommitRequest) (*pb.CommitResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := timestamppb.Now()
+	resp := &pb.CommitResponse{CommitTime: now}
+	// writes are staged so that a failed precondition applies none of them
+	staged := map[string]*pb.Document{}
+	for _, w := range req.Writes {
+		name := w.GetDelete()
+		if doc := w.GetUpdate(); doc != nil {
+			name = doc.Name
+		}
+		old, ok := staged[name]
+		if !ok {
+			old = s.docs[name].doc
+		}
+		switch p := w.CurrentDocument.GetConditionType().(type) {
+		case *pb.Precondition_Exists:
+			if p.Exists != (old != nil) {
+				if old != nil {
+					return nil, status.Errorf(codes.AlreadyExists, "document %s already exists", name)
+				}
+				return nil, status.Errorf(codes.NotFound, "no document %s", name)
+			}
+		case *pb.Precondition_UpdateTime:
+			if old == nil || !old.UpdateTime.AsTime().Equal(p.UpdateTime.AsTime()) {
+				return nil, status.Errorf(codes.FailedPrecondition, "document %s was not last updated at %v", name, p.UpdateTime.AsTime())
+			}
+		}
+		if w.GetDelete() != "" {
+			staged[name] = nil
+			resp.WriteResults = append(resp.WriteResults, &pb.WriteResult{UpdateTime: now})
+			continue
+		}
+		doc := proto.Clone(w.GetUpdate()).(*pb.Document)
+		if w.UpdateMask != nil {
+			fields := map[string]*pb.Value{}
+			if old != nil {
+				fields = proto.Clone(old).(*pb.Document).Fields
+			}
+			for _, path := range w.UpdateMask.FieldPaths {
+				setProtoField(fields, fsvalue.ParseFieldPath(path), protoField(doc.Fields, fsvalue.ParseFieldPath(path)))
+			}
+			doc.Fields = fields
+		}
+		for _, t := range w.UpdateTransforms {
+			if t.GetSetToServerValue() != pb.DocumentTransform_FieldTransform_REQUEST_TIME {
+				return nil, status.Errorf(codes.Unimplemented, "go-firestore-mock: the snapshot server does not apply transform %v", t)
+			}
+			if doc.Fields == nil {
+				doc.Fields = map[string]*pb.Value{}
+			}
+			setProtoField(doc.Fields, fsvalue.ParseFieldPath(t.FieldPath), &pb.Value{ValueType: &pb.Value_TimestampValue{TimestampValue: now}})
+		}
+		doc.CreateTime, doc.UpdateTime = now, now
+		if old != nil {
+			doc.CreateTime = old.CreateTime
+		}
+		staged[name] = doc
+		resp.WriteResults = append(resp.WriteResults, &pb.WriteResult{UpdateTime: now})
+	}
+	for name, doc := range staged {
+		if doc == nil {
+			delete(s.docs, name)
+		} else {
+			s.docs[name] = factoryDoc{doc: doc}
+		}
+	}
+	return resp, nil
+}
+
+// protoField returns the value at path in fields, or nil if there is none.
+func protoField(fields map[string]*pb.Value, path firestore.FieldPath) *pb.Value {
+	for i, seg := range path {
+		v, ok := fields[seg]
+		if !ok || i == len(path)-1 {
+			return v
+		}
+		fields = v.GetMapValue().GetFields()
+	}
+	return nil
+}
+
+// setProtoField sets the value at path in fields, creating maps on the way,
+// or deletes it if v is nil.
+func setProtoField(fields map[string]*pb.Value, path firestore.FieldPath, v *pb.Value) {
+	for _, seg := range path[:len(path)-1] {
+		m := fields[seg].GetMapValue()
+		if m == nil {
+			m = &pb.MapValue{}
+			fields[seg] = &pb.Value{ValueType: &pb.Value_MapValue{MapValue: m}}
+		}
+		if m.Fields == nil {
+			m.Fields = map[string]*pb.Value{}
+		}
+		fields = m.Fields
+	}
+	if v == nil {
+		delete(fields, path[len(path)-1])
+	} else {
+		fields[path[len(path)-1]] = v
+	}
+}
+
+// ListCollectionIds lists the IDs of the collections holding documents (at
+// any depth) directly under the parent.
+func (s *snapshotServer) ListCollectionIds(_ context.Context, req *pb.ListCollectionIdsRequest) (*pb.ListCollectionIdsResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	seen := map[string]bool{}
+	for name, d := range s.docs {
+		if rest, ok := strings.CutPrefix(name, req.Parent+"/"); ok && d.doc != nil {
+			seen[strings.SplitN(rest, "/", 2)[0]] = true
+		}
+	}
+	resp := &pb.ListCollectionIdsResponse{}
+	for id := range seen {
+		resp.CollectionIds = append(resp.CollectionIds, id)
+	}
+	sort.Strings(resp.CollectionIds)
+	return resp, nil
+}
+
+// ListDocuments lists the documents of a collection, including missing ones
+// that hold subcollections, in one page.
+func (s *snapshotServer) ListDocuments(_ context.Context, req *pb.ListDocumentsRequest) (*pb.ListDocumentsResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	coll := req.Parent + "/" + req.CollectionId + "/"
+	seen := map[string]bool{}
+	for name, d := range s.docs {
+		if rest, ok := strings.CutPrefix(name, coll); ok && d.doc != nil {
+			seen[coll+strings.SplitN(rest, "/", 2)[0]] = true
+		}
+	}
+	resp := &pb.ListDocumentsResponse{}
+	for name := range seen {
+		resp.Documents = append(resp.Documents, &pb.Document{Name: name})
+	}
+	sort.Slice(resp.Documents, func(i, j int) bool { return resp.Documents[i].Name < resp.Documents[j].Name })
+	return resp, nil
+}
+
+// RunQuery serves collection and collection group queries over the stored
+// documents, sorted by their orderings and then by name and cut to their
+// limit; documents missing an ordered field are left out. LimitToLast
+// queries arrive with reversed orderings, as the SDK sends them. Filters,
+// cursors, offsets and projections are not supported.
+func (s *snapshotServer) RunQuery(req *pb.RunQueryRequest, stream pb.Firestore_RunQueryServer) error {
+	q := req.GetStructuredQuery()
+	if q == nil || len(q.From) != 1 || q.Where != nil || q.StartAt != nil || q.EndAt != nil || q.Offset != 0 || q.Select != nil {
+		return status.Error(codes.Unimplemented, "go-firestore-mock: the snapshot server only runs queries without filters, cursors, offsets or projections")
+	}
+	from := q.From[0]
+	s.mu.Lock()
+	var docs []*pb.Document
+	for name, d := range s.docs {
+		rest, ok := strings.CutPrefix(name, req.Parent+"/")
+		if !ok || d.doc == nil {
+			continue
+		}
+		segs := strings.Split(rest, "/")
+		if segs[len(segs)-2] != from.CollectionId || (!from.AllDescendants && len(segs) != 2) {
+			continue
+		}
+		if hasOrderFields(d.doc, q.OrderBy) {
+			docs = append(docs, d.doc)
+		}
+	}
+	s.mu.Unlock()
+
+	nameDir := pb.StructuredQuery_ASCENDING
+	if n := len(q.OrderBy); n > 0 {
+		nameDir = q.OrderBy[n-1].Direction
+	}
+	sort.Slice(docs, func(i, j int) bool {
+		for _, o := range q.OrderBy {
+			c := compareProtoValues(orderValue(docs[i], o), orderValue(docs[j], o))
+			if o.Direction == pb.StructuredQuery_DESCENDING {
+				c = -c
+			}
+			if c != 0 {
+				return c < 0
+			}
+		}
+		if nameDir == pb.StructuredQuery_DESCENDING {
+			return docs[i].Name > docs[j].Name
+		}
+		return docs[i].Name < docs[j].Name
+	})
+	if q.Limit != nil && int(q.Limit.Value) < len(docs) {
+		docs = docs[:q.Limit.Value]
+	}
+
+	readTime := timestamppb.Now()
+	if len(docs) == 0 {
+		return stream.Send(&pb.RunQueryResponse{ReadTime: readTime})
+	}
+	for _, doc := range docs {
+		if err := stream.Send(&pb.RunQueryResponse{Document: doc, ReadTime: readTime}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func hasOrderFields(doc *pb.Document, orders []*pb.StructuredQuery_Order) bool {
+	for _, o := range orders {
+		if orderValue(doc, o) == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// orderValue returns the value of doc ordered on by o, or nil if doc has
+// no such field. The document name is a reference value.
+func orderValue(doc *pb.Document, o *pb.StructuredQuery_Order) *pb.Value {
+	path := o.GetField().GetFieldPath()
+	if path == firestore.DocumentID {
+		return &pb.Value{ValueType: &pb.Value_ReferenceValue{ReferenceValue: doc.Name}}
+	}
+	fields := doc.Fields
+	segs := fsvalue.ParseFieldPath(path)
+	for i, seg := range segs {
+		v, ok := fields[seg]
+		if !ok {
+			return nil
+		}
+		if i == len(segs)-1 {
+			return v
+		}
+		fields = v.GetMapValue().GetFields()
+	}
+	return nil
+}
+
+// compareProtoValues orders values of the scalar types by the Firestore
+// type order and then by value; values of other types compare equal to
+// each other.
+func compareProtoValues(a, b *pb.Value) int {
+	if c := cmp.Compare(protoTypeRank(a), protoTypeRank(b)); c != 0 {
+		return c
+	}
+	switch a.ValueType.(type) {
+	case *pb.Value_BooleanValue:
+		return cmp.Compare(boolRank(a.GetBooleanValue()), boolRank(b.GetBooleanValue()))
+	case *pb.Value_IntegerValue, *pb.Value_DoubleValue:
+		return cmp.Compare(protoNumber(a), protoNumber(b))
+	case *pb.Value_TimestampValue:
+		return a.GetTimestampValue().AsTime().Compare(b.GetTimestampValue().AsTime())
+	case *pb.Value_StringValue:
+		return strings.Compare(a.GetStringValue(), b.GetStringValue())
+	case *pb.Value_ReferenceValue:
+		return strings.Compare(a.GetReferenceValue(), b.GetReferenceValue())
+	}
+	return 0
+}
+
+func protoTypeRank(v *pb.Value) int {
+	switch v.ValueType.(type) {
+	case *pb.Value_NullValue:
+		return 0
+	case *pb.Value_BooleanValue:
+		return 1
+	case *pb.Value_IntegerValue, *pb.Value_DoubleValue:
+		return 2
+	case *pb.Value_TimestampValue:
+		return 3
+	case *pb.Value_StringValue:
+		return 4
+	case *pb.Value_ReferenceValue:
+		return 6
+	}
+	return 9
+}
+
+func boolRank(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func protoNumber(v *pb.Value) float64 {
+	if i, ok := v.ValueType.(*pb.Value_IntegerValue); ok {
+		return float64(i.IntegerValue)
+	}
+	return v.GetDoubleValue()
+}
+
+// newSnapshotClient returns a real *firestore.Client backed by a
+// snapshotServer, for tests that need snapshots with data or writes that can
+// be read back.
+func newSnapshotClient(t *testing.T) *firestore.Client {
+	t.Helper()
+	backend := &factoryBackend{docs: map[string]factoryDoc{}}
+	f, err := startSnapshotFactory("test-project", firestore.DefaultDatabaseID, backend, &snapshotServer{factoryBackend: backend})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = f.Close() })
+	return f.client
+}
+
+// newSnapshot writes data to path through client and returns the snapshot
+// read back from it.
+func newSnapshot(t *testing.T, client *firestore.Client, path string, data any) *firestore.DocumentSnapshot {
+	t.Helper()
+	ctx := context.Background()
+	ref := client.Doc(path)
+	if _, err := ref.Set(ctx, data); err != nil {
+		t.Fatalf("Set(%s): %v", path, err)
+	}
+	snap, err := ref.Get(ctx)
+	if err != nil {
+		t.Fatalf("Get(%s): %v", path, err)
+	}
+	return snap
+}
+
+func TestSnapshotServer_Commit(t *testing.T) {
+	ctx := context.Background()
+	client := newSnapshotClient(t)
+	ref := client.Doc("users/u1")
+
+	if _, err := ref.Create(ctx, map[string]any{"name": "Ann", "profile": map[string]any{"age": 30, "city": "Oslo"}}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	created, err := ref.Get(ctx)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if _, err := ref.Create(ctx, map[string]any{}); status.Code(err) != codes.AlreadyExists {
+		t.Errorf("Create(existing) error = %v, want AlreadyExists", err)
+	}
+	if _, err := client.Doc("users/u2").Update(ctx, []firestore.Update{{Path: "a", Value: 1}}); status.Code(err) != codes.NotFound {
+		t.Errorf("Update(missing) error = %v, want NotFound", err)
+	}
+	_, err = ref.Update(ctx, []firestore.Update{
+		{Path: "profile.age", Value: 31},
+		{Path: "profile.city", Value: firestore.Delete},
+		{Path: "seen", Value: firestore.ServerTimestamp},
+	})
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	snap, err := ref.Get(ctx)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	got := snap.Data()
+	if got["name"] != "Ann" || !reflect.DeepEqual(got["profile"], map[string]any{"age": int64(31)}) {
+		t.Errorf("Data() = %v, want name Ann and profile {age: 31}", got)
+	}
+	if seen, ok := got["seen"].(time.Time); !ok || !seen.Equal(snap.UpdateTime) {
+		t.Errorf("Data()[seen] = %v, want the commit time %v", got["seen"], snap.UpdateTime)
+	}
+	if !snap.CreateTime.Equal(created.CreateTime) {
+		t.Errorf("CreateTime = %v, want %v kept from Create", snap.CreateTime, created.CreateTime)
+	}
+	if _, err := ref.Update(ctx, []firestore.Update{{Path: "a", Value: 1}}, firestore.LastUpdateTime(created.UpdateTime)); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("Update(stale update time) error = %v, want FailedPrecondition", err)
+	}
+	if _, err := ref.Update(ctx, []firestore.Update{{Path: "a", Value: 1}}, firestore.LastUpdateTime(snap.UpdateTime)); err != nil {
+		t.Errorf("Update(current update time) error = %v", err)
+	}
+}
@@ -103,6 +103,8 @@ func toFirestoreQueryer(q Query) (firestore.Queryer, error) {
 		return v.q, nil
 	case *collectionRefWrapper:
 		return v.ref, nil
+	case interface{ unwrapQuery() Query }:
+		return toFirestoreQueryer(v.unwrapQuery())
 	}
 	if cr, ok := q.(CollectionRef); ok {
 		if ref := cr.Reference(); ref != nil {
@@ -0,0 +1,236 @@
+package firestore
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"reflect"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+)
+
+// TypedCollection stores values of the struct type T in a CollectionRef,
+// replacing the usual Doc(id).Get / DataTo / Set boilerplate of repositories:
+//
+//	type User struct {
+//		ID     string `firestore:"-" docid:"true"`
+//		Name   string `firestore:"name"`
+//		Status string `firestore:"status"`
+//	}
+//
+//	users := NewTypedCollection[User](client.Collection("users"))
+//	u, err := users.Get(ctx, "u1")             // u.ID == "u1"
+//	active, err := users.Query(ctx, users.Ref().Where("status", "==", "active"))
+//
+// A string field tagged `docid:"true"` (and `firestore:"-"`, so it is not
+// stored as data) is filled with the document ID on reads and selects the
+// document on writes.
+//
+// TypedCollection only uses the CollectionRef interface, so it works with the
+// wrapper returned by NewFirestoreClient as well as with mocks and fakes.
+type TypedCollection[T any] struct {
+	coll  CollectionRef
+	idIdx []int // index of the docid field, nil if T has none
+}
+
+// NewTypedCollection returns a TypedCollection over coll. T must be a struct
+// type; it panics otherwise, or if the docid field is not a string or is
+// promoted through an embedded pointer, which may be nil.
+func NewTypedCollection[T any](coll CollectionRef) *TypedCollection[T] {
+	t := reflect.TypeFor[T]()
+	if t.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("go-firestore-mock: TypedCollection type %s is not a struct", t))
+	}
+	return &TypedCollection[T]{coll: coll, idIdx: docIDField(t)}
+}
+
+// docIDField returns the index of the field of t tagged `docid:"true"`.
+func docIDField(t reflect.Type) []int {
+	for _, f := range reflect.VisibleFields(t) {
+		if f.Tag.Get("docid") != "true" {
+			continue
+		}
+		if f.Type.Kind() != reflect.String {
+			panic(fmt.Sprintf("go-firestore-mock: docid field %s.%s must be a string", t, f.Name))
+		}
+		for i, st := 0, t; i < len(f.Index)-1; i++ {
+			embedded := st.Field(f.Index[i])
+			if embedded.Type.Kind() == reflect.Pointer {
+				panic(fmt.Sprintf("go-firestore-mock: docid field %s.%s is promoted through the embedded pointer %s", t, f.Name, embedded.Name))
+			}
+			st = embedded.Type
+		}
+		return f.Index
+	}
+	return nil
+}
+
+// Ref returns the underlying CollectionRef, e.g. to build queries for Query,
+// Stream and Watch.
+func (c *TypedCollection[T]) Ref() CollectionRef {
+	return c.coll
+}
+
+// Get reads the document with the given ID and decodes it into a T. A missing
+// document yields the SDK error (codes.NotFound).
+func (c *TypedCollection[T]) Get(ctx context.Context, id string) (T, error) {
+	var v T
+	snap, err := c.coll.Doc(id).Get(ctx)
+	if err != nil {
+		return v, err
+	}
+	if err := snap.DataTo(&v); err != nil {
+		return v, err
+	}
+	c.setID(&v, id)
+	return v, nil
+}
+
+// Put writes v with Set, replacing the document named by its docid field (or
+// merging the given fields into it, with a firestore.Merge(paths...) option;
+// firestore.MergeAll only applies to map data and fails for a struct). If the
+// docid field is empty a new document ID is allocated. Put returns v with the
+// docid field set.
+func (c *TypedCollection[T]) Put(ctx context.Context, v T, opts ...firestore.SetOption) (T, error) {
+	doc := c.docFor(&v)
+	if _, err := doc.Set(ctx, v, opts...); err != nil {
+		return v, err
+	}
+	return v, nil
+}
+
+// Create writes v with Create, failing with codes.AlreadyExists if the
+// document named by its docid field exists. If the docid field is empty a new
+// document ID is allocated. Create returns v with the docid field set.
+func (c *TypedCollection[T]) Create(ctx context.Context, v T) (T, error) {
+	doc := c.docFor(&v)
+	if _, err := doc.Create(ctx, v); err != nil {
+		return v, err
+	}
+	return v, nil
+}
+
+// Delete deletes the document with the given ID.
+func (c *TypedCollection[T]) Delete(ctx context.Context, id string, preconds ...firestore.Precondition) error {
+	_, err := c.coll.Doc(id).Delete(ctx, preconds...)
+	return err
+}
+
+// Query runs q with GetAll and decodes every result into a T. A nil q reads
+// the whole collection.
+func (c *TypedCollection[T]) Query(ctx context.Context, q Query) ([]T, error) {
+	if q == nil {
+		q = c.coll
+	}
+	snaps, err := q.Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]T, 0, len(snaps))
+	for _, snap := range snaps {
+		v, err := c.decode(snap)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+// Stream runs q and yields its results one at a time, decoded into T. A nil q
+// reads the whole collection. The underlying iterator is stopped when the loop
+// ends. The SDK only serves LimitToLast queries in full, so run them with
+// Query instead.
+func (c *TypedCollection[T]) Stream(ctx context.Context, q Query) iter.Seq2[T, error] {
+	if q == nil {
+		q = c.coll
+	}
+	return func(yield func(T, error) bool) {
+		for snap, err := range All(q.Documents(ctx)) {
+			var v T
+			if err == nil {
+				v, err = c.decode(snap)
+			}
+			if !yield(v, err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
+// Watch listens to q and yields the full, decoded result set each time it
+// changes. A nil q watches the whole collection. Stop listening by breaking
+// out of the loop or cancelling ctx.
+func (c *TypedCollection[T]) Watch(ctx context.Context, q Query) iter.Seq2[[]T, error] {
+	if q == nil {
+		q = c.coll
+	}
+	return func(yield func([]T, error) bool) {
+		for qs, err := range AllQuerySnapshots(q.Snapshots(ctx)) {
+			var vs []T
+			if err == nil {
+				vs, err = c.decodeQuerySnapshot(qs)
+			}
+			if !yield(vs, err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (c *TypedCollection[T]) decodeQuerySnapshot(qs *firestore.QuerySnapshot) ([]T, error) {
+	it := qs.Documents
+	defer it.Stop()
+	vs := []T{}
+	for {
+		snap, err := it.Next()
+		if err == iterator.Done {
+			return vs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		v, err := c.decode(snap)
+		if err != nil {
+			return nil, err
+		}
+		vs = append(vs, v)
+	}
+}
+
+func (c *TypedCollection[T]) decode(snap *firestore.DocumentSnapshot) (T, error) {
+	var v T
+	if err := snap.DataTo(&v); err != nil {
+		return v, err
+	}
+	if snap.Ref != nil {
+		c.setID(&v, snap.Ref.ID)
+	}
+	return v, nil
+}
+
+// docFor returns the DocumentRef named by v's docid field, allocating a new
+// document (and setting the field) when it is empty or T has no docid field.
+func (c *TypedCollection[T]) docFor(v *T) DocumentRef {
+	if id := c.id(v); id != "" {
+		return c.coll.Doc(id)
+	}
+	doc := c.coll.NewDoc()
+	c.setID(v, doc.ID())
+	return doc
+}
+
+func (c *TypedCollection[T]) id(v *T) string {
+	if c.idIdx == nil {
+		return ""
+	}
+	return reflect.ValueOf(v).Elem().FieldByIndex(c.idIdx).String()
+}
+
+func (c *TypedCollection[T]) setID(v *T, id string) {
+	if c.idIdx == nil {
+		return
+	}
+	reflect.ValueOf(v).Elem().FieldByIndex(c.idIdx).SetString(id)
+}
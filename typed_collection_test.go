@@ -0,0 +1,251 @@
+package firestore
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"cloud.google.com/go/firestore"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type typedUser struct {
+	ID     string `firestore:"-" docid:"true"`
+	Name   string `firestore:"name"`
+	Status string `firestore:"status"`
+}
+
+func TestTypedCollection_Get(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	coll := NewMockCollectionRef(ctrl)
+	doc := NewMockDocumentRef(ctrl)
+	snap := NewMockDocumentSnapshot(ctrl)
+
+	coll.EXPECT().Doc("u1").Return(doc)
+	doc.EXPECT().Get(ctx).Return(snap, nil)
+	snap.EXPECT().DataTo(gomock.Any()).DoAndReturn(func(p any) error {
+		*p.(*typedUser) = typedUser{Name: "Ann", Status: "active"}
+		return nil
+	})
+
+	users := NewTypedCollection[typedUser](coll)
+	got, err := users.Get(ctx, "u1")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if want := (typedUser{ID: "u1", Name: "Ann", Status: "active"}); got != want {
+		t.Errorf("Get() = %+v, want %+v", got, want)
+	}
+}
+
+func TestTypedCollection_GetNotFound(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	coll := NewMockCollectionRef(ctrl)
+	doc := NewMockDocumentRef(ctrl)
+	notFound := status.Error(codes.NotFound, "not found")
+
+	coll.EXPECT().Doc("missing").Return(doc)
+	doc.EXPECT().Get(ctx).Return(nil, notFound)
+
+	if _, err := NewTypedCollection[typedUser](coll).Get(ctx, "missing"); status.Code(err) != codes.NotFound {
+		t.Errorf("Get() error = %v, want NotFound", err)
+	}
+}
+
+func TestTypedCollection_PutAndCreate(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	coll := NewMockCollectionRef(ctrl)
+	existing := NewMockDocumentRef(ctrl)
+	fresh := NewMockDocumentRef(ctrl)
+
+	coll.EXPECT().Doc("u1").Return(existing)
+	existing.EXPECT().Set(ctx, typedUser{ID: "u1", Name: "Ann"}, firestore.Merge([]string{"name"})).Return(nil, nil)
+	coll.EXPECT().NewDoc().Return(fresh)
+	fresh.EXPECT().ID().Return("auto123")
+	fresh.EXPECT().Create(ctx, typedUser{ID: "auto123", Name: "Bob"}).Return(nil, nil)
+
+	users := NewTypedCollection[typedUser](coll)
+	if _, err := users.Put(ctx, typedUser{ID: "u1", Name: "Ann"}, firestore.Merge([]string{"name"})); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	created, err := users.Create(ctx, typedUser{Name: "Bob"})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if created.ID != "auto123" {
+		t.Errorf("Create() ID = %q, want %q", created.ID, "auto123")
+	}
+}
+
+func TestTypedCollection_QueryAndStream(t *testing.T) {
+	ctx := context.Background()
+	client := newSnapshotClient(t)
+	ann := newSnapshot(t, client, "users/u1", map[string]any{"name": "Ann", "status": "active"})
+	bob := newSnapshot(t, client, "users/u2", map[string]any{"name": "Bob", "status": "active"})
+
+	r := NewQueryResponder()
+	r.OnQuery(QuerySpec{
+		Collection: "users",
+		Filters:    []FilterSpec{{Path: "status", Op: "==", Value: "active"}},
+	}).Return(ann, bob)
+
+	users := NewTypedCollection[typedUser](r.Collection("users"))
+	got, err := users.Query(ctx, users.Ref().Where("status", "==", "active"))
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	want := []typedUser{{ID: "u1", Name: "Ann", Status: "active"}, {ID: "u2", Name: "Bob", Status: "active"}}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("Query() = %+v, want %+v", got, want)
+	}
+
+	n := 0
+	for u, err := range users.Stream(ctx, users.Ref().Where("status", "==", "active")) {
+		if err != nil {
+			t.Fatalf("Stream() error = %v", err)
+		}
+		if u.ID != "u1" {
+			t.Errorf("Stream() first = %+v, want u1", u)
+		}
+		n++
+		break
+	}
+	if n != 1 {
+		t.Errorf("Stream() yielded %d values before break, want 1", n)
+	}
+}
+
+func TestTypedCollection_QueryLimitToLast(t *testing.T) {
+	ctx := context.Background()
+	sc := newSnapshotClient(t)
+	newSnapshot(t, sc, "users/u1", map[string]any{"name": "Ann"})
+	newSnapshot(t, sc, "users/u2", map[string]any{"name": "Bob"})
+	newSnapshot(t, sc, "users/u3", map[string]any{"name": "Cid"})
+
+	users := NewTypedCollection[typedUser](NewFirestoreClient(sc).Collection("users"))
+	got, err := users.Query(ctx, users.Ref().OrderBy("name", firestore.Asc).LimitToLast(2))
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if len(got) != 2 || got[0].ID != "u2" || got[1].ID != "u3" {
+		t.Errorf("Query() = %+v, want u2, u3", got)
+	}
+}
+
+func TestTypedCollection_QueryError(t *testing.T) {
+	boom := errors.New("boom")
+	r := NewQueryResponder()
+	r.OnQuery(QuerySpec{Collection: "users"}).ReturnError(boom)
+
+	if _, err := NewTypedCollection[typedUser](r.Collection("users")).Query(context.Background(), nil); !errors.Is(err, boom) {
+		t.Errorf("Query() error = %v, want boom", err)
+	}
+}
+
+func TestTypedCollection_Watch(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	sdk := newSnapshotClient(t)
+	newSnapshot(t, sdk, "users/u1", map[string]any{"name": "Ann", "status": "active"})
+	newSnapshot(t, sdk, "users/u2", map[string]any{"name": "Bob", "status": "banned"})
+	boom := errors.New("boom")
+
+	q := NewMockQuery(ctrl)
+	it := NewMockQuerySnapshotIterator(ctrl)
+	q.EXPECT().Snapshots(ctx).Return(it)
+	gomock.InOrder(
+		it.EXPECT().Next().Return(&firestore.QuerySnapshot{Documents: sdk.Collection("users").Documents(ctx)}, nil),
+		it.EXPECT().Next().Return(&firestore.QuerySnapshot{Documents: sdk.Collection("empty").Documents(ctx)}, nil),
+		it.EXPECT().Next().Return(nil, boom),
+		it.EXPECT().Stop(),
+	)
+
+	var got [][]typedUser
+	var gotErr error
+	for users, err := range NewTypedCollection[typedUser](nil).Watch(ctx, q) {
+		if err != nil {
+			gotErr = err
+			continue
+		}
+		got = append(got, users)
+	}
+	want := [][]typedUser{
+		{{ID: "u1", Name: "Ann", Status: "active"}, {ID: "u2", Name: "Bob", Status: "banned"}},
+		{},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Watch() yielded %+v, want %+v", got, want)
+	}
+	if !errors.Is(gotErr, boom) {
+		t.Errorf("Watch() error = %v, want boom", gotErr)
+	}
+}
+
+func TestNewTypedCollection_Panics(t *testing.T) {
+	type base struct {
+		ID string `firestore:"-" docid:"true"`
+	}
+	tests := []struct {
+		name string
+		new  func()
+	}{
+		{
+			name: "non-string docid field",
+			new: func() {
+				type bad struct {
+					ID int `firestore:"-" docid:"true"`
+				}
+				NewTypedCollection[bad](nil)
+			},
+		},
+		{
+			name: "docid field behind an embedded pointer",
+			new: func() {
+				type bad struct {
+					*base
+					Name string `firestore:"name"`
+				}
+				NewTypedCollection[bad](nil)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("NewTypedCollection() did not panic")
+				}
+			}()
+			tt.new()
+		})
+	}
+}
+
+func TestNewTypedCollection_EmbeddedDocID(t *testing.T) {
+	type base struct {
+		ID string `firestore:"-" docid:"true"`
+	}
+	type user struct {
+		base
+		Name string `firestore:"name"`
+	}
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	coll := NewMockCollectionRef(ctrl)
+	doc := NewMockDocumentRef(ctrl)
+	snap := NewMockDocumentSnapshot(ctrl)
+	coll.EXPECT().Doc("u1").Return(doc)
+	doc.EXPECT().Get(ctx).Return(snap, nil)
+	snap.EXPECT().DataTo(gomock.Any()).Return(nil)
+
+	got, err := NewTypedCollection[user](coll).Get(ctx, "u1")
+	if err != nil || got.ID != "u1" {
+		t.Errorf("Get() = %+v, %v, want ID u1", got, err)
+	}
+}
@@ -0,0 +1,126 @@
+package firestore
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	pb "cloud.google.com/go/firestore/apiv1/firestorepb"
+	"google.golang.org/genproto/googleapis/type/latlng"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// toProtoValue converts a document field value, as returned by
+// DocumentSnapshot.Data, to its Firestore wire representation.
+func toProtoValue(v any) (*pb.Value, error) {
+	switch v := v.(type) {
+	case nil:
+		return &pb.Value{ValueType: &pb.Value_NullValue{NullValue: structpb.NullValue_NULL_VALUE}}, nil
+	case bool:
+		return &pb.Value{ValueType: &pb.Value_BooleanValue{BooleanValue: v}}, nil
+	case int:
+		return intValue(int64(v)), nil
+	case int8:
+		return intValue(int64(v)), nil
+	case int16:
+		return intValue(int64(v)), nil
+	case int32:
+		return intValue(int64(v)), nil
+	case int64:
+		return intValue(v), nil
+	case uint8:
+		return intValue(int64(v)), nil
+	case uint16:
+		return intValue(int64(v)), nil
+	case uint32:
+		return intValue(int64(v)), nil
+	case float32:
+		return doubleValue(float64(v)), nil
+	case float64:
+		return doubleValue(v), nil
+	case string:
+		return &pb.Value{ValueType: &pb.Value_StringValue{StringValue: v}}, nil
+	case []byte:
+		return &pb.Value{ValueType: &pb.Value_BytesValue{BytesValue: v}}, nil
+	case time.Time:
+		return &pb.Value{ValueType: &pb.Value_TimestampValue{TimestampValue: timestamppb.New(v)}}, nil
+	case *latlng.LatLng:
+		return &pb.Value{ValueType: &pb.Value_GeoPointValue{GeoPointValue: v}}, nil
+	case *firestore.DocumentRef:
+		if v == nil {
+			return toProtoValue(nil)
+		}
+		return &pb.Value{ValueType: &pb.Value_ReferenceValue{ReferenceValue: v.Path}}, nil
+	case firestore.Vector64:
+		return vectorValue(v), nil
+	case firestore.Vector32:
+		vec := make([]float64, len(v))
+		for i, x := range v {
+			vec[i] = float64(x)
+		}
+		return vectorValue(vec), nil
+	case []any:
+		values := make([]*pb.Value, len(v))
+		for i, x := range v {
+			pv, err := toProtoValue(x)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = pv
+		}
+		return &pb.Value{ValueType: &pb.Value_ArrayValue{ArrayValue: &pb.ArrayValue{Values: values}}}, nil
+	case map[string]any:
+		fields, err := toProtoFields(v)
+		if err != nil {
+			return nil, err
+		}
+		return &pb.Value{ValueType: &pb.Value_MapValue{MapValue: &pb.MapValue{Fields: fields}}}, nil
+	}
+	return nil, fmt.Errorf("go-firestore-mock: cannot encode value of type %T", v)
+}
+
+// sortedKeys returns the keys of m in order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// toProtoFields converts document data to its Firestore wire representation.
+func toProtoFields(data map[string]any) (map[string]*pb.Value, error) {
+	fields := make(map[string]*pb.Value, len(data))
+	for _, k := range sortedKeys(data) { // report the first bad field deterministically
+		pv, err := toProtoValue(data[k])
+		if err != nil {
+			return nil, fmt.Errorf("%w (field %q)", err, k)
+		}
+		fields[k] = pv
+	}
+	return fields, nil
+}
+
+func intValue(n int64) *pb.Value {
+	return &pb.Value{ValueType: &pb.Value_IntegerValue{IntegerValue: n}}
+}
+
+func doubleValue(f float64) *pb.Value {
+	return &pb.Value{ValueType: &pb.Value_DoubleValue{DoubleValue: f}}
+}
+
+// vectorValue encodes a vector the way the SDK does: as a map with a
+// "__type__" of "__vector__" and the elements as an array of doubles.
+func vectorValue(vec []float64) *pb.Value {
+	values := make([]*pb.Value, len(vec))
+	for i, x := range vec {
+		values[i] = doubleValue(x)
+	}
+	return &pb.Value{ValueType: &pb.Value_MapValue{MapValue: &pb.MapValue{Fields: map[string]*pb.Value{
+		"__type__": {ValueType: &pb.Value_StringValue{StringValue: "__vector__"}},
+		"value":    {ValueType: &pb.Value_ArrayValue{ArrayValue: &pb.ArrayValue{Values: values}}},
+	}}}}
+}